@@ -9,6 +9,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the node's first character
+	End() token.Position // position just past the node's last character
 }
 
 type Statement interface {
@@ -21,8 +23,27 @@ type Expression interface {
 	expressionNode()
 }
 
+// Span is the source range covered by a node. It is embedded in every node
+// and filled in by the parser.
+type Span struct {
+	Start token.Position
+	Stop  token.Position
+}
+
+func (s *Span) Pos() token.Position { return s.Start }
+func (s *Span) End() token.Position { return s.Stop }
+
+// SetSpan records the source range covered by the node.
+func (s *Span) SetSpan(start, end token.Position) {
+	s.Start = start
+	s.Stop = end
+}
+
 type Program struct {
+	Span
 	Statements []Statement
+	Strict     bool   // parsed from a .ts file
+	Source     string // the text it was parsed from
 }
 
 func (p *Program) TokenLiteral() string {
@@ -41,9 +62,11 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Span
+	Token   token.Token // the token.LET token
+	Name    *Identifier
+	Pattern Expression // an ObjectPattern or ArrayPattern, in place of Name
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -52,7 +75,11 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -64,7 +91,27 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// DeclarationList is a let, const or var statement declaring more than
+// one variable, as in let i = 0, j = n. A single declaration is a
+// LetStatement on its own.
+type DeclarationList struct {
+	Span
+	Token        token.Token // the let, const or var token
+	Declarations []*LetStatement
+}
+
+func (dl *DeclarationList) statementNode()       {}
+func (dl *DeclarationList) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DeclarationList) String() string {
+	var out bytes.Buffer
+	for _, decl := range dl.Declarations {
+		out.WriteString(decl.String())
+	}
+	return out.String()
+}
+
 type ReturnStatement struct {
+	Span
 	Token       token.Token // the 'return' token
 	ReturnValue Expression
 }
@@ -86,6 +133,7 @@ func (rs *ReturnStatement) String() string {
 }
 
 type ExportStatement struct {
+	Span
 	Token token.Token // the 'export' token
 }
 
@@ -96,6 +144,7 @@ func (es *ExportStatement) String() string {
 }
 
 type ExpressionStatement struct {
+	Span
 	Token      token.Token // the first token of the expression
 	Expression Expression
 }
@@ -110,6 +159,7 @@ func (es *ExpressionStatement) String() string {
 }
 
 type ImportStatement struct {
+	Span
 	Token  token.Token // The 'import' token
 	Alias  *Identifier // The alias for the imported module
 	Source *StringLiteral
@@ -130,6 +180,7 @@ func (is *ImportStatement) String() string {
 }
 
 type BlockStatement struct {
+	Span
 	Token      token.Token // the { token
 	Statements []Statement
 }
@@ -144,26 +195,266 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+type WhileStatement struct {
+	Span
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      Statement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	return "while (" + ws.Condition.String() + ") " + ws.Body.String()
+}
+
+type DoWhileStatement struct {
+	Span
+	Token     token.Token // the 'do' token
+	Body      Statement
+	Condition Expression
+}
+
+func (dw *DoWhileStatement) statementNode()       {}
+func (dw *DoWhileStatement) TokenLiteral() string { return dw.Token.Literal }
+func (dw *DoWhileStatement) String() string {
+	return "do " + dw.Body.String() + " while (" + dw.Condition.String() + ");"
+}
+
+type ForStatement struct {
+	Span
+	Token     token.Token // the 'for' token
+	Init      Statement   // may be nil
+	Condition Expression  // may be nil
+	Update    Expression  // may be nil
+	Body      Statement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(fs.Init.String())
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Update != nil {
+		out.WriteString(fs.Update.String())
+	}
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// ForOfStatement is for (const x of iterable) and ForInStatement is
+// for (const key in object). Declaration is the LET/CONST/VAR token, or the
+// zero token when the loop assigns to an existing variable.
+type ForOfStatement struct {
+	Span
+	Token       token.Token // the 'for' token
+	Declaration token.Token
+	Variable    *Identifier
+	Pattern     Expression // a destructuring pattern, in place of Variable
+	Iterable    Expression
+	Body        Statement
+}
+
+func (fs *ForOfStatement) statementNode()       {}
+func (fs *ForOfStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForOfStatement) String() string {
+	var variable Expression = fs.Variable
+	if fs.Pattern != nil {
+		variable = fs.Pattern
+	}
+	return "for (" + forHead(fs.Declaration, variable) + " of " + fs.Iterable.String() + ") " + fs.Body.String()
+}
+
+type ForInStatement struct {
+	Span
+	Token       token.Token // the 'for' token
+	Declaration token.Token
+	Variable    *Identifier
+	Object      Expression
+	Body        Statement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) String() string {
+	return "for (" + forHead(fs.Declaration, fs.Variable) + " in " + fs.Object.String() + ") " + fs.Body.String()
+}
+
+func forHead(decl token.Token, variable Expression) string {
+	if decl.Literal == "" {
+		return variable.String()
+	}
+	return decl.Literal + " " + variable.String()
+}
+
+type BreakStatement struct {
+	Span
+	Token token.Token // the 'break' token
+	Label *Identifier // may be nil
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string {
+	if bs.Label != nil {
+		return "break " + bs.Label.String() + ";"
+	}
+	return "break;"
+}
+
+type ContinueStatement struct {
+	Span
+	Token token.Token // the 'continue' token
+	Label *Identifier // may be nil
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string {
+	if cs.Label != nil {
+		return "continue " + cs.Label.String() + ";"
+	}
+	return "continue;"
+}
+
+type LabeledStatement struct {
+	Span
+	Token token.Token // the label token
+	Label *Identifier
+	Body  Statement
+}
+
+func (ls *LabeledStatement) statementNode()       {}
+func (ls *LabeledStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LabeledStatement) String() string {
+	return ls.Label.String() + ": " + ls.Body.String()
+}
+
+// SwitchStatement is switch (Discriminant) { case a: ... default: ... }.
+type SwitchStatement struct {
+	Span
+	Token        token.Token // the 'switch' token
+	Discriminant Expression
+	Cases        []*SwitchCase
+}
+
+func (ss *SwitchStatement) statementNode()       {}
+func (ss *SwitchStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwitchStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("switch (" + ss.Discriminant.String() + ") { ")
+	for _, c := range ss.Cases {
+		out.WriteString(c.String() + " ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// SwitchCase is a case clause of a switch, or its default clause if Test is
+// nil.
+type SwitchCase struct {
+	Span
+	Token      token.Token // the 'case' or 'default' token
+	Test       Expression
+	Consequent []Statement
+}
+
+func (sc *SwitchCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SwitchCase) String() string {
+	var out bytes.Buffer
+	if sc.Test != nil {
+		out.WriteString("case " + sc.Test.String() + ":")
+	} else {
+		out.WriteString("default:")
+	}
+	for _, s := range sc.Consequent {
+		out.WriteString(" " + s.String())
+	}
+	return out.String()
+}
+
+type ThrowStatement struct {
+	Span
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Value.String() + ";"
+}
+
+// TryStatement has a CatchBody, a Finally block, or both. CatchParam is nil
+// for 'catch { ... }' without a binding.
+type TryStatement struct {
+	Span
+	Token      token.Token // the 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	CatchBody  *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {" + ts.Block.String() + "}")
+	if ts.CatchBody != nil {
+		out.WriteString(" catch ")
+		if ts.CatchParam != nil {
+			out.WriteString("(" + ts.CatchParam.String() + ") ")
+		}
+		out.WriteString("{" + ts.CatchBody.String() + "}")
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally {" + ts.Finally.String() + "}")
+	}
+
+	return out.String()
+}
+
 type Identifier struct {
+	Span
 	Token token.Token // the token.IDENT token
 	Value string
-	Type  string // e.g. "number", "string", "void"
+	Type  TypeNode // type annotation, or nil
+
+	// A function parameter may be optional, (x?: number), or have a
+	// default value, (x = 1).
+	Optional bool
+	Default  Expression
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
-type IntegerLiteral struct {
+type NumberLiteral struct {
+	Span
 	Token token.Token
-	Value int64
+	Value float64
 }
 
-func (il *IntegerLiteral) expressionNode()      {}
-func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
-func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+func (nl *NumberLiteral) expressionNode()      {}
+func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NumberLiteral) String() string       { return nl.Token.Literal }
 
 type StringLiteral struct {
+	Span
 	Token token.Token
 	Value string
 }
@@ -172,7 +463,59 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// TemplateLiteral is a template string. Its text is split into quasis
+// around the ${} substitutions, so there is one more quasi than there are
+// expressions.
+type TemplateLiteral struct {
+	Span
+	Token       token.Token // the first template token
+	Quasis      []TemplateQuasi
+	Expressions []Expression
+}
+
+// TemplateQuasi is a piece of template text, with escapes processed
+// (Cooked) and as written (Raw). Invalid marks a malformed escape, which
+// tagged templates allow: the cooked value is then undefined.
+type TemplateQuasi struct {
+	Cooked  string
+	Raw     string
+	Invalid bool
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("`")
+	for i, quasi := range tl.Quasis {
+		out.WriteString(quasi.Raw)
+		if i < len(tl.Expressions) {
+			out.WriteString("${" + tl.Expressions[i].String() + "}")
+		}
+	}
+	out.WriteString("`")
+
+	return out.String()
+}
+
+// TaggedTemplateExpression calls Tag with the template's strings and the
+// values of its substitutions: html`<p>${name}</p>`
+type TaggedTemplateExpression struct {
+	Span
+	Token    token.Token // the first token of the template
+	Tag      Expression
+	Template *TemplateLiteral
+}
+
+func (tt *TaggedTemplateExpression) expressionNode()      {}
+func (tt *TaggedTemplateExpression) TokenLiteral() string { return tt.Token.Literal }
+func (tt *TaggedTemplateExpression) String() string {
+	return tt.Tag.String() + tt.Template.String()
+}
+
 type PrefixExpression struct {
+	Span
 	Token    token.Token // The prefix token, e.g. ! or -
 	Operator string
 	Right    Expression
@@ -185,17 +528,36 @@ func (pe *PrefixExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
+	if token.IsKeyword(pe.Operator) {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(")")
 
 	return out.String()
 }
 
+// AwaitExpression suspends the enclosing async function until Argument,
+// usually a promise, settles: await fetch(url)
+type AwaitExpression struct {
+	Span
+	Token    token.Token // the 'await' token
+	Argument Expression
+}
+
+func (ae *AwaitExpression) expressionNode()      {}
+func (ae *AwaitExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AwaitExpression) String() string {
+	return "(await " + ae.Argument.String() + ")"
+}
+
 type InfixExpression struct {
+	Span
 	Token    token.Token // The operator token, e.g. +
 	Left     Expression
 	Operator string
 	Right    Expression
+	Optional bool // obj?.name, a member read only if obj is not null or undefined
 }
 
 func (ie *InfixExpression) expressionNode()      {}
@@ -205,14 +567,65 @@ func (ie *InfixExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
-	out.WriteString(" " + ie.Operator + " ")
+	if ie.Optional {
+		out.WriteString(" ?. ")
+	} else {
+		out.WriteString(" " + ie.Operator + " ")
+	}
 	out.WriteString(ie.Right.String())
 	out.WriteString(")")
 
 	return out.String()
 }
 
+// ConditionalExpression is cond ? a : b.
+type ConditionalExpression struct {
+	Span
+	Token       token.Token // the '?' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
+
+// SequenceExpression is the comma operator, a, b, which evaluates each
+// expression in turn and gives the value of the last.
+type SequenceExpression struct {
+	Span
+	Token       token.Token // the first ',' token
+	Expressions []Expression
+}
+
+func (se *SequenceExpression) expressionNode()      {}
+func (se *SequenceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SequenceExpression) String() string {
+	list := make([]string, len(se.Expressions))
+	for i, e := range se.Expressions {
+		list[i] = e.String()
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// OptionalChain is a chain of member reads, indexes and calls containing
+// an optional link, a?.b.c(). If the object of an optional link is null or
+// undefined, the rest of the chain is skipped and it gives undefined.
+type OptionalChain struct {
+	Span
+	Token      token.Token // the first '?.' token
+	Expression Expression
+}
+
+func (oc *OptionalChain) expressionNode()      {}
+func (oc *OptionalChain) TokenLiteral() string { return oc.Token.Literal }
+func (oc *OptionalChain) String() string       { return oc.Expression.String() }
+
 type IfExpression struct {
+	Span
 	Token       token.Token // The 'if' token
 	Condition   Expression
 	Consequence *BlockStatement
@@ -238,6 +651,7 @@ func (ie *IfExpression) String() string {
 }
 
 type Boolean struct {
+	Span
 	Token token.Token
 	Value bool
 }
@@ -246,10 +660,22 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+type NullLiteral struct {
+	Span
+	Token token.Token
+}
+
+func (nl *NullLiteral) expressionNode()      {}
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NullLiteral) String() string       { return "null" }
+
 type CallExpression struct {
+	Span
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
+	TypeArgs  []TypeNode  // explicit type arguments: f<number>(x)
 	Arguments []Expression
+	Optional  bool // f?.(x), a call only if f is not null or undefined
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -263,6 +689,10 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	out.WriteString(typeArgList(ce.TypeArgs))
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -271,11 +701,16 @@ func (ce *CallExpression) String() string {
 }
 
 type FunctionLiteral struct {
-	Token      token.Token // The 'function' token
+	Span
+	Token      token.Token // The 'function' token, or the first token of an arrow function
 	Parameters []*Identifier
-	Body       *BlockStatement
+	Body       *BlockStatement // expression-bodied arrows get a single return statement
 	Name       string
-	ReturnType string
+	TypeParams []*TypeParam // of a generic function
+	ReturnType TypeNode     // nil if not annotated
+	Arrow      bool         // arrow functions do not bind their own 'this'
+	Async      bool
+	Rest       bool // the last parameter collects the remaining arguments
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -283,15 +718,26 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	params := ParameterList(fl.Parameters, fl.Rest)
+
+	if fl.Async {
+		out.WriteString("async ")
 	}
 
-	out.WriteString(fl.TokenLiteral())
+	if fl.Arrow {
+		out.WriteString(typeParamList(fl.TypeParams))
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
+	}
+
+	out.WriteString("function")
 	if fl.Name != "" {
 		out.WriteString(" " + fl.Name)
 	}
+	out.WriteString(typeParamList(fl.TypeParams))
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -300,9 +746,180 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// ParameterList renders the parameters of a function, the last one as
+// ...rest if rest is set.
+func ParameterList(params []*Identifier, rest bool) []string {
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = p.String()
+		if p.Optional {
+			list[i] += "?"
+		}
+		if p.Default != nil {
+			list[i] += " = " + p.Default.String()
+		}
+	}
+	if rest && len(list) > 0 {
+		list[len(list)-1] = "..." + list[len(list)-1]
+	}
+	return list
+}
+
+type ThisExpression struct {
+	Span
+	Token token.Token // the 'this' token
+}
+
+func (te *ThisExpression) expressionNode()      {}
+func (te *ThisExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *ThisExpression) String() string       { return "this" }
+
+// SuperExpression is 'super' in super(...) calls and super.x lookups.
+type SuperExpression struct {
+	Span
+	Token token.Token // the 'super' token
+}
+
+func (se *SuperExpression) expressionNode()      {}
+func (se *SuperExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SuperExpression) String() string       { return "super" }
+
+// ClassLiteral is a class declaration or class expression.
+type ClassLiteral struct {
+	Span
+	Token      token.Token // the 'class' token
+	Name       *Identifier // nil for anonymous class expressions
+	TypeParams []*TypeParam
+	SuperClass Expression // the extends clause, if any
+	SuperArgs  []TypeNode // type arguments of the superclass: extends Base<T>
+	Members    []*ClassMember
+}
+
+func (cl *ClassLiteral) expressionNode()      {}
+func (cl *ClassLiteral) TokenLiteral() string { return cl.Token.Literal }
+func (cl *ClassLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("class")
+	if cl.Name != nil {
+		out.WriteString(" " + cl.Name.String())
+	}
+	out.WriteString(typeParamList(cl.TypeParams))
+	if cl.SuperClass != nil {
+		out.WriteString(" extends " + cl.SuperClass.String() + typeArgList(cl.SuperArgs))
+	}
+	out.WriteString(" {")
+	for _, m := range cl.Members {
+		out.WriteString(" " + m.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type ClassMemberKind int
+
+const (
+	ClassField ClassMemberKind = iota
+	ClassMethod
+	ClassGetter
+	ClassSetter
+	ClassConstructor
+)
+
+// Modifiers are the TypeScript modifiers of a class member or parameter
+// property that only the type checker looks at.
+type Modifiers struct {
+	Access   string // "private" or "protected", or "" if public
+	Readonly bool
+}
+
+func (m Modifiers) String() string {
+	var out bytes.Buffer
+	if m.Access != "" {
+		out.WriteString(m.Access + " ")
+	}
+	if m.Readonly {
+		out.WriteString("readonly ")
+	}
+	return out.String()
+}
+
+// ClassMember is a field, method, accessor or constructor in a class body.
+// Names of #private members keep their leading '#'.
+type ClassMember struct {
+	Span
+	Modifiers
+	Token  token.Token // the first token of the member
+	Kind   ClassMemberKind
+	Name   string
+	Static bool
+	Value  *FunctionLiteral // methods, accessors and the constructor
+	Init   Expression       // field initializer, if any
+	Type   TypeNode         // field type annotation, or nil
+
+	// Constructor parameters declared with public, private, protected or
+	// readonly, which also become fields of the instance, and their
+	// modifiers.
+	ParameterProperties []string
+	ParameterModifiers  map[string]Modifiers
+}
+
+func (cm *ClassMember) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(cm.Modifiers.String())
+	if cm.Static {
+		out.WriteString("static ")
+	}
+
+	switch cm.Kind {
+	case ClassField:
+		out.WriteString(cm.Name)
+		if cm.Type != nil {
+			out.WriteString(": " + cm.Type.String())
+		}
+		if cm.Init != nil {
+			out.WriteString(" = " + cm.Init.String())
+		}
+		out.WriteString(";")
+		return out.String()
+	case ClassGetter:
+		out.WriteString("get ")
+	case ClassSetter:
+		out.WriteString("set ")
+	}
+
+	params := ParameterList(cm.Value.Parameters, cm.Value.Rest)
+	out.WriteString(cm.Name + "(" + strings.Join(params, ", ") + ") ")
+	out.WriteString(cm.Value.Body.String())
+
+	return out.String()
+}
+
+type NewExpression struct {
+	Span
+	Token       token.Token // the 'new' token
+	Constructor Expression
+	TypeArgs    []TypeNode // explicit type arguments: new Box<number>(x)
+	Arguments   []Expression
+}
+
+func (ne *NewExpression) expressionNode()      {}
+func (ne *NewExpression) TokenLiteral() string { return ne.Token.Literal }
+func (ne *NewExpression) String() string {
+	args := []string{}
+	for _, a := range ne.Arguments {
+		args = append(args, a.String())
+	}
+	return "new " + ne.Constructor.String() + typeArgList(ne.TypeArgs) + "(" + strings.Join(args, ", ") + ")"
+}
+
 type HashLiteral struct {
+	Span
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Keys  []Expression // keys of Pairs in source order; a SpreadElement key has no value
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -311,8 +928,12 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		if _, ok := key.(*SpreadElement); ok {
+			pairs = append(pairs, key.String())
+			continue
+		}
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -323,6 +944,7 @@ func (hl *HashLiteral) String() string {
 }
 
 type ArrayLiteral struct {
+	Span
 	Token    token.Token // the '[' token
 	Elements []Expression
 }
@@ -345,9 +967,11 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token // The [ token
-	Left  Expression
-	Index Expression
+	Span
+	Token    token.Token // The [ token
+	Left     Expression
+	Index    Expression
+	Optional bool // a?.[i], an index only if a is not null or undefined
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -357,6 +981,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -365,9 +992,11 @@ func (ie *IndexExpression) String() string {
 }
 
 type AssignmentExpression struct {
-	Token token.Token // The '=' token
-	Left  Expression
-	Value Expression
+	Span
+	Token    token.Token // The '=' token, or a compound one such as '+='
+	Operator string
+	Left     Expression
+	Value    Expression
 }
 
 func (ae *AssignmentExpression) expressionNode()      {}
@@ -376,8 +1005,249 @@ func (ae *AssignmentExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ae.Left.String())
-	out.WriteString(" = ")
+	out.WriteString(" " + ae.Operator + " ")
 	out.WriteString(ae.Value.String())
 
 	return out.String()
 }
+
+// SpreadElement is ...value in a call's arguments, an array literal or an
+// object literal.
+type SpreadElement struct {
+	Span
+	Token    token.Token // the '...' token
+	Argument Expression
+}
+
+func (se *SpreadElement) expressionNode()      {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadElement) String() string       { return "..." + se.Argument.String() }
+
+// ObjectPattern is a destructuring pattern, { a, b: { c }, d = 1, ...rest },
+// in a declaration, parameter, for-of head or assignment. Type is the
+// annotation of the whole pattern, if it has one.
+type ObjectPattern struct {
+	Span
+	Token      token.Token // the '{' token
+	Properties []*PatternProperty
+	Rest       Expression // the target of ...rest, or nil
+	Type       TypeNode
+}
+
+func (op *ObjectPattern) expressionNode()      {}
+func (op *ObjectPattern) TokenLiteral() string { return op.Token.Literal }
+func (op *ObjectPattern) String() string {
+	parts := make([]string, 0, len(op.Properties)+1)
+	for _, prop := range op.Properties {
+		parts = append(parts, prop.String())
+	}
+	if op.Rest != nil {
+		parts = append(parts, "..."+op.Rest.String())
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+// PatternProperty is key: target = default in an object pattern. In the
+// shorthand { a }, Target is the identifier a. A computed key, [expr], may
+// be any expression.
+type PatternProperty struct {
+	Span
+	Key      Expression
+	Computed bool
+	Target   Expression // an identifier or pattern; in assignments, any assignable expression
+	Default  Expression // nil if it has none
+}
+
+func (pp *PatternProperty) TokenLiteral() string { return pp.Key.TokenLiteral() }
+func (pp *PatternProperty) String() string {
+	var out bytes.Buffer
+	key := pp.Key.String()
+	if pp.Computed {
+		key = "[" + key + "]"
+	}
+	out.WriteString(key)
+	if ident, ok := pp.Target.(*Identifier); !ok || pp.Computed || ident.Value != key {
+		out.WriteString(": " + pp.Target.String())
+	}
+	if pp.Default != nil {
+		out.WriteString(" = " + pp.Default.String())
+	}
+	return out.String()
+}
+
+// ArrayPattern is a destructuring pattern, [a, , [b], c = 1, ...rest].
+// Holes are nil elements.
+type ArrayPattern struct {
+	Span
+	Token    token.Token // the '[' token
+	Elements []*PatternElement
+	Rest     Expression // the target of ...rest, or nil
+	Type     TypeNode
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	parts := make([]string, 0, len(ap.Elements)+1)
+	for _, el := range ap.Elements {
+		if el == nil {
+			parts = append(parts, "")
+		} else {
+			parts = append(parts, el.String())
+		}
+	}
+	if ap.Rest != nil {
+		parts = append(parts, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// PatternElement is target = default in an array pattern.
+type PatternElement struct {
+	Span
+	Target  Expression
+	Default Expression // nil if it has none
+}
+
+func (pe *PatternElement) TokenLiteral() string { return pe.Target.TokenLiteral() }
+func (pe *PatternElement) String() string {
+	if pe.Default != nil {
+		return pe.Target.String() + " = " + pe.Default.String()
+	}
+	return pe.Target.String()
+}
+
+// PatternNames returns the variables a pattern declares, in order.
+func PatternNames(pattern Expression) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *Identifier:
+		return []*Identifier{pattern}
+	case *ObjectPattern:
+		var names []*Identifier
+		for _, prop := range pattern.Properties {
+			names = append(names, PatternNames(prop.Target)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, PatternNames(pattern.Rest)...)
+		}
+		return names
+	case *ArrayPattern:
+		var names []*Identifier
+		for _, el := range pattern.Elements {
+			if el != nil {
+				names = append(names, PatternNames(el.Target)...)
+			}
+		}
+		if pattern.Rest != nil {
+			names = append(names, PatternNames(pattern.Rest)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// VarNames returns the variables the var declarations in a function body
+// or program declare, including those in nested blocks and loops, but not
+// those in nested functions. Names declared more than once are returned
+// each time.
+func VarNames(stmts []Statement) []*Identifier {
+	var names []*Identifier
+	for _, stmt := range stmts {
+		names = append(names, varNames(stmt)...)
+	}
+	return names
+}
+
+func varNames(node Node) []*Identifier {
+	switch node := node.(type) {
+	case *LetStatement:
+		if node.Token.Type != token.VAR {
+			return nil
+		}
+		if node.Pattern != nil {
+			return PatternNames(node.Pattern)
+		}
+		return []*Identifier{node.Name}
+	case *DeclarationList:
+		var names []*Identifier
+		for _, decl := range node.Declarations {
+			names = append(names, varNames(decl)...)
+		}
+		return names
+	case *BlockStatement:
+		return VarNames(node.Statements)
+	case *ExpressionStatement:
+		if ifExp, ok := node.Expression.(*IfExpression); ok {
+			return varNames(ifExp)
+		}
+	case *IfExpression:
+		names := varNames(node.Consequence)
+		if node.Alternative != nil {
+			names = append(names, varNames(node.Alternative)...)
+		}
+		return names
+	case *WhileStatement:
+		return varNames(node.Body)
+	case *DoWhileStatement:
+		return varNames(node.Body)
+	case *ForStatement:
+		var names []*Identifier
+		if node.Init != nil {
+			names = varNames(node.Init)
+		}
+		return append(names, varNames(node.Body)...)
+	case *ForOfStatement:
+		var names []*Identifier
+		if node.Declaration.Type == token.VAR {
+			if node.Pattern != nil {
+				names = PatternNames(node.Pattern)
+			} else {
+				names = []*Identifier{node.Variable}
+			}
+		}
+		return append(names, varNames(node.Body)...)
+	case *ForInStatement:
+		var names []*Identifier
+		if node.Declaration.Type == token.VAR {
+			names = []*Identifier{node.Variable}
+		}
+		return append(names, varNames(node.Body)...)
+	case *LabeledStatement:
+		return varNames(node.Body)
+	case *SwitchStatement:
+		var names []*Identifier
+		for _, c := range node.Cases {
+			names = append(names, VarNames(c.Consequent)...)
+		}
+		return names
+	case *TryStatement:
+		names := varNames(node.Block)
+		if node.CatchBody != nil {
+			names = append(names, varNames(node.CatchBody)...)
+		}
+		if node.Finally != nil {
+			names = append(names, varNames(node.Finally)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// UpdateExpression is ++ or -- applied before (++x) or after (x++) its
+// operand.
+type UpdateExpression struct {
+	Span
+	Token    token.Token // the '++' or '--' token
+	Operator string
+	Prefix   bool
+	Target   Expression
+}
+
+func (ue *UpdateExpression) expressionNode()      {}
+func (ue *UpdateExpression) TokenLiteral() string { return ue.Token.Literal }
+func (ue *UpdateExpression) String() string {
+	if ue.Prefix {
+		return "(" + ue.Operator + ue.Target.String() + ")"
+	}
+	return "(" + ue.Target.String() + ue.Operator + ")"
+}
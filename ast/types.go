@@ -0,0 +1,416 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+	"ts-engine/token"
+)
+
+// TypeNode is a type expression written in an annotation, such as number[]
+// or { name: string }.
+type TypeNode interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type referred to by name: a primitive such as number, a
+// class or interface, or a type from a module such as http.IncomingMessage.
+type NamedType struct {
+	Span
+	Token token.Token // the first token of the name
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// GenericType applies a generic type to type arguments: Promise<number>.
+type GenericType struct {
+	Span
+	Token token.Token // the '<' token
+	Base  *NamedType
+	Args  []TypeNode
+}
+
+func (gt *GenericType) typeNode()            {}
+func (gt *GenericType) TokenLiteral() string { return gt.Token.Literal }
+func (gt *GenericType) String() string {
+	return gt.Base.String() + "<" + joinTypes(gt.Args, ", ") + ">"
+}
+
+type ArrayType struct {
+	Span
+	Token token.Token // the '[' token
+	Elem  TypeNode
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string {
+	switch at.Elem.(type) {
+	case *UnionType, *IntersectionType, *FunctionType, *KeyofType:
+		return "(" + at.Elem.String() + ")[]"
+	}
+	return at.Elem.String() + "[]"
+}
+
+type TupleType struct {
+	Span
+	Token token.Token // the '[' token
+	Elems []TypeNode
+}
+
+func (tt *TupleType) typeNode()            {}
+func (tt *TupleType) TokenLiteral() string { return tt.Token.Literal }
+func (tt *TupleType) String() string       { return "[" + joinTypes(tt.Elems, ", ") + "]" }
+
+// UnionType is A | B: a value of any of Types.
+type UnionType struct {
+	Span
+	Token token.Token // the first token of the type
+	Types []TypeNode
+}
+
+func (ut *UnionType) typeNode()            {}
+func (ut *UnionType) TokenLiteral() string { return ut.Token.Literal }
+func (ut *UnionType) String() string       { return joinTypes(ut.Types, " | ") }
+
+// IntersectionType is A & B: a value of all of Types.
+type IntersectionType struct {
+	Span
+	Token token.Token // the first token of the type
+	Types []TypeNode
+}
+
+func (it *IntersectionType) typeNode()            {}
+func (it *IntersectionType) TokenLiteral() string { return it.Token.Literal }
+func (it *IntersectionType) String() string {
+	types := make([]string, len(it.Types))
+	for i, t := range it.Types {
+		types[i] = t.String()
+		if _, ok := t.(*UnionType); ok {
+			types[i] = "(" + types[i] + ")"
+		}
+	}
+	return strings.Join(types, " & ")
+}
+
+// LiteralType is a type with a single value, written as a string, number
+// or boolean literal: "left", 42, -1, true.
+type LiteralType struct {
+	Span
+	Token token.Token
+	Value Expression // a StringLiteral, NumberLiteral, Boolean or negated NumberLiteral
+}
+
+func (lt *LiteralType) typeNode()            {}
+func (lt *LiteralType) TokenLiteral() string { return lt.Token.Literal }
+func (lt *LiteralType) String() string {
+	if s, ok := lt.Value.(*StringLiteral); ok {
+		return `"` + s.Value + `"`
+	}
+	if p, ok := lt.Value.(*PrefixExpression); ok {
+		return p.Operator + p.Right.String()
+	}
+	return lt.Value.String()
+}
+
+// FunctionType is the type of a function: (x: number, y?: string) => void.
+type FunctionType struct {
+	Span
+	Token      token.Token // the '(' token, or '<' for a generic function
+	TypeParams []*TypeParam
+	Params     []*FunctionTypeParam
+	Return     TypeNode
+}
+
+type FunctionTypeParam struct {
+	Name     string
+	Optional bool
+	Rest     bool     // a ...rest parameter, which must come last
+	Type     TypeNode // nil if not annotated
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	return typeParamList(ft.TypeParams) + "(" + paramList(ft.Params) + ") => " + ft.Return.String()
+}
+
+func paramList(params []*FunctionTypeParam) string {
+	list := make([]string, len(params))
+	for i, param := range params {
+		list[i] = param.Name
+		if param.Rest {
+			list[i] = "..." + list[i]
+		}
+		if param.Optional {
+			list[i] += "?"
+		}
+		if param.Type != nil {
+			list[i] += ": " + param.Type.String()
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// ObjectType is an object literal type: { name: string; age?: number }.
+type ObjectType struct {
+	Span
+	Token   token.Token // the '{' token
+	Members []*PropertySignature
+	Index   *IndexSignature // nil if there is none
+}
+
+// PropertySignature is a property of an object type. Methods, written as
+// area(): number, have a FunctionType.
+type PropertySignature struct {
+	Span
+	Token    token.Token // the property name
+	Name     string
+	Optional bool
+	Readonly bool
+	Method   bool
+	Type     TypeNode
+}
+
+// IndexSignature is [key: string]: T, the type of the properties of an
+// object type that are not listed.
+type IndexSignature struct {
+	KeyName string
+	Key     TypeNode
+	Value   TypeNode
+}
+
+func (ot *ObjectType) typeNode()            {}
+func (ot *ObjectType) TokenLiteral() string { return ot.Token.Literal }
+func (ot *ObjectType) String() string {
+	if len(ot.Members) == 0 && ot.Index == nil {
+		return "{}"
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{ ")
+	for _, m := range ot.Members {
+		out.WriteString(m.String() + "; ")
+	}
+	if ot.Index != nil {
+		out.WriteString("[" + ot.Index.KeyName + ": " + ot.Index.Key.String() + "]: " + ot.Index.Value.String() + "; ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func (ps *PropertySignature) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PropertySignature) String() string {
+	var out bytes.Buffer
+	if ps.Readonly {
+		out.WriteString("readonly ")
+	}
+	out.WriteString(ps.Name)
+	if ps.Optional {
+		out.WriteString("?")
+	}
+	if fn, ok := ps.Type.(*FunctionType); ok && ps.Method {
+		out.WriteString(typeParamList(fn.TypeParams) + "(" + paramList(fn.Params) + "): " + fn.Return.String())
+		return out.String()
+	}
+	out.WriteString(": " + ps.Type.String())
+	return out.String()
+}
+
+// IndexedAccessType looks up the type of a property: Person["name"].
+type IndexedAccessType struct {
+	Span
+	Token  token.Token // the '[' token
+	Object TypeNode
+	Index  TypeNode
+}
+
+func (it *IndexedAccessType) typeNode()            {}
+func (it *IndexedAccessType) TokenLiteral() string { return it.Token.Literal }
+func (it *IndexedAccessType) String() string {
+	return it.Object.String() + "[" + it.Index.String() + "]"
+}
+
+// KeyofType is keyof T: the names of the properties of T.
+type KeyofType struct {
+	Span
+	Token token.Token // the 'keyof' token
+	Type  TypeNode
+}
+
+func (kt *KeyofType) typeNode()            {}
+func (kt *KeyofType) TokenLiteral() string { return kt.Token.Literal }
+func (kt *KeyofType) String() string       { return "keyof " + kt.Type.String() }
+
+// TypeofType is typeof x: the type of a variable, or of a property path
+// such as config.server.
+type TypeofType struct {
+	Span
+	Token token.Token // the 'typeof' token
+	Name  string
+}
+
+func (tt *TypeofType) typeNode()            {}
+func (tt *TypeofType) TokenLiteral() string { return tt.Token.Literal }
+func (tt *TypeofType) String() string       { return "typeof " + tt.Name }
+
+// TypePredicate is the return type of a type guard, x is Fish: the
+// function returns a boolean telling whether its parameter x is a Fish.
+type TypePredicate struct {
+	Span
+	Token     token.Token // the parameter name
+	ParamName string
+	Type      TypeNode
+}
+
+func (tp *TypePredicate) typeNode()            {}
+func (tp *TypePredicate) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TypePredicate) String() string       { return tp.ParamName + " is " + tp.Type.String() }
+
+// TypeParam is a type parameter of a generic function, class, interface
+// or type alias, with its constraint and default if it has them:
+// T extends object = {}.
+type TypeParam struct {
+	Span
+	Token      token.Token // the parameter's name
+	Name       string
+	Constraint TypeNode // nil if unconstrained
+	Default    TypeNode // nil if it has no default
+}
+
+func (tp *TypeParam) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TypeParam) String() string {
+	s := tp.Name
+	if tp.Constraint != nil {
+		s += " extends " + tp.Constraint.String()
+	}
+	if tp.Default != nil {
+		s += " = " + tp.Default.String()
+	}
+	return s
+}
+
+// typeParamList renders type parameters as <T, U>, or nothing if there
+// are none.
+func typeParamList(params []*TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	list := make([]string, len(params))
+	for i, param := range params {
+		list[i] = param.String()
+	}
+	return "<" + strings.Join(list, ", ") + ">"
+}
+
+func typeArgList(args []TypeNode) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return "<" + joinTypes(args, ", ") + ">"
+}
+
+// InterfaceDeclaration is interface Name<T> extends A, B { members }.
+type InterfaceDeclaration struct {
+	Span
+	Token      token.Token // the 'interface' token
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Extends    []TypeNode
+	Body       *ObjectType
+}
+
+func (id *InterfaceDeclaration) statementNode()       {}
+func (id *InterfaceDeclaration) TokenLiteral() string { return id.Token.Literal }
+func (id *InterfaceDeclaration) String() string {
+	var out bytes.Buffer
+	out.WriteString("interface " + id.Name.String() + typeParamList(id.TypeParams) + " ")
+	if len(id.Extends) > 0 {
+		out.WriteString("extends " + joinTypes(id.Extends, ", ") + " ")
+	}
+	out.WriteString(id.Body.String())
+	return out.String()
+}
+
+// TypeAliasDeclaration is type Name<T> = Type;
+type TypeAliasDeclaration struct {
+	Span
+	Token      token.Token // the 'type' token
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Type       TypeNode
+}
+
+func (ta *TypeAliasDeclaration) statementNode()       {}
+func (ta *TypeAliasDeclaration) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAliasDeclaration) String() string {
+	return "type " + ta.Name.String() + typeParamList(ta.TypeParams) + " = " + ta.Type.String() + ";"
+}
+
+// EnumDeclaration is enum Name { A, B = 2, C = "c" }, or a const enum,
+// whose members are inlined where they are used.
+type EnumDeclaration struct {
+	Span
+	Token   token.Token // the 'enum' token
+	Name    *Identifier
+	Const   bool
+	Members []*EnumMember
+}
+
+func (ed *EnumDeclaration) statementNode()       {}
+func (ed *EnumDeclaration) TokenLiteral() string { return ed.Token.Literal }
+func (ed *EnumDeclaration) String() string {
+	var out bytes.Buffer
+	if ed.Const {
+		out.WriteString("const ")
+	}
+	out.WriteString("enum " + ed.Name.String() + " { ")
+	members := make([]string, len(ed.Members))
+	for i, member := range ed.Members {
+		members[i] = member.String()
+	}
+	out.WriteString(strings.Join(members, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+// EnumMember is a member of an enum. Value is the literal it was worked
+// out to be by the parser, counting up from the previous member if it has
+// no initializer, or the initializer itself if it is computed at runtime.
+type EnumMember struct {
+	Span
+	Token    token.Token // the member's name
+	Name     string
+	Value    Expression
+	Computed bool
+}
+
+func (em *EnumMember) TokenLiteral() string { return em.Token.Literal }
+func (em *EnumMember) String() string       { return em.Name + " = " + em.Value.String() }
+
+// InlinedEnumMember is a use of a member of a const enum, Direction.Up,
+// replaced by its value when parsed.
+type InlinedEnumMember struct {
+	Span
+	Token  token.Token // the enum's name
+	Enum   string
+	Member string
+	Value  Expression
+}
+
+func (im *InlinedEnumMember) expressionNode()      {}
+func (im *InlinedEnumMember) TokenLiteral() string { return im.Token.Literal }
+func (im *InlinedEnumMember) String() string {
+	return im.Value.String() + " /* " + im.Enum + "." + im.Member + " */"
+}
+
+func joinTypes(types []TypeNode, sep string) string {
+	list := make([]string, len(types))
+	for i, t := range types {
+		list[i] = t.String()
+	}
+	return strings.Join(list, sep)
+}
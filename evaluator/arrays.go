@@ -0,0 +1,676 @@
+package evaluator
+
+import (
+	"math"
+	"slices"
+	"ts-engine/object"
+)
+
+var arrayClass *object.Class
+
+// newArrayClass creates the builtin Array class, whose prototype holds the
+// methods of arrays. Arrays are *object.Array values rather than Hashes;
+// their named properties inherit from the prototype.
+func newArrayClass() *object.Class {
+	class := &object.Class{
+		Name:      "Array",
+		Prototype: &object.Hash{},
+		Statics:   &object.Hash{},
+		Init:      initArray,
+	}
+	proto := class.Prototype
+	proto.Set("constructor", class)
+
+	// Mutators
+	proto.Set("push", arrayMethod("push", arrayPush))
+	proto.Set("pop", arrayMethod("pop", arrayPop))
+	proto.Set("shift", arrayMethod("shift", arrayShift))
+	proto.Set("unshift", arrayMethod("unshift", arrayUnshift))
+	proto.Set("splice", arrayMethod("splice", arraySplice))
+	proto.Set("sort", arrayMethod("sort", arraySort))
+	proto.Set("reverse", arrayMethod("reverse", arrayReverse))
+	proto.Set("fill", arrayMethod("fill", arrayFill))
+
+	// Methods that leave the array as it is
+	proto.Set("slice", arrayMethod("slice", arraySlice))
+	proto.Set("concat", arrayMethod("concat", arrayConcat))
+	proto.Set("join", arrayMethod("join", arrayJoin))
+	proto.Set("toString", arrayMethod("toString", func(arr *object.Array, args []object.Object) object.Object {
+		return joinElements(arr, ",")
+	}))
+	proto.Set("indexOf", arrayMethod("indexOf", arrayIndexOf))
+	proto.Set("lastIndexOf", arrayMethod("lastIndexOf", arrayLastIndexOf))
+	proto.Set("includes", arrayMethod("includes", arrayIncludes))
+	proto.Set("at", arrayMethod("at", arrayAt))
+	proto.Set("flat", arrayMethod("flat", arrayFlat))
+	proto.Set("toSorted", arrayMethod("toSorted", func(arr *object.Array, args []object.Object) object.Object {
+		return arraySort(copyArray(arr), args)
+	}))
+	proto.Set("toReversed", arrayMethod("toReversed", func(arr *object.Array, args []object.Object) object.Object {
+		return arrayReverse(copyArray(arr), args)
+	}))
+	proto.Set("toSpliced", arrayMethod("toSpliced", func(arr *object.Array, args []object.Object) object.Object {
+		spliced := copyArray(arr)
+		if result := arraySplice(spliced, args); isError(result) {
+			return result
+		}
+		return spliced
+	}))
+	proto.Set("with", arrayMethod("with", arrayWith))
+
+	// Methods taking a callback
+	proto.Set("forEach", arrayMethod("forEach", arrayForEach))
+	proto.Set("map", arrayMethod("map", arrayMap))
+	proto.Set("filter", arrayMethod("filter", arrayFilter))
+	proto.Set("flatMap", arrayMethod("flatMap", arrayFlatMap))
+	proto.Set("some", arrayMethod("some", arraySome))
+	proto.Set("every", arrayMethod("every", arrayEvery))
+	proto.Set("find", arrayMethod("find", arrayFinder(false, false)))
+	proto.Set("findIndex", arrayMethod("findIndex", arrayFinder(false, true)))
+	proto.Set("findLast", arrayMethod("findLast", arrayFinder(true, false)))
+	proto.Set("findLastIndex", arrayMethod("findLastIndex", arrayFinder(true, true)))
+	proto.Set("reduce", arrayMethod("reduce", arrayReducer(false)))
+	proto.Set("reduceRight", arrayMethod("reduceRight", arrayReducer(true)))
+
+	class.Statics.Set("isArray", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		_, ok := argument(args, 0).(*object.Array)
+		return nativeBoolToBooleanObject(ok)
+	}})
+	class.Statics.Set("from", &object.Builtin{Fn: arrayFrom})
+	class.Statics.Set("of", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return &object.Array{Elements: append([]object.Object{}, args...)}
+	}})
+
+	return class
+}
+
+// initArray implements new Array(length) and new Array(a, b, ...). The
+// array it returns takes the place of the new instance.
+func initArray(this *object.Hash, args []object.Object) object.Object {
+	arr := newArray(args)
+	if isError(arr) {
+		return arr
+	}
+	return &object.ReturnValue{Value: arr}
+}
+
+// newArray builds the array new Array(...args) gives.
+func newArray(args []object.Object) object.Object {
+	if len(args) != 1 {
+		return &object.Array{Elements: append([]object.Object{}, args...)}
+	}
+	n, ok := args[0].(*object.Number)
+	if !ok {
+		return &object.Array{Elements: []object.Object{args[0]}}
+	}
+	if err := checkArrayLength(n.Value); err != nil {
+		return err
+	}
+	elements := make([]object.Object, int(n.Value))
+	for i := range elements {
+		elements[i] = UNDEFINED
+	}
+	return &object.Array{Elements: elements}
+}
+
+// arrayMethod makes a method of Array.prototype, which must be called on
+// an array, from its implementation.
+func arrayMethod(name string, fn func(arr *object.Array, args []object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{Method: func(this object.Object, args ...object.Object) object.Object {
+		arr, ok := this.(*object.Array)
+		if !ok {
+			return newTypeError("Array.prototype.%s called on %s", name, this.Inspect())
+		}
+		return fn(arr, args)
+	}}
+}
+
+func copyArray(arr *object.Array) *object.Array {
+	return &object.Array{Elements: append([]object.Object{}, arr.Elements...)}
+}
+
+func arrayLength(arr *object.Array) object.Object {
+	return &object.Number{Value: float64(len(arr.Elements))}
+}
+
+// setArrayLength implements assigning arr.length, which truncates the
+// array or pads it with undefined.
+func setArrayLength(arr *object.Array, val object.Object) object.Object {
+	n, ok := val.(*object.Number)
+	if !ok {
+		return newRangeError("Invalid array length")
+	}
+	if err := checkArrayLength(n.Value); err != nil {
+		return err
+	}
+	length := int(n.Value)
+	if length <= len(arr.Elements) {
+		arr.Elements = arr.Elements[:length]
+	}
+	for len(arr.Elements) < length {
+		arr.Elements = append(arr.Elements, UNDEFINED)
+	}
+	return val
+}
+
+// relativeIndex converts an index argument of slice, splice and the like
+// to a position in an array of the given length. Negative indexes count
+// back from the end, and the result is clamped to [0, length]. An
+// undefined argument gives def.
+func relativeIndex(arg object.Object, length, def int) (int, object.Object) {
+	if arg.Type() == object.UNDEFINED_OBJ {
+		return def, nil
+	}
+	n, err := toNumber(arg)
+	if err != nil {
+		return 0, err
+	}
+	n = toIntegerOrInfinity(n)
+	if n < 0 {
+		n = math.Max(n+float64(length), 0)
+	}
+	return int(math.Min(n, float64(length))), nil
+}
+
+// toIntegerOrInfinity truncates a number towards zero, taking NaN as 0.
+func toIntegerOrInfinity(n float64) float64 {
+	if math.IsNaN(n) {
+		return 0
+	}
+	return math.Trunc(n)
+}
+
+func arrayPush(arr *object.Array, args []object.Object) object.Object {
+	arr.Elements = append(arr.Elements, args...)
+	return arrayLength(arr)
+}
+
+func arrayPop(arr *object.Array, args []object.Object) object.Object {
+	if len(arr.Elements) == 0 {
+		return UNDEFINED
+	}
+	last := arr.Elements[len(arr.Elements)-1]
+	arr.Elements = arr.Elements[:len(arr.Elements)-1]
+	return last
+}
+
+func arrayShift(arr *object.Array, args []object.Object) object.Object {
+	if len(arr.Elements) == 0 {
+		return UNDEFINED
+	}
+	first := arr.Elements[0]
+	arr.Elements = append([]object.Object{}, arr.Elements[1:]...)
+	return first
+}
+
+func arrayUnshift(arr *object.Array, args []object.Object) object.Object {
+	arr.Elements = append(append([]object.Object{}, args...), arr.Elements...)
+	return arrayLength(arr)
+}
+
+// arraySplice implements splice(start, deleteCount, ...items), which
+// replaces deleteCount elements from start with items and returns the
+// elements it removed.
+func arraySplice(arr *object.Array, args []object.Object) object.Object {
+	length := len(arr.Elements)
+	start, err := relativeIndex(argument(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	switch {
+	case len(args) == 1:
+		count = length - start
+	case len(args) > 1:
+		n, err := toNumber(args[1])
+		if err != nil {
+			return err
+		}
+		count = int(math.Min(math.Max(toIntegerOrInfinity(n), 0), float64(length-start)))
+	}
+
+	var items []object.Object
+	if len(args) > 2 {
+		items = args[2:]
+	}
+	removed := append([]object.Object{}, arr.Elements[start:start+count]...)
+	arr.Elements = slices.Concat(arr.Elements[:start], items, arr.Elements[start+count:])
+	return &object.Array{Elements: removed}
+}
+
+func arraySlice(arr *object.Array, args []object.Object) object.Object {
+	length := len(arr.Elements)
+	start, err := relativeIndex(argument(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+	end, err := relativeIndex(argument(args, 1), length, length)
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+	return &object.Array{Elements: append([]object.Object{}, arr.Elements[start:end]...)}
+}
+
+// arrayConcat joins the array with the arguments. Arrays among them are
+// spread into the result, and other values added as they are.
+func arrayConcat(arr *object.Array, args []object.Object) object.Object {
+	elements := append([]object.Object{}, arr.Elements...)
+	for _, arg := range args {
+		if other, ok := arg.(*object.Array); ok {
+			elements = append(elements, other.Elements...)
+		} else {
+			elements = append(elements, arg)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayJoin(arr *object.Array, args []object.Object) object.Object {
+	sep := argument(args, 0)
+	if sep.Type() == object.UNDEFINED_OBJ {
+		return joinElements(arr, ",")
+	}
+	str := toJSString(sep)
+	if isError(str) {
+		return str
+	}
+	return joinElements(arr, str.(*object.String).Value)
+}
+
+func arrayIndexOf(arr *object.Array, args []object.Object) object.Object {
+	from, err := relativeIndex(argument(args, 1), len(arr.Elements), 0)
+	if err != nil {
+		return err
+	}
+	for i := from; i < len(arr.Elements); i++ {
+		if strictEquals(arr.Elements[i], argument(args, 0)) {
+			return &object.Number{Value: float64(i)}
+		}
+	}
+	return &object.Number{Value: -1}
+}
+
+func arrayLastIndexOf(arr *object.Array, args []object.Object) object.Object {
+	from := len(arr.Elements) - 1
+	if len(args) > 1 {
+		n, err := toNumber(args[1])
+		if err != nil {
+			return err
+		}
+		n = toIntegerOrInfinity(n)
+		if n < 0 {
+			n += float64(len(arr.Elements))
+		}
+		from = int(math.Min(n, float64(from)))
+	}
+	for i := from; i >= 0; i-- {
+		if strictEquals(arr.Elements[i], argument(args, 0)) {
+			return &object.Number{Value: float64(i)}
+		}
+	}
+	return &object.Number{Value: -1}
+}
+
+// arrayIncludes differs from indexOf in that it finds NaN.
+func arrayIncludes(arr *object.Array, args []object.Object) object.Object {
+	from, err := relativeIndex(argument(args, 1), len(arr.Elements), 0)
+	if err != nil {
+		return err
+	}
+	for _, el := range arr.Elements[from:] {
+		if sameValueZero(el, argument(args, 0)) {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+// arrayAt implements at(index), where a negative index counts back from
+// the end.
+func arrayAt(arr *object.Array, args []object.Object) object.Object {
+	n, err := toNumber(argument(args, 0))
+	if err != nil {
+		return err
+	}
+	i := toIntegerOrInfinity(n)
+	if i < 0 {
+		i += float64(len(arr.Elements))
+	}
+	if i < 0 || i >= float64(len(arr.Elements)) {
+		return UNDEFINED
+	}
+	return arr.Elements[int(i)]
+}
+
+// arrayWith implements with(index, value), which returns a copy of the
+// array with the element at index replaced.
+func arrayWith(arr *object.Array, args []object.Object) object.Object {
+	n, err := toNumber(argument(args, 0))
+	if err != nil {
+		return err
+	}
+	i := toIntegerOrInfinity(n)
+	if i < 0 {
+		i += float64(len(arr.Elements))
+	}
+	if i < 0 || i >= float64(len(arr.Elements)) {
+		return newRangeError("Invalid index : %s", argument(args, 0).Inspect())
+	}
+	result := copyArray(arr)
+	result.Elements[int(i)] = argument(args, 1)
+	return result
+}
+
+func arrayFill(arr *object.Array, args []object.Object) object.Object {
+	length := len(arr.Elements)
+	start, err := relativeIndex(argument(args, 1), length, 0)
+	if err != nil {
+		return err
+	}
+	end, err := relativeIndex(argument(args, 2), length, length)
+	if err != nil {
+		return err
+	}
+	for i := start; i < end; i++ {
+		arr.Elements[i] = argument(args, 0)
+	}
+	return arr
+}
+
+func arrayReverse(arr *object.Array, args []object.Object) object.Object {
+	slices.Reverse(arr.Elements)
+	return arr
+}
+
+// arraySort sorts the array in place with a comparator, which returns a
+// negative number if its first argument comes first. Without one, elements
+// are compared as strings. Either way, undefined elements go last, and the
+// sort is stable.
+func arraySort(arr *object.Array, args []object.Object) object.Object {
+	compare := argument(args, 0)
+	if compare.Type() != object.UNDEFINED_OBJ && !isCallable(compare) {
+		return newTypeError("The comparison function must be either a function or undefined")
+	}
+
+	var failure object.Object
+	slices.SortStableFunc(arr.Elements, func(a, b object.Object) int {
+		switch {
+		case failure != nil:
+			return 0
+		case a.Type() == object.UNDEFINED_OBJ || b.Type() == object.UNDEFINED_OBJ:
+			return boolToInt(a.Type() == object.UNDEFINED_OBJ) - boolToInt(b.Type() == object.UNDEFINED_OBJ)
+		case isCallable(compare):
+			result := applyFunction(compare, []object.Object{a, b})
+			if isError(result) {
+				failure = result
+				return 0
+			}
+			n, err := toNumber(result)
+			if err != nil {
+				failure = err
+				return 0
+			}
+			switch {
+			case n < 0:
+				return -1
+			case n > 0:
+				return 1
+			}
+			return 0
+		}
+
+		as, bs := toJSString(a), toJSString(b)
+		if isError(as) {
+			failure = as
+			return 0
+		}
+		if isError(bs) {
+			failure = bs
+			return 0
+		}
+		return compareUTF16(as.(*object.String).Value, bs.(*object.String).Value)
+	})
+	if failure != nil {
+		return failure
+	}
+	return arr
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arrayFlat implements flat(depth), which spreads nested arrays into the
+// result, depth levels deep, 1 by default.
+func arrayFlat(arr *object.Array, args []object.Object) object.Object {
+	depth := 1.0
+	if arg := argument(args, 0); arg.Type() != object.UNDEFINED_OBJ {
+		n, err := toNumber(arg)
+		if err != nil {
+			return err
+		}
+		depth = toIntegerOrInfinity(n)
+	}
+	return &object.Array{Elements: flatten(arr.Elements, depth)}
+}
+
+func flatten(elements []object.Object, depth float64) []object.Object {
+	var flat []object.Object
+	for _, el := range elements {
+		if inner, ok := el.(*object.Array); ok && depth >= 1 {
+			flat = append(flat, flatten(inner.Elements, depth-1)...)
+			continue
+		}
+		flat = append(flat, el)
+	}
+	return flat
+}
+
+// eachElement calls the callback passed to an array method on each element
+// in turn, with the element, its index and the array, and 'this' bound to
+// the method's second argument. visit is given each result, and stops the
+// iteration by returning false. Elements appended by the callback are not
+// visited.
+func eachElement(arr *object.Array, args []object.Object, reverse bool, visit func(i int, el, result object.Object) bool) object.Object {
+	callback := argument(args, 0)
+	if !isCallable(callback) {
+		return newTypeError("%s is not a function", callback.Inspect())
+	}
+	thisArg := argument(args, 1)
+
+	length := len(arr.Elements)
+	for n := 0; n < length; n++ {
+		i := n
+		if reverse {
+			i = length - 1 - n
+		}
+		var el object.Object = UNDEFINED
+		if i < len(arr.Elements) {
+			el = arr.Elements[i]
+		}
+		result := applyMethod(callback, thisArg, []object.Object{el, &object.Number{Value: float64(i)}, arr})
+		if isError(result) {
+			return result
+		}
+		if !visit(i, el, result) {
+			break
+		}
+	}
+	return nil
+}
+
+func arrayForEach(arr *object.Array, args []object.Object) object.Object {
+	if err := eachElement(arr, args, false, func(int, object.Object, object.Object) bool { return true }); err != nil {
+		return err
+	}
+	return UNDEFINED
+}
+
+func arrayMap(arr *object.Array, args []object.Object) object.Object {
+	mapped := make([]object.Object, 0, len(arr.Elements))
+	err := eachElement(arr, args, false, func(i int, el, result object.Object) bool {
+		mapped = append(mapped, result)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: mapped}
+}
+
+func arrayFilter(arr *object.Array, args []object.Object) object.Object {
+	var kept []object.Object
+	err := eachElement(arr, args, false, func(i int, el, result object.Object) bool {
+		if isTruthy(result) {
+			kept = append(kept, el)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: kept}
+}
+
+// arrayFlatMap maps each element and flattens the results one level.
+func arrayFlatMap(arr *object.Array, args []object.Object) object.Object {
+	mapped := arrayMap(arr, args)
+	if isError(mapped) {
+		return mapped
+	}
+	return &object.Array{Elements: flatten(mapped.(*object.Array).Elements, 1)}
+}
+
+func arraySome(arr *object.Array, args []object.Object) object.Object {
+	found := false
+	err := eachElement(arr, args, false, func(i int, el, result object.Object) bool {
+		found = isTruthy(result)
+		return !found
+	})
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(found)
+}
+
+func arrayEvery(arr *object.Array, args []object.Object) object.Object {
+	all := true
+	err := eachElement(arr, args, false, func(i int, el, result object.Object) bool {
+		all = isTruthy(result)
+		return all
+	})
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(all)
+}
+
+// arrayFinder makes find, findIndex, findLast and findLastIndex, which give
+// the first element the callback accepts, searching from the end if last
+// is set, or its index if index is set.
+func arrayFinder(last, index bool) func(arr *object.Array, args []object.Object) object.Object {
+	return func(arr *object.Array, args []object.Object) object.Object {
+		var found object.Object = UNDEFINED
+		if index {
+			found = &object.Number{Value: -1}
+		}
+		err := eachElement(arr, args, last, func(i int, el, result object.Object) bool {
+			if !isTruthy(result) {
+				return true
+			}
+			if index {
+				found = &object.Number{Value: float64(i)}
+			} else {
+				found = el
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+		return found
+	}
+}
+
+// arrayReducer makes reduce, or reduceRight if right is set, which fold the
+// elements into an accumulator, starting from the second argument or, if
+// there is none, the first element.
+func arrayReducer(right bool) func(arr *object.Array, args []object.Object) object.Object {
+	return func(arr *object.Array, args []object.Object) object.Object {
+		callback := argument(args, 0)
+		if !isCallable(callback) {
+			return newTypeError("%s is not a function", callback.Inspect())
+		}
+
+		indexes := make([]int, len(arr.Elements))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		if right {
+			slices.Reverse(indexes)
+		}
+
+		var acc object.Object
+		if len(args) > 1 {
+			acc = args[1]
+		} else {
+			if len(indexes) == 0 {
+				return newTypeError("Reduce of empty array with no initial value")
+			}
+			acc = arr.Elements[indexes[0]]
+			indexes = indexes[1:]
+		}
+
+		for _, i := range indexes {
+			if i >= len(arr.Elements) {
+				continue
+			}
+			acc = applyFunction(callback, []object.Object{acc, arr.Elements[i], &object.Number{Value: float64(i)}, arr})
+			if isError(acc) {
+				return acc
+			}
+		}
+		return acc
+	}
+}
+
+// arrayFrom implements Array.from(items, mapFn), which makes an array of
+// the values of an iterable, or of the elements of an array-like object
+// such as { length: 3 }, passing each through mapFn if it is given.
+func arrayFrom(args ...object.Object) object.Object {
+	items := argument(args, 0)
+	if isNullish(items) {
+		return newTypeError("%s is not iterable", items.Inspect())
+	}
+
+	elements, ok := iterableValues(items)
+	if !ok {
+		elements = []object.Object{}
+		if _, isObject := properties(items); isObject {
+			n, err := toNumber(getProperty(items, "length", items))
+			if err != nil {
+				return err
+			}
+			length := math.Max(toIntegerOrInfinity(n), 0)
+			if err := checkArrayLength(length); err != nil {
+				return err
+			}
+			for i := 0; i < int(length); i++ {
+				elements = append(elements, getProperty(items, propertyKey(&object.Number{Value: float64(i)}), items))
+			}
+		}
+	}
+
+	arr := &object.Array{Elements: elements}
+	if mapFn := argument(args, 1); mapFn.Type() != object.UNDEFINED_OBJ {
+		return arrayMap(arr, args[1:])
+	}
+	return arr
+}
@@ -0,0 +1,48 @@
+package evaluator
+
+import "testing"
+
+func TestArrayMethods(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"length", `[1, 2, 3].length`, "3"},
+		{"setting length", `let a = [1, 2, 3]; a.length = 1; a`, "[1]"},
+		{"push and pop", `let a = [1]; a.push(2, 3); a.pop() + a.length`, "5"},
+		{"shift and unshift", `let a = [2]; a.unshift(0, 1); a.shift(); a`, "[1, 2]"},
+		{"splice", `let a = [1, 2, 3, 4]; let removed = a.splice(1, 2, "x"); [a, removed]`, "[[1, x, 4], [2, 3]]"},
+		{"slice", `[1, 2, 3, 4].slice(1, -1)`, "[2, 3]"},
+		{"concat", `[1].concat([2, 3], 4)`, "[1, 2, 3, 4]"},
+		{"indexOf and includes", `let a = [1, NaN]; [a.indexOf(NaN), a.includes(NaN), a.lastIndexOf(1)]`, "[-1, true, 0]"},
+		{"find", `[[1, 5, 8].find(x => x > 4), [1].find(x => x > 4), [1, 5, 8].findLast(x => x > 4)]`, "[5, undefined, 8]"},
+		{"findIndex", `[[1, 5].findIndex(x => x > 4), [1, 5].findLastIndex(x => x > 9)]`, "[1, -1]"},
+		{"filter and map", `[1, 2, 3, 4].filter(x => x % 2 == 0).map((x, i) => x * 10 + i)`, "[20, 41]"},
+		{"reduce", `[1, 2, 3].reduce((s, x) => s + x, 10)`, "16"},
+		{"reduce without initial value", `["a", "b"].reduceRight((s, x) => s + x)`, "ba"},
+		{"reduce of empty array", `[].reduce((s, x) => s + x);`,
+			"ERROR: TypeError: Reduce of empty array with no initial value"},
+		{"forEach", `let s = 0; [1, 2].forEach(x => { s += x; }); s`, "3"},
+		{"some and every", `[[1, 2].some(x => x > 1), [1, 2].every(x => x > 1)]`, "[true, false]"},
+		{"default sort compares strings", `[10, 9, 1].sort()`, "[1, 10, 9]"},
+		{"sort with comparator", `[3, 1, 2].sort((a, b) => b - a)`, "[3, 2, 1]"},
+		{"sort puts undefined last", `[undefined, 2, 1].sort()`, "[1, 2, undefined]"},
+		{"reverse and join", `[1, 2, 3].reverse().join("-")`, "3-2-1"},
+		{"flat and flatMap", `[[1, [2]], 3].flat().concat([1, 2].flatMap(x => [x, x]))`, "[1, [2], 3, 1, 1, 2, 2]"},
+		{"at", `[[1, 2, 3].at(-1), [1].at(5)]`, "[3, undefined]"},
+		{"copying methods", `let a = [3, 1, 2]; [a.toSorted(), a.toReversed(), a.with(0, 9), a]`,
+			"[[1, 2, 3], [2, 1, 3], [9, 1, 2], [3, 1, 2]]"},
+		{"callbacks see the array", `[1].map((x, i, arr) => arr.length)`, "[1]"},
+		{"closures", `let k = 3; [1, 2].map(function(x) { return x * k; })`, "[3, 6]"},
+	})
+}
+
+func TestArrayGlobal(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"isArray", `[Array.isArray([]), Array.isArray({ length: 0 })]`, "[true, false]"},
+		{"from an iterable", `Array.from("ab")`, "[a, b]"},
+		{"from an array-like", `Array.from({ length: 3 }, (_, i) => i * 2)`, "[0, 2, 4]"},
+		{"of", `Array.of(7)`, "[7]"},
+		{"new Array with a length", `new Array(3).length`, "3"},
+		{"new Array with elements", `new Array(1, 2)`, "[1, 2]"},
+		{"invalid length", `new Array(-1);`, "ERROR: RangeError: Invalid array length"},
+		{"instanceof", `[] instanceof Array`, "true"},
+	})
+}
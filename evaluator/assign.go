@@ -0,0 +1,244 @@
+package evaluator
+
+import (
+	"math"
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// maxArrayLength is the largest length of a JavaScript array.
+const maxArrayLength = 1<<32 - 1
+
+// maxDenseLength is the largest length an array can be given by writing
+// past its end, setting its length or new Array(n). Arrays are stored
+// densely, so a[1e9] = 1 would fill memory with undefined rather than
+// leave a hole.
+const maxDenseLength = 1 << 24
+
+// checkArrayLength checks that n is a valid array length that fits in
+// maxDenseLength.
+func checkArrayLength(n float64) *object.Error {
+	switch {
+	case n < 0 || n != math.Trunc(n) || n >= maxArrayLength:
+		return newRangeError("Invalid array length")
+	case n > maxDenseLength:
+		return newRangeError("Array length %s exceeds the supported maximum of %d", object.FormatNumber(n), maxDenseLength)
+	}
+	return nil
+}
+
+// reference is an assignable location: a variable, an object property or
+// an array element. The object and key are evaluated once, so that in
+// obj[next()] += 1 next is only called once.
+type reference struct {
+	env  *object.Environment
+	name string // variable name, when obj is nil
+
+	obj     object.Object       // object, class or array holding the property
+	key     object.Object       // property key or array index
+	this    object.Object       // receiver for accessors: obj, or 'this' for super.x
+	private *object.PrivateName // the member, when key is a #private name
+}
+
+func evalReference(node ast.Expression, env *object.Environment) (*reference, object.Object) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return &reference{env: env, name: node.Value}, nil
+
+	case *ast.InfixExpression:
+		if node.Operator != "." {
+			break
+		}
+		obj := Eval(node.Left, env)
+		if isError(obj) {
+			return nil, obj
+		}
+		this := obj
+		if _, ok := node.Left.(*ast.SuperExpression); ok {
+			this = evalThis(env)
+		}
+		key := node.Right.(*ast.Identifier).Value
+		ref := &reference{obj: obj, key: &object.String{Value: key}, this: this}
+		if isPrivateName(key) {
+			name, err := privateName(key, env)
+			if err != nil {
+				return nil, err
+			}
+			ref.private = &name
+		}
+		return ref, nil
+
+	case *ast.IndexExpression:
+		obj := Eval(node.Left, env)
+		if isError(obj) {
+			return nil, obj
+		}
+		key := Eval(node.Index, env)
+		if isError(key) {
+			return nil, key
+		}
+		return &reference{obj: obj, key: key, this: obj}, nil
+	}
+
+	return nil, newSyntaxError("invalid assignment target")
+}
+
+func (r *reference) get() object.Object {
+	switch {
+	case r.obj == nil:
+		return lookupVariable(r.name, r.env)
+	case r.private != nil:
+		return getPrivateMember(r.obj, *r.private, r.this)
+	}
+
+	key := elementIndex(r.obj, r.key)
+	if _, ok := key.(*object.String); ok {
+		return getProperty(r.obj, propertyKey(key), r.this)
+	}
+	return evalIndexExpression(r.obj, key)
+}
+
+func (r *reference) set(val object.Object) object.Object {
+	switch {
+	case r.obj == nil:
+		return assignVariable(r.name, val, r.env)
+	case r.private != nil:
+		return setPrivateMember(r.obj, *r.private, val)
+	}
+
+	key := elementIndex(r.obj, r.key)
+	if arr, ok := r.obj.(*object.Array); ok && key.Type() == object.NUMBER_OBJ {
+		return setArrayElement(arr, key, val)
+	}
+	// Assigning to super.x sets x on 'this'
+	return setProperty(r.this, propertyKey(r.key), val)
+}
+
+// propertyKey converts an index value to a property name.
+func propertyKey(key object.Object) string {
+	if s, ok := key.(*object.String); ok {
+		return s.Value
+	}
+	return key.Inspect()
+}
+
+// setArrayElement stores val at index, growing the array as needed. The
+// gap left by writing past the end is filled with null.
+func setArrayElement(arr *object.Array, index object.Object, val object.Object) object.Object {
+	n, ok := index.(*object.Number)
+	if !ok || n.Value < 0 || n.Value != math.Trunc(n.Value) {
+		return newTypeError("invalid array index: %s", index.Inspect())
+	}
+	if n.Value >= maxArrayLength {
+		return newRangeError("invalid array length")
+	}
+	if err := checkArrayLength(n.Value + 1); err != nil {
+		return err
+	}
+
+	i := int(n.Value)
+	for len(arr.Elements) <= i {
+		arr.Elements = append(arr.Elements, UNDEFINED)
+	}
+	arr.Elements[i] = val
+
+	return val
+}
+
+// evalAssignmentExpression evaluates = and the compound assignments. The
+// logical ones (&&=, ||=, ??=) only evaluate and assign the right-hand
+// side when the current value does not already decide the result.
+func evalAssignmentExpression(node *ast.AssignmentExpression, env *object.Environment) object.Object {
+	// [a, b] = [b, a]
+	switch node.Left.(type) {
+	case *ast.ObjectPattern, *ast.ArrayPattern:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return assignTo(node.Left, val, env)
+	}
+
+	ref, err := evalReference(node.Left, env)
+	if err != nil {
+		return err
+	}
+
+	if node.Operator == "=" {
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return ref.set(val)
+	}
+
+	current := ref.get()
+	if isError(current) {
+		return current
+	}
+
+	switch node.Operator {
+	case "&&=":
+		if !isTruthy(current) {
+			return current
+		}
+	case "||=":
+		if isTruthy(current) {
+			return current
+		}
+	case "??=":
+		if !isNullish(current) {
+			return current
+		}
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch node.Operator {
+	case "&&=", "||=", "??=":
+	default:
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	return ref.set(val)
+}
+
+// evalUpdateExpression evaluates ++ and --. The prefix forms produce the
+// new value and the postfix forms the old one.
+func evalUpdateExpression(node *ast.UpdateExpression, env *object.Environment) object.Object {
+	ref, err := evalReference(node.Target, env)
+	if err != nil {
+		return err
+	}
+
+	current := ref.get()
+	if isError(current) {
+		return current
+	}
+	value, err := toNumber(current)
+	if err != nil {
+		return err
+	}
+	n := &object.Number{Value: value}
+
+	updated := &object.Number{Value: value + 1}
+	if node.Operator == "--" {
+		updated = &object.Number{Value: value - 1}
+	}
+
+	if result := ref.set(updated); isError(result) {
+		return result
+	}
+
+	if node.Prefix {
+		return updated
+	}
+	return n
+}
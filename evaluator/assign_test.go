@@ -0,0 +1,30 @@
+package evaluator
+
+import "testing"
+
+func TestAssignment(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"dot assignment", `let o = {}; o.a = 1; o.a`, "1"},
+		{"bracket assignment", `let o = {}; o["b"] = 2; o.b`, "2"},
+		{"nested", `let o = { inner: {} }; o.inner.x = 3; o.inner.x`, "3"},
+		{"array index", `let a = [1, 2]; a[0] = 5; a`, "[5, 2]"},
+		{"string index", `let a = [1, 2]; a["1"] = 9; [a, a["0"], a["01"]]`, "[[1, 9], 1, undefined]"},
+		{"non-index key of an array", `let a = [1]; a["01"] = 2; [a.length, a["01"]]`, "[1, 2]"},
+		{"growing an array", `let a = [1]; a[3] = 4; a[3]`, "4"},
+		{"growth is capped", `let a = []; a[2 ** 30] = 1;`,
+			"ERROR: RangeError: Array length 1073741825 exceeds the supported maximum of 16777216"},
+		{"cyclic array", `let a = [1]; a[1] = a; a`, "[1, [Circular]]"},
+		{"cyclic object", `let o = {}; o.self = o; o`, "{self: [Circular]}"},
+		{"assignment gives its value", `let o = {}; (o.x = 7) + 1`, "8"},
+		{"compound", `let x = 10; x += 5; x -= 3; x *= 2; x`, "24"},
+		{"compound on a property", `let o = { n: 1 }; o.n += 2; o.n`, "3"},
+		{"logical assignment", `
+			let o = { b: false, c: 1 };
+			o.a ??= "d"; o.b ||= 2; o.c &&= 3;
+			o.a + o.b + o.c`, "d23"},
+		{"??= keeps a value", `let x = 0; x ??= 5; x`, "0"},
+		{"postfix", `let i = 1; let j = i++; j * 10 + i`, "12"},
+		{"prefix", `let i = 1; let j = --i; j * 10 + i`, "0"},
+		{"increment an element", `let a = [1]; a[0]++; ++a[0]`, "3"},
+	})
+}
@@ -2,8 +2,11 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
+	tshttp "ts-engine/http"
 	"ts-engine/object"
 )
 
@@ -30,8 +33,8 @@ func createHttpServer(args ...object.Object) object.Object {
 
 			portVal := listenArgs[0]
 			var port int
-			if intObj, ok := portVal.(*object.Integer); ok {
-				port = int(intObj.Value)
+			if num, ok := portVal.(*object.Number); ok && num.Value == math.Trunc(num.Value) {
+				port = int(num.Value)
 			} else {
 				return newError("port must be integer")
 			}
@@ -45,101 +48,154 @@ func createHttpServer(args ...object.Object) object.Object {
 			}
 
 			addr := ":" + strconv.Itoa(port)
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return newError("server error: %s", err)
+			}
+			fmt.Printf("Starting server on %s...\n", addr)
 
-			// Execute listen callback immediately just to simulate "started" if valid
-			// In Node, this happens after 'listening' event. We'll just call it before blocking or async.
-			// But ListenAndServe blocks. So we should probably call it before?
-			// But if it fails, we shouldn't have called it.
-			// Ideally, run ListenAndServe in goroutine.
+			// The server keeps the program running. Requests arrive on the
+			// server's goroutines and are handled as tasks on the event
+			// loop; each waits until the handler ends the response.
+			loop.hold()
+			if listenCb != nil {
+				loop.post(func() object.Object {
+					return applyFunction(listenCb, []object.Object{})
+				})
+			}
 
-			go func() {
-				// We need a way to keep main alive?
-				// For now, let's assume valid server script ends with .listen() and we want to BLOCK.
-				// If we run in goroutine, the script finishes and program exits.
-				// The user provided code has .listen() at the end.
-			}()
+			go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				done := make(chan struct{})
+				loop.post(func() object.Object {
+					return handleRequest(handlerFn, w, r, done)
+				})
+				<-done
+			}))
 
-			// If we block, we can't run the callback?
-			// The callback is usually "Server running...".
-			// If we block, we never run it.
+			return NULL
+		},
+	}
 
-			// Simple hack: Run callback, then block.
-			if listenCb != nil {
-				applyFunction(listenCb, []object.Object{})
-			}
+	return &object.Hash{Pairs: serverMap}
+}
 
-			fmt.Printf("Starting server on %s...\n", addr)
-			err := http.ListenAndServe(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				// 1. Convert Request
-				tsReq := &object.Hash{
-					Pairs: map[string]object.Object{
-						"url":    &object.String{Value: r.URL.String()},
-						"method": &object.String{Value: r.Method},
-					},
-				}
+// handleRequest calls the server's handler with request and response
+// objects for r. done is closed when the handler ends the response; the
+// response ignores writes after that, as the Go handler has returned.
+func handleRequest(handlerFn *object.Function, w http.ResponseWriter, r *http.Request, done chan struct{}) object.Object {
+	ended := false
+
+	// 1. Convert Request
+	tsReq := &object.Hash{
+		Pairs: map[string]object.Object{
+			"url":    &object.String{Value: r.URL.String()},
+			"method": &object.String{Value: r.Method},
+		},
+	}
 
-				// 2. Wrap Response
-				// We need methods: writeHead, end
-				// We can't use a simple Hash because it needs methods that close over 'w'.
-				// But we can return a Hash full of Builtins!
-
-				tsRes := &object.Hash{
-					Pairs: map[string]object.Object{
-						"writeHead": &object.Builtin{
-							Fn: func(args ...object.Object) object.Object {
-								if len(args) < 1 {
-									return NULL
-								}
-								status := 200
-								if s, ok := args[0].(*object.Integer); ok {
-									status = int(s.Value)
+	// 2. Wrap Response
+	// We need methods: writeHead, end
+	// We can't use a simple Hash because it needs methods that close over 'w'.
+	// But we can return a Hash full of Builtins!
+
+	tsRes := &object.Hash{
+		Pairs: map[string]object.Object{
+			"writeHead": &object.Builtin{
+				Fn: func(args ...object.Object) object.Object {
+					if len(args) < 1 || ended {
+						return NULL
+					}
+					status := 200
+					if s, ok := args[0].(*object.Number); ok {
+						status = int(s.Value)
+					}
+
+					// Handle headers (arg 1)
+					if len(args) > 1 {
+						if headers, ok := args[1].(*object.Hash); ok {
+							for key, val := range headers.Pairs {
+								// We only support String or Number values for headers for now
+								if strVal, ok := val.(*object.String); ok {
+									w.Header().Set(key, strVal.Value)
+								} else if numVal, ok := val.(*object.Number); ok {
+									w.Header().Set(key, object.FormatNumber(numVal.Value))
 								}
+							}
+						}
+					}
+
+					w.WriteHeader(status)
+					return NULL
+				},
+			},
+			"end": &object.Builtin{
+				Fn: func(args ...object.Object) object.Object {
+					if ended {
+						return NULL
+					}
+					// Support calling end with data: res.end("data")
+					if len(args) > 0 {
+						if s, ok := args[0].(*object.String); ok {
+							w.Write([]byte(s.Value))
+						} else {
+							// Fallback for non-string, e.g. integer or just Inspect
+							w.Write([]byte(args[0].Inspect()))
+						}
+					}
+					ended = true
+					close(done)
+					return NULL
+				},
+			},
+		},
+	}
 
-								// Handle headers (arg 1)
-								if len(args) > 1 {
-									if headers, ok := args[1].(*object.Hash); ok {
-										for key, val := range headers.Pairs {
-											// We only support String or Integer values for headers for now
-											if strVal, ok := val.(*object.String); ok {
-												w.Header().Set(key, strVal.Value)
-											} else if intVal, ok := val.(*object.Integer); ok {
-												w.Header().Set(key, strconv.Itoa(int(intVal.Value)))
-											}
-										}
-									}
-								}
+	// 3. Call Handler
+	return applyFunction(handlerFn, []object.Object{tsReq, tsRes})
+}
 
-								w.WriteHeader(status)
-								return NULL
-							},
-						},
-						"end": &object.Builtin{
-							Fn: func(args ...object.Object) object.Object {
-								// Support calling end with data: res.end("data")
-								if len(args) > 0 {
-									if s, ok := args[0].(*object.String); ok {
-										w.Write([]byte(s.Value))
-									} else {
-										// Fallback for non-string, e.g. integer or just Inspect
-										w.Write([]byte(args[0].Inspect()))
-									}
-								}
-								return NULL
-							},
-						},
-					},
+// fetch starts an HTTP request on another goroutine and returns a promise
+// of the response. As in the Fetch API, reading the body with text() or
+// json() also gives a promise.
+func fetch(args ...object.Object) object.Object {
+	promise := newPromise()
+
+	loop.hold()
+	go func() {
+		response := tshttp.Fetch(args...)
+		loop.post(func() object.Object {
+			loop.release()
+
+			if err, ok := response.(*object.Error); ok {
+				rejectPromise(promise, err)
+				return NULL
+			}
+			if hash, ok := response.(*object.Hash); ok {
+				for _, name := range []string{"text", "json"} {
+					if method, ok := hash.Pairs[name].(*object.Builtin); ok {
+						hash.Set(name, promisify(method))
+					}
 				}
+			}
+			resolvePromise(promise, response)
+			return NULL
+		})
+	}()
 
-				// 3. Call Handler
-				applyFunction(handlerFn, []object.Object{tsReq, tsRes})
-			}))
+	return promise
+}
 
-			if err != nil {
-				return newError("server error: %s", err)
+// promisify wraps a builtin so that it returns a promise of its result.
+func promisify(fn *object.Builtin) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			promise := newPromise()
+			if result := fn.Fn(args...); isError(result) {
+				rejectPromise(promise, result.(*object.Error))
+			} else {
+				resolvePromise(promise, result)
 			}
-			return NULL
+			return promise
 		},
 	}
-
-	return &object.Hash{Pairs: serverMap}
 }
@@ -0,0 +1,545 @@
+package evaluator
+
+import (
+	"math"
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/object"
+	"ts-engine/token"
+	"unicode/utf16"
+)
+
+// evalClassLiteral creates a class from its declaration. Methods and
+// accessors go on the prototype (or the statics), static fields are
+// initialized now and instance fields each time an instance is built.
+func evalClassLiteral(node *ast.ClassLiteral, env *object.Environment) object.Object {
+	class := &object.Class{Prototype: &object.Hash{}, Statics: &object.Hash{}}
+	if node.Name != nil {
+		class.Name = node.Name.Value
+	}
+
+	if node.SuperClass != nil {
+		super := Eval(node.SuperClass, env)
+		if isError(super) {
+			return super
+		}
+		parent, ok := super.(*object.Class)
+		if !ok {
+			return newTypeError("Class extends value %s is not a constructor", super.Inspect())
+		}
+		class.Super = parent
+		class.Prototype.Proto = parent.Prototype
+		class.Statics.Proto = parent.Statics
+	}
+	class.Prototype.Set("constructor", class)
+
+	// The class body has its own scope, where the class name is bound and
+	// "super" (a keyword, so never a user binding) names the class being
+	// defined for super calls and lookups in its methods.
+	class.Env = object.NewEnclosedEnvironment(env)
+	class.Env.Set("super", class)
+	if node.Name != nil {
+		class.Env.Set(class.Name, class)
+	}
+	// Its #private names are bound to it too, so that a #name in a method
+	// resolves to the innermost enclosing class declaring it.
+	for _, member := range node.Members {
+		if isPrivateName(member.Name) {
+			class.Env.Set(member.Name, class)
+		}
+	}
+
+	for _, member := range node.Members {
+		switch member.Kind {
+		case ast.ClassConstructor:
+			class.Constructor = newMethod(member, class)
+			class.ParameterProperties = member.ParameterProperties
+
+		case ast.ClassField:
+			if !member.Static {
+				class.Fields = append(class.Fields, member)
+				continue
+			}
+			val := evalFieldInitializer(member, class, class)
+			if isError(val) {
+				return val
+			}
+			defineField(class.Statics, class, member.Name, val)
+
+		default:
+			home := class.Prototype
+			if member.Static {
+				home = class.Statics
+			}
+			defineMethod(home, class, member, newMethod(member, class))
+		}
+	}
+
+	if node.Name != nil {
+		env.Set(class.Name, class)
+	}
+
+	return class
+}
+
+func newMethod(member *ast.ClassMember, class *object.Class) *object.Function {
+	return &object.Function{
+		Name:       member.Name,
+		Parameters: member.Value.Parameters,
+		Body:       member.Value.Body,
+		Env:        class.Env,
+		Async:      member.Value.Async,
+		Rest:       member.Value.Rest,
+	}
+}
+
+// defineMethod adds a method or accessor to a prototype or statics object.
+// A getter and setter of the same name share one Accessor.
+func defineMethod(home *object.Hash, class *object.Class, member *ast.ClassMember, fn *object.Function) {
+	var val object.Object = fn
+
+	if member.Kind == ast.ClassGetter || member.Kind == ast.ClassSetter {
+		var current object.Object
+		if isPrivateName(member.Name) {
+			current = home.Private[object.PrivateName{Class: class, Name: member.Name}]
+		} else {
+			current, _ = home.GetOwn(member.Name)
+		}
+		accessor, ok := current.(*object.Accessor)
+		if !ok {
+			accessor = &object.Accessor{}
+		}
+		if member.Kind == ast.ClassGetter {
+			accessor.Getter = fn
+		} else {
+			accessor.Setter = fn
+		}
+		val = accessor
+	}
+
+	defineField(home, class, member.Name, val)
+}
+
+// defineField creates or overwrites an own property without invoking
+// setters, as class fields do. A #private name is a member of class.
+func defineField(obj *object.Hash, class *object.Class, name string, val object.Object) {
+	if isPrivateName(name) {
+		if obj.Private == nil {
+			obj.Private = make(map[object.PrivateName]object.Object)
+		}
+		obj.Private[object.PrivateName{Class: class, Name: name}] = val
+		return
+	}
+	obj.Set(name, val)
+}
+
+func isPrivateName(name string) bool {
+	return strings.HasPrefix(name, "#")
+}
+
+// privateName resolves a #private name used in env to the member of the
+// innermost enclosing class that declares it.
+func privateName(name string, env *object.Environment) (object.PrivateName, *object.Error) {
+	if class, ok := env.Get(name); ok {
+		return object.PrivateName{Class: class.(*object.Class), Name: name}, nil
+	}
+	return object.PrivateName{}, newSyntaxError("Private field '%s' must be declared in an enclosing class", name)
+}
+
+// evalFieldInitializer evaluates a field's initializer with 'this' bound to
+// the object receiving the field: the instance, or the class for statics.
+func evalFieldInitializer(member *ast.ClassMember, class *object.Class, this object.Object) object.Object {
+	if member.Init == nil {
+		return UNDEFINED
+	}
+
+	env := object.NewEnclosedEnvironment(class.Env)
+	env.Set("this", this)
+
+	val := Eval(member.Init, env)
+	if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+		fn.Name = member.Name
+	}
+	return val
+}
+
+// newInstance implements new for classes, and calls of builtin classes
+// such as Error which may omit new.
+func newInstance(class *object.Class, args []object.Object, pos token.Position) object.Object {
+	this := &object.Hash{Proto: class.Prototype}
+
+	if err := enterCall(); err != nil {
+		return err
+	}
+	defer exitCall()
+	callStack = append(callStack, object.CallFrame{Function: functionName(class), Call: pos})
+	result := construct(class, this, args)
+	callStack = callStack[:len(callStack)-1]
+
+	if isError(result) {
+		return result
+	}
+	// A constructor may return a different object to use instead
+	if override, ok := result.(*object.ReturnValue); ok && !isPrimitive(override.Value) {
+		return override.Value
+	}
+	return this
+}
+
+// construct runs the constructor of class on this. A derived class's
+// constructor initializes its own fields when it calls super(); a base
+// class's fields are initialized before its constructor body runs.
+//
+// An explicit return in the constructor gives its *object.ReturnValue, so
+// that it can be told apart from the value of the last statement of the
+// body, which is not the constructor's result.
+func construct(class *object.Class, this *object.Hash, args []object.Object) object.Object {
+	if class.Init != nil {
+		return class.Init(this, args)
+	}
+
+	if class.Constructor == nil {
+		// The implicit constructor passes its arguments on to the superclass
+		if class.Super != nil {
+			if result := construct(class.Super, this, args); isError(result) {
+				return result
+			}
+		}
+		return initializeInstance(class, this, nil)
+	}
+
+	if class.Super == nil {
+		env, err := extendFunctionEnv(class.Constructor, this, args)
+		if err != nil {
+			return err
+		}
+		if result := initializeInstance(class, this, env); isError(result) {
+			return result
+		}
+		result := evalBlockStatement(class.Constructor.Body, env)
+		if _, ok := result.(*object.ReturnValue); ok || isError(result) {
+			return result
+		}
+		return NULL
+	}
+
+	// In a derived constructor 'this' is uninitialized until super() binds
+	// it to the instance, which "new" (a keyword, so never a user binding)
+	// holds until then.
+	env, err := extendFunctionEnv(class.Constructor, uninitialized, args)
+	if err != nil {
+		return err
+	}
+	env.Set("new", this)
+
+	result := evalBlockStatement(class.Constructor.Body, env)
+	if isError(result) {
+		return result
+	}
+	if ret, ok := result.(*object.ReturnValue); ok && !isPrimitive(ret.Value) {
+		return result
+	}
+	if current, _ := env.GetCurrent("this"); current == uninitialized {
+		return newReferenceError(uninitializedThis)
+	}
+	return NULL
+}
+
+// uninitializedThis is the error for using 'this' in a derived constructor
+// before super() has been called.
+const uninitializedThis = "Must call super constructor in derived class before accessing 'this' or returning from derived constructor"
+
+// initializeInstance defines the fields declared by class on a new
+// instance. env is the constructor's scope, holding the values of its
+// parameter properties.
+func initializeInstance(class *object.Class, this *object.Hash, env *object.Environment) object.Object {
+	for _, field := range class.Fields {
+		val := evalFieldInitializer(field, class, this)
+		if isError(val) {
+			return val
+		}
+		defineField(this, class, field.Name, val)
+	}
+
+	for _, name := range class.ParameterProperties {
+		val, _ := env.Get(name)
+		defineField(this, class, name, val)
+	}
+
+	return NULL
+}
+
+// currentClass returns the class whose body encloses env.
+func currentClass(env *object.Environment) (*object.Class, *object.Error) {
+	if val, ok := env.Get("super"); ok {
+		if class, ok := val.(*object.Class); ok && class.Super != nil {
+			return class, nil
+		}
+	}
+	return nil, newSyntaxError("'super' keyword unexpected here")
+}
+
+// evalSuperCall runs the superclass constructor on 'this', then initializes
+// the fields of the calling class. Its value is 'this'.
+func evalSuperCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	class, err := currentClass(env)
+	if err != nil {
+		return err
+	}
+	newObj, _ := env.Get("new")
+	instance, ok := newObj.(*object.Hash)
+	if !ok {
+		return newSyntaxError("'super' keyword unexpected here")
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if this, _ := env.Get("this"); this != uninitialized {
+		return newReferenceError("Super constructor may only be called once")
+	}
+	if result := construct(class.Super, instance, args); isError(result) {
+		return result
+	}
+	env.Assign("this", instance)
+	if result := initializeInstance(class, instance, env); isError(result) {
+		return result
+	}
+	return instance
+}
+
+// evalSuperExpression returns the object super.x looks x up on: the
+// superclass prototype in methods, or the superclass statics in static
+// methods.
+func evalSuperExpression(env *object.Environment) object.Object {
+	class, err := currentClass(env)
+	if err != nil {
+		return err
+	}
+	this := evalThis(env)
+	if isError(this) {
+		return this
+	}
+	if _, ok := this.(*object.Class); ok {
+		return class.Super.Statics
+	}
+	return class.Super.Prototype
+}
+
+// evalMemberExpression evaluates obj.name. It also returns the receiver to
+// bind as 'this' if the member is called, which for super.name is the
+// current 'this' rather than the superclass prototype.
+func evalMemberExpression(node *ast.InfixExpression, env *object.Environment) (object.Object, object.Object) {
+	obj := Eval(node.Left, env)
+	if isError(obj) {
+		return NULL, obj
+	}
+	if skipsChain(obj, node.Optional) {
+		return NULL, brokenChain
+	}
+
+	receiver := obj
+	if _, ok := node.Left.(*ast.SuperExpression); ok {
+		receiver = evalThis(env)
+	}
+
+	// Only the dot syntax can name #private members
+	key := node.Right.(*ast.Identifier).Value
+	if isPrivateName(key) {
+		name, err := privateName(key, env)
+		if err != nil {
+			return receiver, err
+		}
+		return receiver, getPrivateMember(obj, name, receiver)
+	}
+	return receiver, getProperty(obj, key, receiver)
+}
+
+func evalThis(env *object.Environment) object.Object {
+	if this, ok := env.Get("this"); ok {
+		if this == uninitialized {
+			return newReferenceError(uninitializedThis)
+		}
+		return this
+	}
+	return UNDEFINED
+}
+
+// evalIn reports whether obj has the property key, either its own or
+// through the prototype chain.
+func evalIn(key, obj object.Object) object.Object {
+	props, ok := properties(obj)
+	if !ok {
+		return newTypeError("cannot use 'in' operator to search for '%s' in %s", propertyKey(key), obj.Inspect())
+	}
+
+	name := propertyKey(key)
+	if _, ok := props.Get(name); ok {
+		return TRUE
+	}
+	switch obj := obj.(type) {
+	case *object.Array:
+		n, isNumber := elementIndex(obj, key).(*object.Number)
+		return nativeBoolToBooleanObject(name == "length" ||
+			isNumber && n.Value >= 0 && n.Value == math.Trunc(n.Value) && int(n.Value) < len(obj.Elements))
+	case *object.Class:
+		return nativeBoolToBooleanObject(name == "name" || name == "prototype")
+	}
+	return FALSE
+}
+
+// properties returns the Hash holding the properties of obj: the object
+// itself, the statics of a class or the named properties of an array.
+func properties(obj object.Object) (*object.Hash, bool) {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		return obj, true
+	case *object.Class:
+		return obj.Statics, true
+	case *object.Array:
+		if obj.Props == nil {
+			obj.Props = &object.Hash{Proto: arrayClass.Prototype}
+		}
+		return obj.Props, true
+	}
+	return nil, false
+}
+
+// getProperty reads a property of obj, following the prototype chain and
+// calling getters with 'this' bound to receiver.
+func getProperty(obj object.Object, key string, receiver object.Object) object.Object {
+	if str, isString := obj.(*object.String); isString && key == "length" {
+		return &object.Number{Value: float64(len(utf16.Encode([]rune(str.Value))))}
+	}
+	props, ok := properties(obj)
+	if !ok && isNullish(obj) {
+		return newTypeError("Cannot read properties of %s (reading '%s')", obj.Inspect(), key)
+	}
+	if !ok {
+		return newTypeError("property access not supported on %s", obj.Type())
+	}
+	if arr, isArray := obj.(*object.Array); isArray && key == "length" {
+		return arrayLength(arr)
+	}
+
+	val, ok := props.Get(key)
+	if !ok {
+		if class, isClass := obj.(*object.Class); isClass {
+			switch key {
+			case "name":
+				return &object.String{Value: class.Name}
+			case "prototype":
+				return class.Prototype
+			}
+		}
+		return UNDEFINED
+	}
+
+	return readProperty(val, receiver)
+}
+
+// getPrivateMember reads a #private member, which only objects built by
+// the class declaring it have.
+func getPrivateMember(obj object.Object, name object.PrivateName, receiver object.Object) object.Object {
+	props, ok := properties(obj)
+	if !ok {
+		return newTypeError("cannot read private member %s from %s", name.Name, obj.Type())
+	}
+
+	val, ok := props.GetPrivate(name)
+	if !ok {
+		return newTypeError("cannot read private member %s from an object whose class did not declare it", name.Name)
+	}
+
+	return readProperty(val, receiver)
+}
+
+// readProperty returns a property's value, calling its getter if it is
+// an accessor.
+func readProperty(val, receiver object.Object) object.Object {
+	if accessor, ok := val.(*object.Accessor); ok {
+		if accessor.Getter == nil {
+			return UNDEFINED
+		}
+		return applyMethod(accessor.Getter, receiver, nil)
+	}
+	return val
+}
+
+// setProperty assigns a property of obj, calling a setter if one is found
+// on the prototype chain.
+func setProperty(obj object.Object, key string, val object.Object) object.Object {
+	props, ok := properties(obj)
+	if !ok {
+		return newTypeError("cannot set property '%s' of %s", key, obj.Inspect())
+	}
+	if arr, isArray := obj.(*object.Array); isArray && key == "length" {
+		return setArrayLength(arr, val)
+	}
+
+	if current, ok := props.Get(key); ok {
+		if accessor, ok := current.(*object.Accessor); ok {
+			return writeAccessor(accessor, key, obj, val)
+		}
+	}
+
+	props.Set(key, val)
+	return val
+}
+
+func setPrivateMember(obj object.Object, name object.PrivateName, val object.Object) object.Object {
+	props, ok := properties(obj)
+	if !ok {
+		return newTypeError("cannot write private member %s to %s", name.Name, obj.Type())
+	}
+
+	current, ok := props.GetPrivate(name)
+	if !ok {
+		return newTypeError("cannot write private member %s to an object whose class did not declare it", name.Name)
+	}
+	if accessor, ok := current.(*object.Accessor); ok {
+		return writeAccessor(accessor, name.Name, obj, val)
+	}
+	if _, own := props.Private[name]; !own {
+		return newTypeError("private method %s is not writable", name.Name)
+	}
+
+	props.Private[name] = val
+	return val
+}
+
+func writeAccessor(accessor *object.Accessor, key string, receiver, val object.Object) object.Object {
+	if accessor.Setter == nil {
+		return newTypeError("cannot set property %s which has only a getter", key)
+	}
+	result := applyMethod(accessor.Setter, receiver, []object.Object{val})
+	if isError(result) {
+		return result
+	}
+	return val
+}
+
+// evalInstanceOf reports whether the class's prototype is on the
+// prototype chain of obj.
+func evalInstanceOf(obj, constructor object.Object) object.Object {
+	class, ok := constructor.(*object.Class)
+	if !ok {
+		return newTypeError("right-hand side of 'instanceof' is not a class")
+	}
+
+	if _, isArray := obj.(*object.Array); isArray {
+		return nativeBoolToBooleanObject(class == arrayClass || class.Prototype == arrayClass.Prototype)
+	}
+	instance, ok := obj.(*object.Hash)
+	if !ok {
+		return FALSE
+	}
+	for proto := instance.Proto; proto != nil; proto = proto.Proto {
+		if proto == class.Prototype {
+			return TRUE
+		}
+	}
+	return FALSE
+}
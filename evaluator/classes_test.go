@@ -0,0 +1,110 @@
+package evaluator
+
+import "testing"
+
+func TestClasses(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"constructor and method", `
+			class Point {
+				constructor(x, y) { this.x = x; this.y = y; }
+				sum() { return this.x + this.y; }
+			}
+			new Point(1, 2).sum()`, "3"},
+		{"parameter properties", `
+			class Point {
+				constructor(public x: number, private readonly y: number) {}
+				sum(): number { return this.x + this.y; }
+			}
+			new Point(1, 2).sum()`, "3"},
+		{"fields", `class C { a = 1; b = this.a + 1; } let c = new C(); c.a + c.b`, "3"},
+		{"static members", `class C { static count = 2; static twice() { return C.count * 2; } } C.twice()`, "4"},
+		{"accessors", `
+			class Temp {
+				c = 0;
+				get f() { return this.c * 9 / 5 + 32; }
+				set f(v) { this.c = (v - 32) * 5 / 9; }
+			}
+			let t = new Temp();
+			t.f = 212;
+			t.c`, "100"},
+		{"inheritance and super", `
+			class Animal {
+				constructor(name) { this.name = name; }
+				speak() { return this.name + " makes a sound"; }
+			}
+			class Dog extends Animal {
+				constructor(name) { super(name); }
+				speak() { return super.speak() + ", woof"; }
+			}
+			new Dog("Rex").speak()`, "Rex makes a sound, woof"},
+		{"instanceof", `class A {} class B extends A {} let b = new B(); b instanceof A && !(new A() instanceof B)`, "true"},
+		{"class expression", `let Named = class { hi() { return "hi"; } }; new Named().hi()`, "hi"},
+		{"private members", `
+			class Counter {
+				#count = 0;
+				#step() { this.#count = this.#count + 1; }
+				inc() { this.#step(); return this.#count; }
+			}
+			let c = new Counter();
+			c.inc();
+			c.inc()`, "2"},
+		{"private fields are hidden", `class P { #x = 1; } new P().x`, "undefined"},
+		{"private names belong to their class", `
+			class A { #x = 1; static peek(o) { return o.#x; } }
+			class B { #x = 2; }
+			A.peek(new B())`,
+			"ERROR: TypeError: cannot read private member #x from an object whose class did not declare it"},
+		{"writing another class's private name", `
+			class A { #x = 1; static poke(o) { o.#x = 3; } }
+			class B { #x = 2; }
+			A.poke(new B())`,
+			"ERROR: TypeError: cannot write private member #x to an object whose class did not declare it"},
+		{"private names of an enclosing class", `
+			class Outer {
+				#s = "outer";
+				read() {
+					class Inner { #t = 1; get(o) { return o.#s; } }
+					return new Inner().get(this);
+				}
+			}
+			new Outer().read()`, "outer"},
+		{"subclass instances have the private names", `
+			class A { #x = 1; static peek(o) { return o.#x; } }
+			class B extends A { #x = 2; }
+			A.peek(new B())`, "1"},
+		{"the last statement is not the result", `
+			class Box {
+				constructor(items) { this.items = items; }
+			}
+			new Box([1, 2]) instanceof Box`, "true"},
+		{"empty constructor", `class E { constructor() {} } new E() instanceof E`, "true"},
+		{"returning an object", `
+			let shared = { shared: true };
+			class Singleton { constructor() { return shared; } }
+			new Singleton() === shared`, "true"},
+		{"returning a primitive", `class P { constructor() { return 1; } } new P() instanceof P`, "true"},
+		{"derived constructor without super", `
+			class A { x = 1; }
+			class D extends A { constructor() { this.y = 2; } }
+			new D()`,
+			"ERROR: ReferenceError: Must call super constructor in derived class before accessing 'this' or returning from derived constructor"},
+		{"this before super", `
+			class A { constructor(v) { this.v = v; } }
+			class D extends A { constructor() { this.y = 2; super(1); } }
+			new D()`,
+			"ERROR: ReferenceError: Must call super constructor in derived class before accessing 'this' or returning from derived constructor"},
+		{"super called twice", `
+			class A { }
+			class D extends A { constructor() { super(); super(); } }
+			new D()`,
+			"ERROR: ReferenceError: Super constructor may only be called once"},
+		{"super in an arrow function", `
+			class A { x = 1; }
+			class D extends A { constructor() { const init = () => super(); init(); this.y = this.x + 1; } }
+			new D().y`, "2"},
+		{"derived constructor returning an object", `
+			class A { }
+			class D extends A { constructor() { return { d: true }; } }
+			new D().d`, "true"},
+	})
+}
@@ -0,0 +1,356 @@
+package evaluator
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"ts-engine/object"
+	"unicode"
+	"unicode/utf16"
+)
+
+// isPrimitive reports whether obj is a primitive value rather than an
+// object.
+func isPrimitive(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Number, *object.String, *object.Boolean, *object.Null, *object.Undefined:
+		return true
+	}
+	return false
+}
+
+// isTruthy converts obj to a boolean: false, 0, NaN, "", null and
+// undefined are falsy and everything else, including every object, is
+// truthy.
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Number:
+		return obj.Value != 0 && !math.IsNaN(obj.Value)
+	case *object.String:
+		return obj.Value != ""
+	case *object.Null, *object.Undefined:
+		return false
+	}
+	return true
+}
+
+// toPrimitive converts an object to a primitive by calling its valueOf and
+// toString methods, in that order unless hint is "string", and taking the
+// first primitive result. Objects without methods of their own convert as
+// Object.prototype.toString does. Primitives are returned as they are.
+func toPrimitive(obj object.Object, hint string) object.Object {
+	if isPrimitive(obj) {
+		return obj
+	}
+
+	methods := []string{"valueOf", "toString"}
+	if hint == "string" {
+		methods = []string{"toString", "valueOf"}
+	}
+	ownToString := false
+	if _, ok := properties(obj); ok {
+		for _, name := range methods {
+			method := getProperty(obj, name, obj)
+			if isError(method) {
+				return method
+			}
+			if !isCallable(method) {
+				continue
+			}
+			ownToString = ownToString || name == "toString"
+			result := applyMethod(method, obj, nil)
+			if isError(result) || isPrimitive(result) {
+				return result
+			}
+		}
+	}
+	if ownToString {
+		return newTypeError("Cannot convert object to primitive value")
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		return joinElements(obj, ",")
+	case *object.Hash:
+		if evalInstanceOf(obj, errorClasses["Error"]) == TRUE {
+			name := getProperty(obj, "name", obj)
+			message := getProperty(obj, "message", obj)
+			return &object.String{Value: errorHeader(name.Inspect(), message.Inspect())}
+		}
+		return &object.String{Value: "[object Object]"}
+	}
+	return &object.String{Value: obj.Inspect()}
+}
+
+// joining holds the arrays whose elements are being joined, further up
+// the stack.
+var joining = map[*object.Array]bool{}
+
+// joinElements converts the elements of arr to strings and joins them
+// with sep. null and undefined elements give empty strings, and so does
+// an array that contains itself, where it is already being joined.
+func joinElements(arr *object.Array, sep string) object.Object {
+	if joining[arr] {
+		return &object.String{Value: ""}
+	}
+	joining[arr] = true
+	defer delete(joining, arr)
+
+	parts := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		if isNullish(el) {
+			continue
+		}
+		str := toJSString(el)
+		if isError(str) {
+			return str
+		}
+		parts[i] = str.(*object.String).Value
+	}
+	return &object.String{Value: strings.Join(parts, sep)}
+}
+
+// toJSString converts a value to a string as JavaScript's String() does.
+// Arrays join their elements with commas, errors give "Name: message", and
+// other objects use their toString method if they have one.
+func toJSString(obj object.Object) object.Object {
+	if s, ok := obj.(*object.String); ok {
+		return s
+	}
+	if isPrimitive(obj) {
+		return &object.String{Value: obj.Inspect()}
+	}
+	prim := toPrimitive(obj, "string")
+	if isError(prim) {
+		return prim
+	}
+	return toJSString(prim)
+}
+
+// toNumber converts a value to a number as JavaScript's Number() does. The
+// error is one thrown by the valueOf or toString method of an object.
+func toNumber(obj object.Object) (float64, object.Object) {
+	switch obj := obj.(type) {
+	case *object.Number:
+		return obj.Value, nil
+	case *object.Boolean:
+		if obj.Value {
+			return 1, nil
+		}
+		return 0, nil
+	case *object.Null:
+		return 0, nil
+	case *object.Undefined:
+		return math.NaN(), nil
+	case *object.String:
+		return stringToNumber(obj.Value), nil
+	}
+
+	prim := toPrimitive(obj, "number")
+	if isError(prim) {
+		return 0, prim
+	}
+	return toNumber(prim)
+}
+
+var decimalLiteral = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// stringToNumber parses s as a number the way JavaScript converts strings:
+// surrounding whitespace is ignored, the empty string is 0, 0x, 0o and 0b
+// prefixes select a radix, and anything else that is not a decimal literal
+// or Infinity is NaN.
+func stringToNumber(s string) float64 {
+	s = strings.TrimFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '\uFEFF' })
+	if s == "" {
+		return 0
+	}
+
+	switch strings.TrimLeft(s, "+-") {
+	case "Infinity":
+		if s[0] == '-' {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		if base, ok := map[byte]float64{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[s[1]]; ok {
+			value := 0.0
+			for _, c := range s[2:] {
+				digit := float64(strings.IndexRune("0123456789abcdef", unicode.ToLower(c)))
+				if digit < 0 || digit >= base {
+					return math.NaN()
+				}
+				value = value*base + digit
+			}
+			return value
+		}
+	}
+
+	if !decimalLiteral.MatchString(s) {
+		return math.NaN()
+	}
+	// Out of range values parse as ±Inf or 0, as they should
+	value, _ := strconv.ParseFloat(s, 64)
+	return value
+}
+
+// strictEquals implements ===: values are equal if they have the same type
+// and value, except that NaN equals nothing, and objects are equal only to
+// themselves.
+func strictEquals(left, right object.Object) bool {
+	switch l := left.(type) {
+	case *object.Number:
+		r, ok := right.(*object.Number)
+		return ok && l.Value == r.Value
+	case *object.String:
+		r, ok := right.(*object.String)
+		return ok && l.Value == r.Value
+	case *object.Boolean:
+		r, ok := right.(*object.Boolean)
+		return ok && l.Value == r.Value
+	case *object.Null, *object.Undefined:
+		return left.Type() == right.Type()
+	}
+	return left == right
+}
+
+// sameValueZero is strictEquals, except that NaN equals itself. It is the
+// equality that array searches such as includes use.
+func sameValueZero(left, right object.Object) bool {
+	l, lok := left.(*object.Number)
+	r, rok := right.(*object.Number)
+	if lok && rok && math.IsNaN(l.Value) && math.IsNaN(r.Value) {
+		return true
+	}
+	return strictEquals(left, right)
+}
+
+// looseEquals implements ==. null and undefined equal each other and
+// nothing else; otherwise values of different types are converted, booleans
+// and strings to numbers and objects to primitives, until their types
+// match.
+func looseEquals(left, right object.Object) (bool, object.Object) {
+	switch {
+	case isNullish(left) || isNullish(right):
+		return isNullish(left) && isNullish(right), nil
+	case !isPrimitive(left) && !isPrimitive(right):
+		return left == right, nil
+	case left.Type() == right.Type():
+		return strictEquals(left, right), nil
+	}
+
+	switch {
+	case left.Type() == object.BOOLEAN_OBJ:
+		n, _ := toNumber(left)
+		return looseEquals(&object.Number{Value: n}, right)
+	case right.Type() == object.BOOLEAN_OBJ:
+		n, _ := toNumber(right)
+		return looseEquals(left, &object.Number{Value: n})
+	case !isPrimitive(left):
+		prim := toPrimitive(left, "default")
+		if isError(prim) {
+			return false, prim
+		}
+		return looseEquals(prim, right)
+	case !isPrimitive(right):
+		prim := toPrimitive(right, "default")
+		if isError(prim) {
+			return false, prim
+		}
+		return looseEquals(left, prim)
+	}
+
+	// A number and a string
+	l, _ := toNumber(left)
+	r, _ := toNumber(right)
+	return l == r, nil
+}
+
+// evalComparison evaluates <, >, <= and >=. Two strings compare by their
+// UTF-16 code units, as JavaScript strings do; any other operands are
+// compared as numbers, and a NaN makes every comparison false.
+func evalComparison(operator string, left, right object.Object) object.Object {
+	left = toPrimitive(left, "number")
+	if isError(left) {
+		return left
+	}
+	right = toPrimitive(right, "number")
+	if isError(right) {
+		return right
+	}
+
+	ls, lok := left.(*object.String)
+	rs, rok := right.(*object.String)
+	if lok && rok {
+		c := compareUTF16(ls.Value, rs.Value)
+		switch operator {
+		case "<":
+			return nativeBoolToBooleanObject(c < 0)
+		case ">":
+			return nativeBoolToBooleanObject(c > 0)
+		case "<=":
+			return nativeBoolToBooleanObject(c <= 0)
+		default:
+			return nativeBoolToBooleanObject(c >= 0)
+		}
+	}
+
+	l, err := toNumber(left)
+	if err != nil {
+		return err
+	}
+	r, err := toNumber(right)
+	if err != nil {
+		return err
+	}
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(l < r)
+	case ">":
+		return nativeBoolToBooleanObject(l > r)
+	case "<=":
+		return nativeBoolToBooleanObject(l <= r)
+	default:
+		return nativeBoolToBooleanObject(l >= r)
+	}
+}
+
+// compareUTF16 compares a and b by their UTF-16 code units. This differs
+// from comparing their bytes only when one has characters outside the
+// Basic Multilingual Plane.
+func compareUTF16(a, b string) int {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return int(ua[i]) - int(ub[i])
+		}
+	}
+	return len(ua) - len(ub)
+}
+
+// evalAddition evaluates +, which concatenates if either operand converts
+// to a string and adds numbers otherwise: 1 + "2" is "12", and 1 + true
+// is 2.
+func evalAddition(left, right object.Object) object.Object {
+	left = toPrimitive(left, "default")
+	if isError(left) {
+		return left
+	}
+	right = toPrimitive(right, "default")
+	if isError(right) {
+		return right
+	}
+
+	if left.Type() == object.STRING_OBJ || right.Type() == object.STRING_OBJ {
+		l, r := toJSString(left), toJSString(right)
+		return &object.String{Value: l.(*object.String).Value + r.(*object.String).Value}
+	}
+
+	l, _ := toNumber(left)
+	r, _ := toNumber(right)
+	return &object.Number{Value: l + r}
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"math"
+	"testing"
+
+	"ts-engine/object"
+)
+
+// TestCoercionConformance checks expressions against the results the
+// ECMAScript spec gives for them.
+func TestCoercionConformance(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// Abstract equality
+		{`null == undefined`, "true"},
+		{`null == 0`, "false"},
+		{`null == false`, "false"},
+		{`1 == "1"`, "true"},
+		{`0 == ""`, "true"},
+		{`"" == "0"`, "false"},
+		{`1 == true`, "true"},
+		{`2 == true`, "false"},
+		{`NaN == NaN`, "false"},
+		{`[1] == 1`, "true"},
+		{`[1, 2] == "1,2"`, "true"},
+		{`[] == false`, "true"},
+		{`({}) == "[object Object]"`, "true"},
+		{`({ valueOf: function() { return 42; } }) == 42`, "true"},
+
+		// Strict equality
+		{`1 === "1"`, "false"},
+		{`null === undefined`, "false"},
+		{`NaN === NaN`, "false"},
+		{`0 === -0`, "true"},
+		{`let o = {}; o === o`, "true"},
+		{`({}) === ({})`, "false"},
+
+		// Addition
+		{`1 + "2"`, "12"},
+		{`"3" + 4 + 5`, "345"},
+		{`3 + 4 + "5"`, "75"},
+		{`true + 1`, "2"},
+		{`null + 1`, "1"},
+		{`undefined + 1`, "NaN"},
+		{`[1, 2] + [3]`, "1,23"},
+		{`({}) + ""`, "[object Object]"},
+		{`let a = [1]; a[1] = a; a + ""`, "1,"},
+		{`({ toString: function() { return "7"; } }) + 1`, "71"},
+		{`({ valueOf: function() { return 1; }, toString: function() { return "two"; } }) + 1`, "2"},
+
+		// Other arithmetic converts to numbers
+		{`"6" * "7"`, "42"},
+		{`"10" - 3`, "7"},
+		{`" 12 " * 1`, "12"},
+		{`"0x10" * 1`, "16"},
+		{`"" * 1`, "0"},
+		{`"abc" * 1`, "NaN"},
+		{`[] * 1`, "0"},
+		{`[5] * 2`, "10"},
+		{`-"3"`, "-3"},
+
+		// Relational comparison
+		{`"B" < "a"`, "true"},
+		{`"10" < "9"`, "true"},
+		{`"10" < 9`, "false"},
+		{`null >= 0`, "true"},
+		{`undefined < 1`, "false"},
+		{`NaN <= NaN`, "false"},
+
+		// ToBoolean
+		{`!!0`, "false"},
+		{`!!""`, "false"},
+		{`!!NaN`, "false"},
+		{`!!"0"`, "true"},
+		{`!![]`, "true"},
+		{`!!{}`, "true"},
+	}
+	for _, tt := range tests {
+		if got := testEval(t, tt.expr).Inspect(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestSameValueZero(t *testing.T) {
+	nan := &object.Number{Value: math.NaN()}
+	zero := &object.Number{Value: 0}
+	negZero := &object.Number{Value: math.Copysign(0, -1)}
+
+	if !sameValueZero(nan, &object.Number{Value: math.NaN()}) {
+		t.Errorf("NaN should be the same value as NaN")
+	}
+	if !sameValueZero(zero, negZero) {
+		t.Errorf("0 should be the same value as -0")
+	}
+	if sameValueZero(zero, &object.String{Value: "0"}) {
+		t.Errorf("0 should not be the same value as \"0\"")
+	}
+}
@@ -0,0 +1,60 @@
+package evaluator
+
+import (
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// evalEnumDeclaration creates the object of an enum, mapping its members'
+// names to their values and, for numeric members, values back to names:
+// Color.Red is 0 and Color[0] is "Red". Const enums have no object, as
+// their members were inlined by the parser.
+func evalEnumDeclaration(node *ast.EnumDeclaration, env *object.Environment) object.Object {
+	if node.Const {
+		return NULL
+	}
+
+	// Computed initializers may refer to the members before them by name
+	membersEnv := object.NewEnclosedEnvironment(env)
+	enum := &object.Hash{Pairs: map[string]object.Object{}}
+	for _, member := range node.Members {
+		val := Eval(member.Value, membersEnv)
+		if isError(val) {
+			return val
+		}
+		if member.Computed && val.Type() != object.NUMBER_OBJ {
+			return newTypeError("computed enum member '%s' must be a number, got %s", member.Name, val.Type())
+		}
+		enum.Set(member.Name, val)
+		if val.Type() == object.NUMBER_OBJ {
+			enum.Set(propertyKey(val), &object.String{Value: member.Name})
+		}
+		membersEnv.Set(member.Name, val)
+	}
+
+	if _, ok := env.GetCurrent(node.Name.Value); ok {
+		return newSyntaxError("cannot redeclare block-scoped variable '%s'", node.Name.Value)
+	}
+	env.Set(node.Name.Value, enum)
+	return NULL
+}
+
+// checkEnum checks that obj is the value of one of the members of an
+// enum, or of the member called member if it is not empty.
+func checkEnum(obj object.Object, decl *ast.EnumDeclaration, member string, env *object.Environment) bool {
+	runtime, _ := env.Get(decl.Name.Value)
+	for _, m := range decl.Members {
+		if member != "" && m.Name != member {
+			continue
+		}
+		if !m.Computed && literalMatches(obj, m.Value) {
+			return true
+		}
+		if hash, ok := runtime.(*object.Hash); ok && m.Computed {
+			if val, ok := hash.GetOwn(m.Name); ok && val.Inspect() == obj.Inspect() && val.Type() == obj.Type() {
+				return true
+			}
+		}
+	}
+	return false
+}
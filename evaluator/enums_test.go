@@ -0,0 +1,20 @@
+package evaluator
+
+import "testing"
+
+func TestEnums(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"auto-increment", `enum Color { Red, Green = 5, Blue } Color.Red + Color.Blue`, "6"},
+		{"reverse mapping", `enum Color { Red, Green = 5 } Color[5]`, "Green"},
+		{"string members", `enum Dir { Up = "UP", Down = "DOWN" } Dir.Down`, "DOWN"},
+		{"members refer to earlier ones", `enum Flags { Read = 1, Write = Read + 1 } Flags.Write`, "2"},
+		{"computed member", `function size() { return 3; } enum E { Len = size() } E.Len`, "3"},
+		{"const enum is inlined", `const enum E { A = 1, B } E.B`, "2"},
+		{"const enum leaves no object", `const enum E { A } typeof E`, "undefined"},
+		{"parameter shadows a const enum", `const enum E { A = 1 } function f(E) { return E.A; } f({ A: "shadowed" })`, "shadowed"},
+		{"block variable shadows a const enum", `const enum E { A = 1 } let r; { let E = { A: "block" }; r = E.A; } r`, "block"},
+		{"shadowing ends with the block", `const enum E { A = 1 } { let E = { A: "block" }; } E.A`, "1"},
+		{"catch parameter shadows a const enum", `const enum E { A = 1 } let r; try { throw { A: "caught" }; } catch (E) { r = E.A; } r + E.A`, "caught1"},
+		{"var shadows a const enum in its function", `const enum E { A = 1 } function f() { { var E = { A: "var" }; } return E.A; } f()`, "var"},
+	})
+}
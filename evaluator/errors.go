@@ -0,0 +1,280 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/object"
+	"ts-engine/token"
+)
+
+// filename names the file the program was read from in stack traces.
+var filename string
+
+// SetFilename sets the name of the file the program was read from, which
+// the frames of stack traces are located in. It must be called before the
+// program starts.
+func SetFilename(name string) {
+	filename = name
+}
+
+// callStack holds the user-visible calls currently being evaluated, used to
+// build the 'stack' property of Error objects.
+var callStack []object.CallFrame
+
+// maxCallDepth is how deeply calls of functions and constructors may nest
+// before a RangeError is thrown, well before the Go stack would overflow.
+const maxCallDepth = 10000
+
+// callDepth is the number of function and constructor calls being
+// evaluated.
+var callDepth int
+
+// enterCall counts a call starting, or returns the RangeError that
+// unbounded recursion runs into. Each successful call must be matched by
+// exitCall.
+func enterCall() *object.Error {
+	if callDepth >= maxCallDepth {
+		return newRangeError("Maximum call stack size exceeded")
+	}
+	callDepth++
+	return nil
+}
+
+func exitCall() {
+	callDepth--
+}
+
+// callWithFrame applies fn with a stack frame recorded for the call at pos.
+func callWithFrame(fn, this object.Object, args []object.Object, pos token.Position) object.Object {
+	callStack = append(callStack, object.CallFrame{Function: functionName(fn), Call: pos})
+	result := applyMethod(fn, this, args)
+	callStack = callStack[:len(callStack)-1]
+	return result
+}
+
+func captureStack() []object.CallFrame {
+	return append([]object.CallFrame(nil), callStack...)
+}
+
+func functionName(fn object.Object) string {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if fn.Name != "" {
+			return fn.Name
+		}
+	case *object.Class:
+		if fn.Name != "" {
+			return "new " + fn.Name
+		}
+	}
+	return "<anonymous>"
+}
+
+// formatStack renders a V8-style stack trace for an error raised at pos
+// while the given calls were active, innermost call last.
+func formatStack(header string, pos token.Position, frames []object.CallFrame) string {
+	var out strings.Builder
+	out.WriteString(header)
+
+	for i := len(frames) - 1; i >= 0; i-- {
+		fmt.Fprintf(&out, "\n    at %s (%s)", frames[i].Function, location(pos))
+		pos = frames[i].Call
+	}
+	fmt.Fprintf(&out, "\n    at <anonymous> (%s)", location(pos))
+
+	return out.String()
+}
+
+// location renders pos as file:line:col, or line:col if the file is not
+// known.
+func location(pos token.Position) string {
+	if filename == "" {
+		return pos.String()
+	}
+	return filename + ":" + pos.String()
+}
+
+func newTypeError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Name: "TypeError", Message: fmt.Sprintf(format, a...)}
+}
+
+func newReferenceError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Name: "ReferenceError", Message: fmt.Sprintf(format, a...)}
+}
+
+func newRangeError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Name: "RangeError", Message: fmt.Sprintf(format, a...)}
+}
+
+func newSyntaxError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Name: "SyntaxError", Message: fmt.Sprintf(format, a...)}
+}
+
+// errorClasses holds the builtin Error class and its subclasses by name.
+var errorClasses = map[string]*object.Class{}
+
+// newErrorClass creates a builtin Error class. The name and default
+// message live on the prototype, as in JavaScript.
+func newErrorClass(name string, super *object.Class) *object.Class {
+	class := &object.Class{
+		Name:      name,
+		Super:     super,
+		Prototype: &object.Hash{},
+		Statics:   &object.Hash{},
+		Init:      initError,
+	}
+	if super != nil {
+		class.Prototype.Proto = super.Prototype
+		class.Statics.Proto = super.Statics
+	}
+	class.Prototype.Set("constructor", class)
+	class.Prototype.Set("name", &object.String{Value: name})
+	class.Prototype.Set("message", &object.String{Value: ""})
+
+	errorClasses[name] = class
+	return class
+}
+
+// newAggregateErrorClass creates AggregateError, whose constructor takes
+// the errors it aggregates before the message.
+func newAggregateErrorClass(super *object.Class) *object.Class {
+	class := newErrorClass("AggregateError", super)
+	class.Init = func(this *object.Hash, args []object.Object) object.Object {
+		var errors []object.Object
+		if arr, ok := argument(args, 0).(*object.Array); ok {
+			errors = append(errors, arr.Elements...)
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		result := initError(this, args)
+		this.Set("errors", &object.Array{Elements: errors})
+		return result
+	}
+	return class
+}
+
+// newErrorObject builds the JavaScript value of an Error of the given kind.
+func newErrorObject(name, message, stack string) *object.Hash {
+	class, ok := errorClasses[name]
+	if !ok {
+		class = errorClasses["Error"]
+	}
+
+	obj := &object.Hash{Proto: class.Prototype}
+	obj.Set("message", &object.String{Value: message})
+	obj.Set("stack", &object.String{Value: stack})
+	return obj
+}
+
+// initError constructs Error instances, including those of user classes
+// extending Error: it sets the message and captures the stack trace.
+func initError(this *object.Hash, args []object.Object) object.Object {
+	message := ""
+	if len(args) > 0 {
+		if s, ok := args[0].(*object.String); ok {
+			message = s.Value
+		} else {
+			message = args[0].Inspect()
+		}
+		this.Set("message", &object.String{Value: message})
+	}
+
+	name := "Error"
+	if val, ok := this.Get("name"); ok {
+		name = val.Inspect()
+	}
+
+	// The innermost frame is the constructor call, which gives the
+	// position the stack trace starts from.
+	frames := captureStack()
+	var pos token.Position
+	if n := len(frames); n > 0 {
+		pos = frames[n-1].Call
+		frames = frames[:n-1]
+	}
+
+	this.Set("stack", &object.String{Value: formatStack(errorHeader(name, message), pos, frames)})
+	return NULL
+}
+
+// errorStack returns the stack trace of an Error instance.
+func errorStack(val object.Object) (string, bool) {
+	obj, ok := val.(*object.Hash)
+	if !ok || evalInstanceOf(obj, errorClasses["Error"]) != TRUE {
+		return "", false
+	}
+	stack, ok := obj.Get("stack")
+	if !ok {
+		return "", false
+	}
+	return stack.Inspect(), true
+}
+
+func errorHeader(name, message string) string {
+	if message == "" {
+		return name
+	}
+	return name + ": " + message
+}
+
+// errorValue returns the JavaScript value carried by err, materializing an
+// Error object for exceptions raised by the engine.
+func errorValue(err *object.Error) object.Object {
+	if err.Value == nil {
+		name := err.Name
+		if name == "" {
+			name = "Error"
+		}
+		stack := formatStack(errorHeader(name, err.Message), err.Pos, err.Stack)
+		err.Value = newErrorObject(name, err.Message, stack)
+	}
+	return err.Value
+}
+
+// thrownError wraps a value thrown by user code. Error-like objects keep
+// their name and message for reporting uncaught exceptions.
+func thrownError(val object.Object) *object.Error {
+	err := &object.Error{Value: val, Message: val.Inspect()}
+
+	if hash, ok := val.(*object.Hash); ok {
+		name, hasName := hash.Get("name")
+		message, hasMessage := hash.Get("message")
+		if hasName && hasMessage {
+			err.Name = name.Inspect()
+			err.Message = message.Inspect()
+		}
+	}
+
+	return err
+}
+
+func evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(node.Block, env)
+
+	if err, ok := result.(*object.Error); ok && node.CatchBody != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if node.CatchParam != nil {
+			catchEnv.Set(node.CatchParam.Value, errorValue(err))
+		}
+		result = Eval(node.CatchBody, catchEnv)
+	}
+
+	if node.Finally != nil {
+		// An abrupt finally block (return, throw, break, continue)
+		// overrides the outcome of the try and catch blocks.
+		finally := Eval(node.Finally, env)
+		if finally != nil {
+			switch finally.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+				return finally
+			}
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
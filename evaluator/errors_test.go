@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExceptions(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"catch a thrown value", `let r; try { throw "boom"; } catch (e) { r = e; } r`, "boom"},
+		{"finally runs", `let s = ""; try { s = s + "t"; } finally { s = s + "f"; } s`, "tf"},
+		{"finally after catch", `
+			let s = "";
+			try { throw 1; } catch (e) { s = s + "c"; } finally { s = s + "f"; }
+			s`, "cf"},
+		{"optional catch binding", `let ok = false; try { throw 1; } catch { ok = true; } ok`, "true"},
+		{"rethrow from catch", `
+			let r;
+			try {
+				try { throw "inner"; } catch (e) { throw e + "!"; }
+			} catch (e) { r = e; }
+			r`, "inner!"},
+		{"error object", `let e = new TypeError("bad"); e.name + ": " + e.message`, "TypeError: bad"},
+		{"error name", `let e = new RangeError("r"); e.name`, "RangeError"},
+		{"runtime failures are catchable", `let r; try { missing(); } catch (e) { r = e.name; } r`, "ReferenceError"},
+		{"calling a non-function", `let r; try { let x = 1; x(); } catch (e) { r = e.name; } r`, "TypeError"},
+		{"function call unwinds", `
+			function fail() { throw new Error("deep"); }
+			let r;
+			try { fail(); } catch (e) { r = e.message; }
+			r`, "deep"},
+		{"unbounded recursion", `
+			function f() { return f(); }
+			let r;
+			try { f(); } catch (e) { r = e.name + ": " + e.message; }
+			r`, "RangeError: Maximum call stack size exceeded"},
+		{"uncaught", `throw new SyntaxError("nope")`, "ERROR: SyntaxError: nope"},
+	})
+}
+
+func TestStackTrace(t *testing.T) {
+	src := "function inner() {\n  throw new Error(\"x\");\n}\nfunction outer() {\n  inner();\n}\nlet s;\ntry { outer(); } catch (e) { s = e.stack; }\ns"
+	stack := testEval(t, src).Inspect()
+	for _, want := range []string{"Error: x", "at inner (2:", "at outer (5:"} {
+		if !strings.Contains(stack, want) {
+			t.Errorf("stack %q does not contain %q", stack, want)
+		}
+	}
+}
+
+func TestStackTraceFilename(t *testing.T) {
+	SetFilename("app.ts")
+	defer SetFilename("")
+
+	src := "function a() {\n  throw new Error(\"x\");\n}\nlet s;\ntry { a(); } catch (e) { s = e.stack; }\ns"
+	stack := testEval(t, src).Inspect()
+	for _, want := range []string{"at a (app.ts:2:", "at <anonymous> (app.ts:5:"} {
+		if !strings.Contains(stack, want) {
+			t.Errorf("stack %q does not contain %q", stack, want)
+		}
+	}
+}
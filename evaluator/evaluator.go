@@ -2,20 +2,36 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"ts-engine/ast"
-	"ts-engine/http"
 	"ts-engine/object"
 	"ts-engine/token"
+	"unicode/utf16"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL      = &object.Null{}
+	UNDEFINED = &object.Undefined{}
+	TRUE      = &object.Boolean{Value: true}
+	FALSE     = &object.Boolean{Value: false}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+
+	// Errors are located at the innermost node that produced them
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+		err.End = node.End()
+		err.Stack = captureStack()
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -23,30 +39,56 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 
-	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+	case *ast.NumberLiteral:
+		return &object.Number{Value: node.Value}
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.TemplateLiteral:
+		return evalTemplateLiteral(node, env)
+
+	case *ast.TaggedTemplateExpression:
+		return evalTaggedTemplate(node, env)
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.NullLiteral:
+		return NULL
+
 	case *ast.PrefixExpression:
+		// typeof an undeclared variable is "undefined" rather than an error
+		if ident, ok := node.Right.(*ast.Identifier); ok && node.Operator == "typeof" {
+			if _, declared := env.Get(ident.Value); !declared && builtins[ident.Value] == nil {
+				return &object.String{Value: "undefined"}
+			}
+		}
+		if node.Operator == "delete" {
+			return evalDelete(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
+	case *ast.AwaitExpression:
+		val := Eval(node.Argument, env)
+		if isError(val) {
+			return val
+		}
+		return evalAwait(val)
+
 	case *ast.InfixExpression:
 		// Special handling for dot operator to avoid evaluating the property as a variable
 		if node.Operator == "." {
-			left := Eval(node.Left, env)
-			if isError(left) {
-				return left
-			}
-			return evalDotIndexExpression(left, node.Right)
+			_, val := evalMemberExpression(node, env)
+			return val
+		}
+		switch node.Operator {
+		case "&&", "||", "??":
+			return evalLogicalExpression(node, env)
 		}
 
 		left := Eval(node.Left, env)
@@ -57,15 +99,66 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
+		switch node.Operator {
+		case "instanceof":
+			return evalInstanceOf(left, right)
+		case "in":
+			return evalIn(left, right)
+		}
 		return evalInfixExpression(node.Operator, left, right)
 
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+
+	case *ast.SequenceExpression:
+		return evalSequenceExpression(node, env)
+
+	case *ast.OptionalChain:
+		return evalOptionalChain(node, env)
+
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return evalBlockStatement(node, object.NewEnclosedEnvironment(env))
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env, nil)
+
+	case *ast.DoWhileStatement:
+		return evalDoWhileStatement(node, env, nil)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env, nil)
+
+	case *ast.ForOfStatement:
+		return evalForOfStatement(node, env, nil)
+
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env, nil)
+
+	case *ast.LabeledStatement:
+		return evalLabeledStatement(node, env)
+
+	case *ast.SwitchStatement:
+		return evalSwitchStatement(node, env)
+
+	case *ast.BreakStatement:
+		if node.Label != nil {
+			return &object.Break{Label: node.Label.Value}
+		}
+		return &object.Break{}
+
+	case *ast.ContinueStatement:
+		if node.Label != nil {
+			return &object.Continue{Label: node.Label.Value}
+		}
+		return &object.Continue{}
+
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: UNDEFINED}
+		}
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
@@ -75,10 +168,24 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ExportStatement:
 		return NULL
 
+	case *ast.InterfaceDeclaration, *ast.TypeAliasDeclaration:
+		// Declared by declareTypes when the enclosing block was entered
+		return NULL
+
+	case *ast.EnumDeclaration:
+		return evalEnumDeclaration(node, env)
+
+	case *ast.InlinedEnumMember:
+		return Eval(node.Value, env)
+
 	case *ast.ImportStatement:
 		return evalImportStatement(node, env)
 
 	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return evalDestructuringDeclaration(node, env)
+		}
+
 		var val object.Object
 		if node.Value != nil {
 			val = Eval(node.Value, env)
@@ -86,27 +193,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 				return val
 			}
 		} else {
-			val = NULL
+			val = UNDEFINED
 		}
 
-		if node.Token.Type == token.VAR {
-			// VAR allows redeclaration
-		} else {
-			// LET and CONST do not allow redeclaration
-			// However, in our REPL/single-run mode, we might want to be lenient or strict.
-			// Standard JS: SyntaxError if redeclared in same scope.
-			if _, ok := env.GetCurrent(node.Name.Value); ok {
-				return newError("cannot redeclare block-scoped variable '%s'", node.Name.Value)
+		// var x; leaves a hoisted x as it is
+		if node.Token.Type == token.VAR && node.Value == nil {
+			if node.Name.Type != nil {
+				env.Annotate(node.Name.Value, node.Name.Type)
 			}
+			return nil
 		}
 
-		if node.Name.Type != "" && val != NULL {
-			if err := checkType(val, node.Name.Type); err != nil {
+		nameFunction(val, node.Name)
+
+		if node.Name.Type != nil && node.Value != nil {
+			if err := checkType(val, node.Name.Type, env); err != nil {
 				return err
 			}
 		}
 
-		env.Set(node.Name.Value, val)
+		if err := declareVariable(node.Token.Type, node.Name.Value, val, env); isError(err) {
+			return err
+		}
+		if node.Name.Type != nil {
+			env.Annotate(node.Name.Value, node.Name.Type)
+		}
+
+	case *ast.DeclarationList:
+		for _, decl := range node.Declarations {
+			if result := Eval(decl, env); isError(result) {
+				return result
+			}
+		}
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -114,26 +232,60 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		fn := &object.Function{Parameters: params, Env: env, Body: body}
+		fn := &object.Function{Name: node.Name, Parameters: params, Env: env, Body: body, Arrow: node.Arrow, Async: node.Async, Rest: node.Rest}
 		if node.Name != "" {
 			env.Set(node.Name, fn)
 		}
 		return fn
 
+	case *ast.ThisExpression:
+		return evalThis(env)
+
+	case *ast.SuperExpression:
+		return evalSuperExpression(env)
+
+	case *ast.ClassLiteral:
+		return evalClassLiteral(node, env)
+
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if _, ok := node.Function.(*ast.SuperExpression); ok {
+			return evalSuperCall(node, env)
+		}
+
+		this, function := evalCallee(node.Function, env)
 		if isError(function) {
 			return function
 		}
+		if skipsChain(function, node.Optional) {
+			return brokenChain
+		}
 		args := evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		// The builtin Error classes and Array may be called without new
+		if class, ok := function.(*object.Class); ok && (errorClasses[class.Name] == class || class == arrayClass) {
+			return newInstance(class, args, node.Pos())
+		}
+
+		return callWithFrame(function, this, args, node.Pos())
+
+	case *ast.NewExpression:
+		return evalNewExpression(node, env)
+
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return thrownError(val)
+
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -143,31 +295,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Array{Elements: elements}
 
 	case *ast.AssignmentExpression:
-		left := node.Left
-		val := Eval(node.Value, env)
-		if isError(val) {
-			return val
-		}
+		return evalAssignmentExpression(node, env)
 
-		if ident, ok := left.(*ast.Identifier); ok {
-			// Check if variable exists before assigning?
-			// For now, let's just Set it.
-			// Ideally we should check if it's CONST or if it exists in scope chain.
-			// Assuming recursive Set for now or just simple Set.
-			// If we want to verify existence:
-			if _, ok := env.Get(ident.Value); !ok {
-				return newError("identifier not found: %s", ident.Value)
-			}
-			env.Set(ident.Value, val)
-			return val
-		}
-		return newError("assignment to non-identifier not supported yet")
+	case *ast.UpdateExpression:
+		return evalUpdateExpression(node, env)
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if skipsChain(left, node.Optional) {
+			return brokenChain
+		}
 		index := Eval(node.Index, env)
 		if isError(index) {
 			return index
@@ -181,7 +321,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	strictTypes = program.Strict
+	declareTypes(program.Statements, env)
+	hoistVars(program.Statements, env)
+	if err := hoistDeclarations(program.Statements, env); err != nil {
+		return err
+	}
+
 	for _, statement := range program.Statements {
+		if functionDeclaration(statement) != nil {
+			continue
+		}
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -198,12 +348,22 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	declareTypes(block.Statements, env)
+	if err := hoistDeclarations(block.Statements, env); err != nil {
+		return err
+	}
+
 	for _, statement := range block.Statements {
+		// Defined when the block was entered
+		if functionDeclaration(statement) != nil {
+			continue
+		}
 		result = Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -225,40 +385,53 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
-	case "await":
-		return right
+	case "+", "~":
+		value, err := toNumber(right)
+		if err != nil {
+			return err
+		}
+		if operator == "~" {
+			value = float64(^toInt32(value))
+		}
+		return &object.Number{Value: value}
+	case "typeof":
+		return &object.String{Value: typeOf(right)}
+	case "void":
+		return UNDEFINED
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newTypeError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
+// evalInfixExpression applies a binary operator to two values, converting
+// them as JavaScript does: see coercion.go.
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
-	switch {
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
-	case operator == "+" && (left.Type() == object.STRING_OBJ || right.Type() == object.STRING_OBJ):
-		return evalStringConcatenation(left, right)
-	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
-	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
-	case operator == "===":
-		return nativeBoolToBooleanObject(left == right && left.Type() == right.Type())
-	case operator == "!==":
-		return nativeBoolToBooleanObject(left != right || left.Type() != right.Type())
-	case operator == "!==":
-		return nativeBoolToBooleanObject(left != right || left.Type() != right.Type())
-	case operator == "&&":
-		return nativeBoolToBooleanObject(isTruthy(left) && isTruthy(right))
-	case operator == "||":
-		return nativeBoolToBooleanObject(isTruthy(left) || isTruthy(right))
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	switch operator {
+	case "===":
+		return nativeBoolToBooleanObject(strictEquals(left, right))
+	case "!==":
+		return nativeBoolToBooleanObject(!strictEquals(left, right))
+	case "==", "!=":
+		equal, err := looseEquals(left, right)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(equal == (operator == "=="))
+	case "<", ">", "<=", ">=":
+		return evalComparison(operator, left, right)
+	case "+":
+		return evalAddition(left, right)
+	}
+
+	l, err := toNumber(left)
+	if err != nil {
+		return err
 	}
+	r, err := toNumber(right)
+	if err != nil {
+		return err
+	}
+	return evalNumberInfixExpression(operator, l, r)
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -276,30 +449,32 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
-func isTruthy(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
-		return false
-	default:
-		return true
-	}
+// isNullish reports whether obj is null or undefined, the values that ??
+// and == null treat alike.
+func isNullish(obj object.Object) bool {
+	return obj == NULL || obj == UNDEFINED
+}
+
+// typeOf returns the name the typeof operator gives the type of obj.
+func typeOf(obj object.Object) string {
+	switch obj.Type() {
+	case object.UNDEFINED_OBJ:
+		return "undefined"
+	case object.NUMBER_OBJ:
+		return "number"
+	case object.STRING_OBJ:
+		return "string"
+	case object.BOOLEAN_OBJ:
+		return "boolean"
+	}
+	if _, isClass := obj.(*object.Class); isClass || isCallable(obj) {
+		return "function"
+	}
+	return "object"
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}
+	return nativeBoolToBooleanObject(!isTruthy(right))
 }
 
 func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
@@ -329,18 +504,25 @@ func evalImportStatement(node *ast.ImportStatement, env *object.Environment) obj
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	value, err := toNumber(right)
+	if err != nil {
+		return err
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return &object.Number{Value: -value}
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[string]object.Object)
+	hash := &object.Hash{}
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
+		if spread, ok := keyNode.(*ast.SpreadElement); ok {
+			if err := spreadProperties(hash, spread, env); err != nil {
+				return err
+			}
+			continue
+		}
+
+		valueNode := node.Pairs[keyNode]
 		var keyStr string
 
 		// If key is Identifier, take the name as string literal (e.g. { name: "val" })
@@ -363,159 +545,114 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return value
 		}
 
-		pairs[keyStr] = value
+		if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+			fn.Name = keyStr
+		}
+
+		hash.Set(keyStr, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
-
+// evalNumberInfixExpression applies an arithmetic or bitwise operator to
+// two numbers. It follows IEEE 754 semantics: division by zero yields
+// ±Infinity or NaN.
+func evalNumberInfixExpression(operator string, leftVal, rightVal float64) object.Object {
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Number{Value: leftVal - rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Number{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Number{Value: leftVal / rightVal}
 	case "%":
-		return &object.Integer{Value: leftVal % rightVal}
-	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
-	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
-	case "===":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!==":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-}
-
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-
-	switch operator {
-	case "+":
-		return &object.String{Value: leftVal + rightVal}
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
-	case "===":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!==":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
+		return &object.Number{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Number{Value: exponent(leftVal, rightVal)}
+	case "&", "|", "^", "<<", ">>", ">>>":
+		return &object.Number{Value: evalBitwiseOperator(operator, leftVal, rightVal)}
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-}
-
-func evalDotIndexExpression(left object.Object, rightNode ast.Node) object.Object {
-	if left.Type() == object.HASH_OBJ {
-		hash := left.(*object.Hash)
-
-		// Right node should be an identifier for dot notation
-		ident, ok := rightNode.(*ast.Identifier)
-		if !ok {
-			return newError("expected identifier after dot, got %T", rightNode)
-		}
-
-		key := ident.Value
-		val, ok := hash.Pairs[key]
-		if !ok {
-			return NULL
-		}
-		return val
+		return newError("unknown operator: %s", operator)
 	}
-	return newError("property access not supported on %s", left.Type())
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
+	index = elementIndex(left, index)
 	switch {
-	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.NUMBER_OBJ:
 		return evalArrayIndexExpression(left, index)
-	case left.Type() == object.HASH_OBJ:
+	case left.Type() == object.STRING_OBJ && index.Type() == object.NUMBER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ, left.Type() == object.ARRAY_OBJ, isNullish(left),
+		left.Type() == object.STRING_OBJ && index.Type() == object.STRING_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newTypeError("index operator not supported: %s", left.Type())
 	}
 }
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
+	idx := index.(*object.Number).Value
+	max := float64(len(arrayObject.Elements) - 1)
 
-	if idx < 0 || idx > max {
-		return NULL
+	if idx < 0 || idx > max || idx != math.Trunc(idx) {
+		return UNDEFINED
 	}
 
-	return arrayObject.Elements[idx]
+	return arrayObject.Elements[int(idx)]
 }
 
-func evalHashIndexExpression(hash, index object.Object) object.Object {
-	hashObject := hash.(*object.Hash)
+// evalStringIndexExpression is the character of str at index, counted in
+// UTF-16 code units as length is.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	units := utf16.Encode([]rune(str.(*object.String).Value))
+	idx := index.(*object.Number).Value
 
-	var key string
-	if s, ok := index.(*object.String); ok {
-		key = s.Value
-	} else {
-		key = index.Inspect()
+	if idx < 0 || idx >= float64(len(units)) || idx != math.Trunc(idx) {
+		return UNDEFINED
 	}
 
-	val, ok := hashObject.Pairs[key]
-	if !ok {
-		return NULL
-	}
-
-	return val
+	return &object.String{Value: string(utf16.Decode(units[int(idx) : int(idx)+1]))}
 }
 
-func evalStringConcatenation(left, right object.Object) object.Object {
-	var leftVal, rightVal string
-
-	if left.Type() == object.STRING_OBJ {
-		leftVal = left.(*object.String).Value
-	} else {
-		leftVal = left.Inspect()
+// elementIndex converts a key of an array or string that is the canonical
+// form of an index, such as the "1" for-in gives, to that index. Other
+// keys are returned unchanged.
+func elementIndex(obj, key object.Object) object.Object {
+	str, ok := key.(*object.String)
+	if !ok || obj.Type() != object.ARRAY_OBJ && obj.Type() != object.STRING_OBJ {
+		return key
 	}
-
-	if right.Type() == object.STRING_OBJ {
-		rightVal = right.(*object.String).Value
-	} else {
-		rightVal = right.Inspect()
+	n, err := strconv.Atoi(str.Value)
+	if err != nil || n < 0 || n >= maxArrayLength || strconv.Itoa(n) != str.Value {
+		return key
 	}
+	return &object.Number{Value: float64(n)}
+}
 
-	return &object.String{Value: leftVal + rightVal}
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	return getProperty(hash, propertyKey(index), hash)
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
-	}
-
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
-	}
-
-	return newError("identifier not found: %s", node.Value)
+	return lookupVariable(node.Value, env)
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			values, err := spreadValues(spread, env)
+			if err != nil {
+				return []object.Object{err}
+			}
+			result = append(result, values...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -526,29 +663,151 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
+// evalCallee evaluates the function part of a call. For method calls such
+// as obj.f() or obj["f"]() it also returns the receiver to bind as 'this'.
+func evalCallee(node ast.Expression, env *object.Environment) (object.Object, object.Object) {
+	switch node := node.(type) {
+	case *ast.InfixExpression:
+		if node.Operator == "." {
+			return evalMemberExpression(node, env)
+		}
+	case *ast.IndexExpression:
+		receiver := Eval(node.Left, env)
+		if isError(receiver) {
+			return NULL, receiver
+		}
+		if skipsChain(receiver, node.Optional) {
+			return NULL, brokenChain
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return NULL, index
+		}
+		return receiver, evalIndexExpression(receiver, index)
+	}
+
+	return UNDEFINED, Eval(node, env)
+}
+
+// evalNewExpression calls a constructor. Classes and plain functions run
+// with 'this' bound to a fresh object, which is the result unless they
+// return an object themselves; builtin constructors build their own result.
+func evalNewExpression(node *ast.NewExpression, env *object.Environment) object.Object {
+	constructor := Eval(node.Constructor, env)
+	if isError(constructor) {
+		return constructor
+	}
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	switch constructor := constructor.(type) {
+	case *object.Class:
+		return newInstance(constructor, args, node.Pos())
+	case *object.Builtin:
+		return callWithFrame(constructor, NULL, args, node.Pos())
+	case *object.Function:
+		if constructor.Arrow {
+			return newTypeError("%s is not a constructor", node.Constructor.String())
+		}
+		this := &object.Hash{}
+		result := callWithFrame(constructor, this, args, node.Pos())
+		if isError(result) {
+			return result
+		}
+		if _, ok := result.(*object.Hash); ok {
+			return result
+		}
+		return this
+	default:
+		return newTypeError("%s is not a constructor", node.Constructor.String())
+	}
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
+	return applyMethod(fn, UNDEFINED, args)
+}
+
+// applyMethod calls fn with 'this' bound to the given receiver.
+func applyMethod(fn object.Object, this object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		if err := enterCall(); err != nil {
+			return err
+		}
+		defer exitCall()
+		if fn.Async {
+			return callAsync(fn, this, args)
+		}
+		extendedEnv, err := extendFunctionEnv(fn, this, args)
+		if err != nil {
+			return err
+		}
 		evaluated := evalBlockStatement(fn.Body, extendedEnv)
+		if evaluated == nil {
+			return UNDEFINED
+		}
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
+		if fn.Method != nil {
+			return fn.Method(this, args...)
+		}
 		return fn.Fn(args...)
 
+	case *object.Class:
+		return newTypeError("class constructor %s cannot be invoked without 'new'", fn.Name)
+
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypeError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// extendFunctionEnv binds the parameters of fn to the arguments of a call.
+// Defaults are evaluated in the new scope from left to right, so that
+// they can refer to the parameters before them.
+func extendFunctionEnv(fn *object.Function, this object.Object, args []object.Object) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
-	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+	// Arrow functions see the 'this' and 'arguments' of their enclosing
+	// function instead
+	if !fn.Arrow {
+		env.Set("this", this)
+		env.Set("arguments", &object.Array{Elements: append([]object.Object{}, args...)})
 	}
 
-	return env
+	// Missing arguments are undefined, and extra ones are dropped unless a
+	// rest parameter collects them
+	params := fn.Parameters
+	if fn.Rest {
+		params = params[:len(params)-1]
+	}
+	for i, param := range params {
+		var arg object.Object = UNDEFINED
+		if i < len(args) {
+			arg = args[i]
+		}
+		if arg == UNDEFINED && param.Default != nil {
+			arg = Eval(param.Default, env)
+			if isError(arg) {
+				return nil, arg
+			}
+			nameFunction(arg, param)
+		}
+		env.Set(param.Value, arg)
+	}
+
+	if fn.Rest {
+		rest := &object.Array{Elements: []object.Object{}}
+		if len(args) > len(params) {
+			rest.Elements = append(rest.Elements, args[len(params):]...)
+		}
+		env.Set(fn.Parameters[len(params)].Value, rest)
+	}
+
+	hoistVars(fn.Body.Statements, env)
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -572,6 +831,10 @@ func newError(format string, a ...interface{}) *object.Error {
 var builtins map[string]object.Object
 
 func init() {
+	errorClass := newErrorClass("Error", nil)
+	promiseClass = newPromiseClass()
+	arrayClass = newArrayClass()
+
 	builtins = map[string]object.Object{
 		"console": &object.Hash{
 			Pairs: map[string]object.Object{
@@ -579,16 +842,73 @@ func init() {
 					Fn: func(args ...object.Object) object.Object {
 						var out []string
 						for _, arg := range args {
+							// Errors print their stack trace, as in Node
+							if stack, ok := errorStack(arg); ok {
+								out = append(out, stack)
+								continue
+							}
 							out = append(out, arg.Inspect())
 						}
 						fmt.Println(strings.Join(out, " "))
-						return NULL
+						return UNDEFINED
 					},
 				},
 			},
 		},
+		"undefined": UNDEFINED,
+		"NaN":       &object.Number{Value: math.NaN()},
+		"Infinity":  &object.Number{Value: math.Inf(1)},
+		"Number": &object.Hash{
+			Pairs: map[string]object.Object{
+				"NaN":               &object.Number{Value: math.NaN()},
+				"POSITIVE_INFINITY": &object.Number{Value: math.Inf(1)},
+				"NEGATIVE_INFINITY": &object.Number{Value: math.Inf(-1)},
+				"MAX_SAFE_INTEGER":  &object.Number{Value: 1<<53 - 1},
+				"MIN_SAFE_INTEGER":  &object.Number{Value: -(1<<53 - 1)},
+				"MAX_VALUE":         &object.Number{Value: math.MaxFloat64},
+				"MIN_VALUE":         &object.Number{Value: math.SmallestNonzeroFloat64},
+				"EPSILON":           &object.Number{Value: math.Nextafter(1, 2) - 1},
+				"isNaN": numberPredicate(func(f float64) bool {
+					return math.IsNaN(f)
+				}),
+				"isFinite": numberPredicate(func(f float64) bool {
+					return !math.IsNaN(f) && !math.IsInf(f, 0)
+				}),
+				"isInteger": numberPredicate(func(f float64) bool {
+					return !math.IsInf(f, 0) && f == math.Trunc(f)
+				}),
+				"isSafeInteger": numberPredicate(func(f float64) bool {
+					return f == math.Trunc(f) && math.Abs(f) <= 1<<53-1
+				}),
+			},
+		},
+		"JSON": &object.Hash{
+			Pairs: map[string]object.Object{
+				"stringify": &object.Builtin{Fn: jsonStringify},
+			},
+		},
+		"String": &object.Hash{
+			Pairs: map[string]object.Object{
+				"raw": &object.Builtin{Fn: stringRaw},
+			},
+		},
+		"Error":          errorClass,
+		"TypeError":      newErrorClass("TypeError", errorClass),
+		"RangeError":     newErrorClass("RangeError", errorClass),
+		"SyntaxError":    newErrorClass("SyntaxError", errorClass),
+		"ReferenceError": newErrorClass("ReferenceError", errorClass),
+		"AggregateError": newAggregateErrorClass(errorClass),
+		"Promise":        promiseClass,
+		"Array":          arrayClass,
+		"setTimeout":     newTimerBuiltin("setTimeout", false),
+		"setInterval":    newTimerBuiltin("setInterval", true),
+		"setImmediate":   &object.Builtin{Fn: setImmediate},
+		"clearTimeout":   &object.Builtin{Fn: clearTimer},
+		"clearInterval":  &object.Builtin{Fn: clearTimer},
+		"clearImmediate": &object.Builtin{Fn: clearTimer},
+		"queueMicrotask": &object.Builtin{Fn: queueMicrotask},
 		"fetch": &object.Builtin{
-			Fn: http.Fetch,
+			Fn: fetch,
 		},
 		"require": &object.Builtin{
 			Fn: func(args ...object.Object) object.Object {
@@ -617,72 +937,19 @@ func init() {
 	}
 }
 
-func checkType(obj object.Object, typeName string) *object.Error {
-	// Handle Array Types: number[]
-	if strings.HasSuffix(typeName, "[]") {
-		// ... existing array logic ...
-		if obj.Type() != object.ARRAY_OBJ {
-			return newError("type mismatch: expected %s, got %s", typeName, obj.Type())
-		}
-		baseType := strings.TrimSuffix(typeName, "[]")
-		array := obj.(*object.Array)
-		for _, el := range array.Elements {
-			if err := checkType(el, baseType); err != nil {
-				return err
+// numberPredicate wraps a float64 test as a Number.isXxx builtin, which
+// returns false for non-number arguments rather than converting them.
+func numberPredicate(test func(float64) bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return FALSE
 			}
-		}
-		return nil
-	}
-
-	// Handle Tuple Types: [string, number]
-	if strings.HasPrefix(typeName, "[") && strings.HasSuffix(typeName, "]") {
-		if obj.Type() != object.ARRAY_OBJ {
-			return newError("type mismatch: expected tuple %s, got %s", typeName, obj.Type())
-		}
-
-		array := obj.(*object.Array)
-		content := typeName[1 : len(typeName)-1] // strip [ and ]
-		types := strings.Split(content, ",")     // split by comma
-
-		// Clean up spaces
-		for i, t := range types {
-			types[i] = strings.TrimSpace(t)
-		}
-
-		if len(array.Elements) != len(types) {
-			return newError("type mismatch: expected tuple length %d, got %d", len(types), len(array.Elements))
-		}
-
-		for i, el := range array.Elements {
-			if err := checkType(el, types[i]); err != nil {
-				return err
+			n, ok := args[0].(*object.Number)
+			if !ok {
+				return FALSE
 			}
-		}
-		return nil
-	}
-
-	switch typeName {
-	case "any", "unknown":
-		return nil
-	case "number":
-		if obj.Type() != object.INTEGER_OBJ {
-			return newError("type mismatch: expected number, got %s", obj.Type())
-		}
-	case "string":
-		if obj.Type() != object.STRING_OBJ {
-			return newError("type mismatch: expected string, got %s", obj.Type())
-		}
-	case "boolean":
-		if obj.Type() != object.BOOLEAN_OBJ {
-			return newError("type mismatch: expected boolean, got %s", obj.Type())
-		}
-	case "never":
-		return newError("type mismatch: cannot assign to never")
-	default:
-		// If type contains '.', treat as named interface/class and allow (for now)
-		// e.g. http.IncomingMessage
-		// Also allow simple identifiers that are not primitives (e.g. MyClass)
-		return nil
+			return nativeBoolToBooleanObject(test(n.Value))
+		},
 	}
-	return nil
 }
@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"testing"
+
+	"ts-engine/lexer"
+	"ts-engine/object"
+	"ts-engine/parser"
+	"ts-engine/token"
+)
+
+// testEval parses and evaluates a .js program, returning the value of its
+// last statement.
+func testEval(t *testing.T, src string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(src), false)
+	program := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Fatalf("parse error: %s\n%s", err, src)
+	}
+	return Eval(program, object.NewEnvironment())
+}
+
+// evalTest is a program and the Inspect of the value it gives.
+type evalTest struct {
+	name string
+	src  string
+	want string
+}
+
+func runEvalTests(t *testing.T, tests []evalTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testEval(t, tt.src).Inspect(); got != tt.want {
+				t.Errorf("got %s, want %s\n%s", got, tt.want, tt.src)
+			}
+		})
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		src      string
+		pos, end token.Position
+	}{
+		{"let a = 1;\nlet b = a + missing;", token.Position{Line: 2, Column: 13, Offset: 23}, token.Position{Line: 2, Column: 20, Offset: 30}},
+		{"let f = 5;\n\n  f(1);", token.Position{Line: 3, Column: 3, Offset: 14}, token.Position{Line: 3, Column: 7, Offset: 18}},
+	}
+	for _, tt := range tests {
+		err, ok := testEval(t, tt.src).(*object.Error)
+		if !ok {
+			t.Fatalf("expected an error from %q", tt.src)
+		}
+		if err.Pos != tt.pos || err.End != tt.end {
+			t.Errorf("%q: got span %+v-%+v, want %+v-%+v", tt.src, err.Pos, err.End, tt.pos, tt.end)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"fraction", `0.5 + 0.25`, "0.75"},
+		{"exponent", `1e3 + 2.5E-1`, "1000.25"},
+		{"empty fraction", `let x = 5.; x + 5.e1`, "55"},
+		{"radix prefixes", `0xff + 0o17 + 0b101`, "275"},
+		{"separators", `1_000_000`, "1000000"},
+		{"division", `10 / 4`, "2.5"},
+		{"division by zero", `1 / 0`, "Infinity"},
+		{"negative division by zero", `-1 / 0`, "-Infinity"},
+		{"not a number", `0 / 0`, "NaN"},
+		{"negative zero", `1 / -0`, "-Infinity"},
+		{"modulo", `-7 % 3`, "-1"},
+		{"integer precision", `9007199254740992 + 1`, "9007199254740992"},
+	})
+}
+
+func TestArrowFunctions(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"expression body", `let add = (a, b) => a + b; add(2, 3)`, "5"},
+		{"single parameter", `let double = x => x * 2; double(4)`, "8"},
+		{"block body", `let f = (x) => { return x + 1; }; f(1)`, "2"},
+		{"no parameters", `let f = () => 42; f()`, "42"},
+		{"closure", `let counter = (n) => () => n; counter(7)()`, "7"},
+		{"lexical this", `
+			let obj = {
+				value: 3,
+				get: function() { let inner = () => this.value; return inner(); }
+			};
+			obj.get()`, "3"},
+	})
+}
+
+func TestDefaultParameters(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"default used", `function f(x = 1) { return x; } f()`, "1"},
+		{"default for undefined", `function f(x = 1) { return x; } f(undefined)`, "1"},
+		{"null is passed", `function f(x = 1) { return x; } f(null)`, "null"},
+		{"earlier parameters", `function f(a, b = a * 2) { return b; } f(3)`, "6"},
+		{"evaluated on each call", `
+			let n = 0;
+			function next() { n = n + 1; return n; }
+			function f(x = next()) { return x; }
+			f(); f()`, "2"},
+		{"optional parameter", `function f(x: number, y?: string) { return y; } f(1)`, "undefined"},
+		{"arrow defaults", `let f = (a, b = 10) => a + b; f(1)`, "11"},
+		{"arguments", `function f(a) { return arguments[0] + arguments[2]; } f(1, 2, 3)`, "4"},
+		{"arrows see the enclosing arguments", `function f() { let g = () => arguments[0]; return g(9); } f(4)`, "4"},
+	})
+}
+
+func TestUndefined(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"missing property", `let o = {}; o.x`, "undefined"},
+		{"missing index", `[1][5]`, "undefined"},
+		{"uninitialized let", `let x; x`, "undefined"},
+		{"no return value", `function f() {} f()`, "undefined"},
+		{"undefined global", `undefined === void 0`, "true"},
+		{"distinct from null", `null === undefined`, "false"},
+		{"typeof", `typeof undefined + " " + typeof null`, "undefined object"},
+		{"void", `let n = 0; let v = void (n = 1); n + " " + v`, "1 undefined"},
+		{"strictly equal to undefined", `let o = {}; o.x === undefined`, "true"},
+		{"reading a property of undefined", `let x; x.y`,
+			"ERROR: TypeError: Cannot read properties of undefined (reading 'y')"},
+		{"this in a plain call", `function f() { return typeof this; } f()`, "undefined"},
+		{"this in a detached method", `
+			const o = { v: 1, get: function () { return this.v; } };
+			const g = o.get;
+			g()`,
+			"ERROR: TypeError: Cannot read properties of undefined (reading 'v')"},
+		{"this in a callback", `[1].map(function () { return this; })[0]`, "undefined"},
+	})
+}
+
+func TestStringLength(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"ascii", `"abc".length`, "3"},
+		{"UTF-16 code units", `"a😀".length`, "3"},
+		{"bracket access", `"abc"["length"]`, "3"},
+		{"strings and arrays alike", `function len(x) { return x.length; } len("ab") + len([1])`, "3"},
+	})
+}
+
+func TestStringIndex(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"number index", `"abc"[1]`, "b"},
+		{"string index", `"abc"["2"]`, "c"},
+		{"out of range", `["abc"[3], "abc"[-1], "abc"[0.5]]`, "[undefined, undefined, undefined]"},
+		{"UTF-16 code units", `"é😀"[0] + "a😀".length`, "é3"},
+	})
+}
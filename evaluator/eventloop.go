@@ -0,0 +1,266 @@
+package evaluator
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// task is a unit of work run by the event loop. A task returning an error
+// has thrown an uncaught exception, which ends the program.
+type task func() object.Object
+
+// eventLoop runs the asynchronous work of a program. Microtasks, such as
+// promise reactions, all run before the next task. Tasks are, in order of
+// priority: timers that are due, tasks posted by host operations like fetch
+// and the HTTP server (usually from other goroutines), and setImmediate
+// callbacks. JavaScript itself only ever runs on one goroutine at a time.
+type eventLoop struct {
+	microtasks []task
+
+	clock      Clock
+	timers     timerHeap
+	immediates []*timer
+	active     map[int]*timer // scheduled timers and immediates by id
+	lastID     int
+	lastSeq    int
+
+	mu    sync.Mutex
+	tasks []task
+	wake  chan struct{} // signalled when a task is posted
+
+	// pending counts the host operations that will still post tasks; the
+	// program ends when there are none and the queues are empty.
+	pending int
+
+	// rejected holds promises rejected since the microtasks last drained,
+	// to report those that are still unhandled by then.
+	rejected []*object.Promise
+}
+
+var loop = &eventLoop{
+	clock:  realClock{},
+	active: map[int]*timer{},
+	wake:   make(chan struct{}, 1),
+}
+
+func (l *eventLoop) enqueueMicrotask(t task) {
+	l.microtasks = append(l.microtasks, t)
+}
+
+// hold records a host operation in progress, which keeps the program
+// running until a matching release.
+func (l *eventLoop) hold() {
+	l.pending++
+}
+
+func (l *eventLoop) release() {
+	l.pending--
+}
+
+// post queues a task. Unlike the other methods it may be called from any
+// goroutine.
+func (l *eventLoop) post(t task) {
+	l.mu.Lock()
+	l.tasks = append(l.tasks, t)
+	l.mu.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runOnce runs the next microtask or, once they are drained, the next
+// task, waiting for a timer or host operation if nothing is ready. It
+// reports false when there is no work left, and returns any uncaught
+// exception.
+func (l *eventLoop) runOnce() (bool, object.Object) {
+	if len(l.microtasks) > 0 {
+		t := l.microtasks[0]
+		l.microtasks = l.microtasks[1:]
+		return true, t()
+	}
+
+	if err := l.unhandledRejection(); err != nil {
+		return false, err
+	}
+
+	for {
+		if len(l.timers) > 0 && !l.timers[0].deadline.After(l.clock.Now()) {
+			return true, l.runTimer(heap.Pop(&l.timers).(*timer))
+		}
+
+		l.mu.Lock()
+		if len(l.tasks) > 0 {
+			t := l.tasks[0]
+			l.tasks = l.tasks[1:]
+			l.mu.Unlock()
+			return true, t()
+		}
+		l.mu.Unlock()
+
+		if len(l.immediates) > 0 {
+			t := l.immediates[0]
+			l.immediates = l.immediates[1:]
+			return true, l.runTimer(t)
+		}
+
+		if len(l.timers) == 0 && l.pending == 0 {
+			return false, nil
+		}
+
+		var deadline time.Time
+		if len(l.timers) > 0 {
+			deadline = l.timers[0].deadline
+		}
+		l.clock.Sleep(deadline, l.wake)
+	}
+}
+
+// unhandledRejection returns the exception of a promise that was rejected
+// without a handler being attached by the end of the current microtasks.
+func (l *eventLoop) unhandledRejection() *object.Error {
+	rejected := l.rejected
+	l.rejected = nil
+
+	for _, promise := range rejected {
+		if !promise.Handled {
+			return promise.Err
+		}
+	}
+	return nil
+}
+
+// Run evaluates a program and then runs the event loop until no work is
+// left. It returns the first uncaught exception, if any.
+func Run(program *ast.Program, env *object.Environment) object.Object {
+	result := Eval(program, env)
+	if isError(result) {
+		return result
+	}
+
+	for {
+		ran, err := loop.runOnce()
+		if isError(err) {
+			return err
+		}
+		if !ran {
+			return result
+		}
+	}
+}
+
+// coroutine runs the body of an async function on its own goroutine, so
+// that await can suspend it with its Go stack intact. Control is handed
+// back and forth over the channels, so only one side runs at a time.
+type coroutine struct {
+	resume chan object.Object // the result of the await being resumed
+	yield  chan struct{}      // the coroutine has suspended or returned
+	frames []object.CallFrame // its calls, while it is suspended
+}
+
+// current is the running coroutine, or nil outside async functions.
+var current *coroutine
+
+// callAsync calls an async function, which runs until its first await and
+// returns a promise of its result.
+func callAsync(fn *object.Function, this object.Object, args []object.Object) object.Object {
+	promise := newPromise()
+	co := &coroutine{resume: make(chan object.Object), yield: make(chan struct{})}
+
+	go func() {
+		<-co.resume
+
+		var result object.Object
+		env, err := extendFunctionEnv(fn, this, args)
+		if err != nil {
+			result = err
+		} else {
+			result = unwrapReturnValue(evalBlockStatement(fn.Body, env))
+		}
+		if err, ok := result.(*object.Error); ok {
+			rejectPromise(promise, err)
+		} else {
+			if result == nil {
+				result = UNDEFINED
+			}
+			resolvePromise(promise, result)
+		}
+
+		co.yield <- struct{}{}
+	}()
+
+	co.run(nil)
+	return promise
+}
+
+// run passes control to the coroutine until it awaits or returns. val is
+// the result of the await it resumes from: a value, or an *object.Error to
+// throw there. The coroutine's calls are put back on the call stack while
+// it runs, on top of those of whatever resumed it.
+func (co *coroutine) run(val object.Object) {
+	caller := current
+	base := len(callStack)
+
+	current = co
+	callStack = append(callStack, co.frames...)
+
+	co.resume <- val
+	<-co.yield
+
+	co.frames = append([]object.CallFrame(nil), callStack[base:]...)
+	callStack = callStack[:base]
+	current = caller
+}
+
+// evalAwait waits for val to settle and returns its value, or its
+// exception. In an async function the coroutine is suspended; at the top
+// level, where there is none, the event loop runs until val settles.
+func evalAwait(val object.Object) object.Object {
+	state := promiseResolve(val).Internal.(*object.Promise)
+
+	co := current
+	if co == nil {
+		return awaitTopLevel(state)
+	}
+
+	onSettled(state, func() object.Object {
+		co.run(settledResult(state))
+		return NULL
+	})
+
+	co.yield <- struct{}{}
+	return <-co.resume
+}
+
+func awaitTopLevel(state *object.Promise) object.Object {
+	// Continue in a reaction even if the promise has already settled, so
+	// that microtasks queued before the await run first, as in a module.
+	resumed := false
+	onSettled(state, func() object.Object {
+		resumed = true
+		return NULL
+	})
+
+	for !resumed {
+		ran, err := loop.runOnce()
+		if isError(err) {
+			return err
+		}
+		if !ran {
+			return newError("top-level await never settled")
+		}
+	}
+
+	return settledResult(state)
+}
+
+func settledResult(state *object.Promise) object.Object {
+	if state.State == object.PromiseRejected {
+		return state.Err
+	}
+	return state.Value
+}
@@ -0,0 +1,218 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"ts-engine/object"
+)
+
+// jsonStringify implements JSON.stringify(value, replacer, space). A
+// function replacer is called on each key and value, and may replace the
+// value; an array replacer lists the object properties to write. space
+// indents nested values by that many spaces, or by the string itself. A
+// value JSON cannot represent, such as undefined or a function, gives
+// undefined.
+func jsonStringify(args ...object.Object) object.Object {
+	w := &jsonWriter{seen: map[object.Object]bool{}}
+	switch replacer := argument(args, 1).(type) {
+	case *object.Function, *object.Builtin:
+		w.replacer = replacer
+	case *object.Array:
+		w.keys = []string{}
+		for _, el := range replacer.Elements {
+			switch el.(type) {
+			case *object.String, *object.Number:
+				if key := el.Inspect(); !slices.Contains(w.keys, key) {
+					w.keys = append(w.keys, key)
+				}
+			}
+		}
+	}
+	switch space := argument(args, 2).(type) {
+	case *object.Number:
+		w.indent = strings.Repeat(" ", int(math.Max(0, math.Min(10, space.Value))))
+	case *object.String:
+		w.indent = space.Value
+		if len(w.indent) > 10 {
+			w.indent = w.indent[:10]
+		}
+	}
+
+	// The value is the "" property of an object holding it, which the
+	// replacer sees as 'this'
+	holder := &object.Hash{}
+	holder.Set("", argument(args, 0))
+	val := w.property(holder, "", argument(args, 0))
+	if isError(val) {
+		return val
+	}
+	if !jsonValue(val) {
+		return UNDEFINED
+	}
+	if err := w.write(val, ""); err != nil {
+		return err
+	}
+	return &object.String{Value: w.out.String()}
+}
+
+type jsonWriter struct {
+	out      strings.Builder
+	indent   string
+	replacer object.Object          // function replacer, or nil
+	keys     []string               // properties listed by an array replacer, or nil
+	seen     map[object.Object]bool // the arrays and objects being written
+}
+
+// property returns the value to write for the property key of holder,
+// whose value is val: the result of val.toJSON(key), if it has that
+// method, passed through the replacer.
+func (w *jsonWriter) property(holder object.Object, key string, val object.Object) object.Object {
+	if hash, ok := val.(*object.Hash); ok {
+		if toJSON := getProperty(hash, "toJSON", hash); isCallable(toJSON) {
+			val = applyMethod(toJSON, hash, []object.Object{&object.String{Value: key}})
+			if isError(val) {
+				return val
+			}
+		}
+	}
+	if w.replacer != nil {
+		val = applyMethod(w.replacer, holder, []object.Object{&object.String{Value: key}, val})
+	}
+	return val
+}
+
+// jsonValue reports whether val has a JSON representation.
+func jsonValue(val object.Object) bool {
+	switch val.(type) {
+	case *object.Undefined, *object.Function, *object.Builtin, *object.Class:
+		return false
+	}
+	return true
+}
+
+// write writes val, whose nested values are indented by prefix.
+func (w *jsonWriter) write(val object.Object, prefix string) object.Object {
+	switch val := val.(type) {
+	case *object.Null:
+		w.out.WriteString("null")
+	case *object.Boolean:
+		w.out.WriteString(val.Inspect())
+	case *object.Number:
+		if math.IsNaN(val.Value) || math.IsInf(val.Value, 0) {
+			w.out.WriteString("null")
+		} else {
+			w.out.WriteString(val.Inspect())
+		}
+	case *object.String:
+		w.out.WriteString(jsonQuote(val.Value))
+	case *object.Array:
+		return w.writeArray(val, prefix)
+	case *object.Hash:
+		return w.writeObject(val, prefix)
+	default:
+		w.out.WriteString("{}")
+	}
+	return nil
+}
+
+func (w *jsonWriter) writeArray(arr *object.Array, prefix string) object.Object {
+	if w.seen[arr] {
+		return newTypeError("Converting circular structure to JSON")
+	}
+	w.seen[arr] = true
+	defer delete(w.seen, arr)
+
+	inner := prefix + w.indent
+	w.out.WriteString("[")
+	for i, el := range arr.Elements {
+		if i > 0 {
+			w.out.WriteString(",")
+		}
+		w.newline(inner)
+		el = w.property(arr, strconv.Itoa(i), el)
+		if isError(el) {
+			return el
+		}
+		// Elements JSON cannot represent are written as null
+		if !jsonValue(el) {
+			el = NULL
+		}
+		if err := w.write(el, inner); err != nil {
+			return err
+		}
+	}
+	if len(arr.Elements) > 0 {
+		w.newline(prefix)
+	}
+	w.out.WriteString("]")
+	return nil
+}
+
+func (w *jsonWriter) writeObject(hash *object.Hash, prefix string) object.Object {
+	if w.seen[hash] {
+		return newTypeError("Converting circular structure to JSON")
+	}
+	w.seen[hash] = true
+	defer delete(w.seen, hash)
+
+	inner := prefix + w.indent
+	w.out.WriteString("{")
+	keys := hash.Keys()
+	if w.keys != nil {
+		keys = w.keys
+	}
+	written := 0
+	for _, key := range keys {
+		val := getProperty(hash, key, hash)
+		if isError(val) {
+			return val
+		}
+		val = w.property(hash, key, val)
+		if isError(val) {
+			return val
+		}
+		// Properties JSON cannot represent are left out
+		if !jsonValue(val) {
+			continue
+		}
+
+		if written > 0 {
+			w.out.WriteString(",")
+		}
+		w.newline(inner)
+		w.out.WriteString(jsonQuote(key))
+		w.out.WriteString(":")
+		if w.indent != "" {
+			w.out.WriteString(" ")
+		}
+		if err := w.write(val, inner); err != nil {
+			return err
+		}
+		written++
+	}
+	if written > 0 {
+		w.newline(prefix)
+	}
+	w.out.WriteString("}")
+	return nil
+}
+
+// newline starts a new line indented by prefix, if output is indented.
+func (w *jsonWriter) newline(prefix string) {
+	if w.indent != "" {
+		w.out.WriteString("\n" + prefix)
+	}
+}
+
+// jsonQuote quotes s as a JSON string.
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,24 @@
+package evaluator
+
+import "testing"
+
+func TestJSONStringify(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"primitives", `JSON.stringify([1, "a", true, null])`, `[1,"a",true,null]`},
+		{"objects", `JSON.stringify({ a: 1, b: { c: "x" } })`, `{"a":1,"b":{"c":"x"}}`},
+		{"undefined properties are left out", `JSON.stringify({ a: undefined, b: 1, f: function() {} })`, `{"b":1}`},
+		{"undefined elements become null", `JSON.stringify([undefined, function() {}])`, `[null,null]`},
+		{"undefined alone", `JSON.stringify(undefined)`, "undefined"},
+		{"escapes", `JSON.stringify("a\"b\n")`, `"a\"b\n"`},
+		{"non-finite numbers", `JSON.stringify([NaN, Infinity])`, `[null,null]`},
+		{"indentation", `JSON.stringify({ a: [1] }, null, 2)`, "{\n  \"a\": [\n    1\n  ]\n}"},
+		{"function replacer", `JSON.stringify({ a: 1, b: "x", c: [2] }, (k, v) => typeof v === "number" ? v * 10 : v)`,
+			`{"a":10,"b":"x","c":[20]}`},
+		{"replacer leaves out undefined", `JSON.stringify({ a: 1, b: 2 }, (k, v) => k === "b" ? undefined : v)`, `{"a":1}`},
+		{"replacer sees the holder", `JSON.stringify(5, function (k, v) { return this[k] + 1; })`, "6"},
+		{"array replacer", `JSON.stringify({ a: 1, b: 2, c: { a: 3, d: 4 } }, ["a", "c"])`, `{"a":1,"c":{"a":3}}`},
+		{"toJSON", `
+			class Point { constructor(x) { this.x = x; } toJSON(key) { return key + ":" + this.x; } }
+			JSON.stringify({ p: new Point(1) })`, `{"p":"p:1"}`},
+	})
+}
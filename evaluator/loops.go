@@ -0,0 +1,292 @@
+package evaluator
+
+import (
+	"slices"
+	"strconv"
+	"ts-engine/ast"
+	"ts-engine/object"
+	"ts-engine/token"
+	"unicode/utf16"
+)
+
+// evalLabeledStatement runs a labeled statement. Loops receive their labels
+// so that 'continue label' can resume them; 'break label' ends here.
+func evalLabeledStatement(node *ast.LabeledStatement, env *object.Environment) object.Object {
+	labels := []string{node.Label.Value}
+	body := node.Body
+	for {
+		inner, ok := body.(*ast.LabeledStatement)
+		if !ok {
+			break
+		}
+		labels = append(labels, inner.Label.Value)
+		body = inner.Body
+	}
+
+	var result object.Object
+	switch body := body.(type) {
+	case *ast.WhileStatement:
+		result = evalWhileStatement(body, env, labels)
+	case *ast.DoWhileStatement:
+		result = evalDoWhileStatement(body, env, labels)
+	case *ast.ForStatement:
+		result = evalForStatement(body, env, labels)
+	case *ast.ForOfStatement:
+		result = evalForOfStatement(body, env, labels)
+	case *ast.ForInStatement:
+		result = evalForInStatement(body, env, labels)
+	default:
+		result = Eval(body, env)
+	}
+
+	if brk, ok := result.(*object.Break); ok && slices.Contains(labels, brk.Label) {
+		return NULL
+	}
+	return result
+}
+
+// loopExit decides whether a loop stops after running its body once. When
+// it stops, the returned object (if any) must be propagated to the caller:
+// a return value, an error, or a break/continue aimed at an outer label.
+func loopExit(result object.Object, labels []string) (bool, object.Object) {
+	switch result := result.(type) {
+	case *object.Break:
+		if result.Label == "" || slices.Contains(labels, result.Label) {
+			return true, nil
+		}
+		return true, result
+	case *object.Continue:
+		if result.Label == "" || slices.Contains(labels, result.Label) {
+			return false, nil
+		}
+		return true, result
+	case *object.ReturnValue, *object.Error:
+		return true, result
+	}
+	return false, nil
+}
+
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment, labels []string) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(node.Body, env)
+		if stop, out := loopExit(result, labels); stop {
+			return loopResult(out)
+		}
+	}
+}
+
+func evalDoWhileStatement(node *ast.DoWhileStatement, env *object.Environment, labels []string) object.Object {
+	for {
+		result := Eval(node.Body, env)
+		if stop, out := loopExit(result, labels); stop {
+			return loopResult(out)
+		}
+
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+	}
+}
+
+// evalForStatement runs a three-clause for loop. Variables declared with
+// let or const in the initializer get a fresh binding per iteration, so
+// closures created in the body capture that iteration's value.
+func evalForStatement(node *ast.ForStatement, env *object.Environment, labels []string) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	var perIteration []string
+	if node.Init != nil {
+		initEnv := loopEnv
+		for _, decl := range declarations(node.Init) {
+			if decl.Token.Type == token.VAR {
+				initEnv = env
+			} else {
+				perIteration = append(perIteration, declaredNames(decl)...)
+			}
+		}
+
+		init := Eval(node.Init, initEnv)
+		if isError(init) {
+			return init
+		}
+	}
+
+	iterEnv := copyLoopBindings(loopEnv, env, perIteration)
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, iterEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+
+		result := Eval(node.Body, iterEnv)
+		if stop, out := loopExit(result, labels); stop {
+			return loopResult(out)
+		}
+
+		iterEnv = copyLoopBindings(iterEnv, env, perIteration)
+		if node.Update != nil {
+			update := Eval(node.Update, iterEnv)
+			if isError(update) {
+				return update
+			}
+		}
+	}
+}
+
+// copyLoopBindings starts a new iteration scope holding copies of the
+// per-iteration bindings of the previous one.
+func copyLoopBindings(prev, outer *object.Environment, names []string) *object.Environment {
+	if len(names) == 0 {
+		return prev
+	}
+
+	next := object.NewEnclosedEnvironment(outer)
+	for _, name := range names {
+		if val, ok := prev.GetCurrent(name); ok {
+			if prev.IsConst(name) {
+				next.SetConst(name, val)
+			} else {
+				next.Set(name, val)
+			}
+			if t, _, ok := prev.Annotation(name); ok {
+				next.Annotate(name, t)
+			}
+		}
+	}
+	return next
+}
+
+func evalForOfStatement(node *ast.ForOfStatement, env *object.Environment, labels []string) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var next func() (object.Object, bool)
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// Arrays are iterated live, so elements pushed by the body are visited
+		i := 0
+		next = func() (object.Object, bool) {
+			if i >= len(iterable.Elements) {
+				return nil, false
+			}
+			i++
+			return iterable.Elements[i-1], true
+		}
+	case *object.String:
+		chars := []rune(iterable.Value)
+		i := 0
+		next = func() (object.Object, bool) {
+			if i >= len(chars) {
+				return nil, false
+			}
+			i++
+			return &object.String{Value: string(chars[i-1])}, true
+		}
+	default:
+		return newTypeError("%s is not iterable", iterable.Type())
+	}
+
+	var variable ast.Expression = node.Variable
+	if node.Pattern != nil {
+		variable = node.Pattern
+	}
+
+	for {
+		val, ok := next()
+		if !ok {
+			return NULL
+		}
+
+		result := evalForEachIteration(node.Declaration, variable, val, node.Body, env)
+		if stop, out := loopExit(result, labels); stop {
+			return loopResult(out)
+		}
+	}
+}
+
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment, labels []string) object.Object {
+	obj := Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	var keys []string
+	switch obj := obj.(type) {
+	case *object.Hash:
+		keys = obj.Keys()
+	case *object.Array:
+		for i := range obj.Elements {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	case *object.String:
+		for i := range utf16.Encode([]rune(obj.Value)) {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	}
+
+	for _, key := range keys {
+		// Keys deleted by an earlier iteration are skipped
+		if hash, ok := obj.(*object.Hash); ok {
+			if _, ok := hash.GetOwn(key); !ok {
+				continue
+			}
+		}
+
+		result := evalForEachIteration(node.Declaration, node.Variable, &object.String{Value: key}, node.Body, env)
+		if stop, out := loopExit(result, labels); stop {
+			return loopResult(out)
+		}
+	}
+
+	return NULL
+}
+
+// evalForEachIteration binds the loop variable of a for-of or for-in loop to
+// val, unpacking it if the variable is a pattern, and runs the body once.
+// Declared variables are fresh per iteration.
+func evalForEachIteration(decl token.Token, variable ast.Expression, val object.Object, body ast.Statement, env *object.Environment) object.Object {
+	iterEnv := object.NewEnclosedEnvironment(env)
+
+	var result object.Object
+	switch decl.Type {
+	case token.LET, token.CONST, token.VAR:
+		result = destructure(variable, val, iterEnv, func(target ast.Expression, v object.Object) object.Object {
+			return declareVariable(decl.Type, target.(*ast.Identifier).Value, v, iterEnv)
+		})
+	default:
+		result = assignTo(variable, val, env)
+	}
+	if isError(result) {
+		return result
+	}
+
+	return Eval(body, iterEnv)
+}
+
+// loopResult is the value of a finished loop: whatever it has to propagate,
+// or NULL.
+func loopResult(out object.Object) object.Object {
+	if out == nil {
+		return NULL
+	}
+	return out
+}
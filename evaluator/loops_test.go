@@ -0,0 +1,56 @@
+package evaluator
+
+import "testing"
+
+func TestLoops(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"while", `let i = 0; let s = ""; while (i < 3) { s = s + i; i = i + 1; } s`, "012"},
+		{"do-while runs once", `let n = 0; do { n = n + 1; } while (false); n`, "1"},
+		{"for", `let s = 0; for (let i = 1; i < 5; i = i + 1) { s = s + i; } s`, "10"},
+		{"for-of array", `let s = ""; for (const x of [1, 2, 3]) { s = s + x; } s`, "123"},
+		{"for-of string", `let s = ""; for (const c of "abc") { s = c + s; } s`, "cba"},
+		{"for-in object", `let s = ""; for (const k in { a: 1, b: 2 }) { s = s + k; } s`, "ab"},
+		{"for-in array", `let s = ""; for (const i in ["x", "y"]) { s = s + i; } s`, "01"},
+		{"for-in reads array elements", `const xs = ["x", "y"]; let s = ""; for (const k in xs) { s = s + xs[k]; } s`, "xy"},
+		{"for-in writes array elements", `const xs = [1, 2]; for (const k in xs) { xs[k] = xs[k] * 10; } xs`, "[10, 20]"},
+		{"for-in reads string characters", `const str = "abc"; let s = ""; for (const k in str) { s = str[k] + s; } s`, "cba"},
+		{"break and continue", `
+			let s = "";
+			for (let i = 0; i < 10; i = i + 1) {
+				if (i == 1) { continue; }
+				if (i == 4) { break; }
+				s = s + i;
+			}
+			s`, "023"},
+		{"labeled continue", `
+			let s = "";
+			outer: for (const a of [1, 2]) {
+				for (const b of [1, 2]) {
+					if (b == 2) { continue outer; }
+					s = s + a + b;
+				}
+			}
+			s`, "1121"},
+		{"labeled break", `
+			let s = "";
+			outer: while (true) {
+				while (true) { s = s + "x"; break outer; }
+			}
+			s`, "x"},
+		{"per-iteration binding", `
+			let first;
+			for (let i = 0; i < 3; i = i + 1) {
+				if (i == 0) { first = () => i; }
+			}
+			first()`, "0"},
+		{"several declarators", `
+			let s = "";
+			for (let i = 0, j = 3; i < j; i++, j--) { s = s + i + j; }
+			s`, "0312"},
+		{"per-iteration bindings of several declarators", `
+			const fs = [];
+			for (let i = 0, j = 10; i < 2; i++) { fs.push(() => i + j); }
+			fs[0]() + fs[1]()`, "21"},
+		{"var declarators", `for (var i = 0, j = 2; i < j; i++) {} i + j`, "4"},
+	})
+}
@@ -0,0 +1,191 @@
+package evaluator
+
+import (
+	"math"
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// evalLogicalExpression evaluates &&, || and ??. The right operand is only
+// evaluated when the left one does not decide the result, and the result
+// is whichever operand was evaluated last, not a boolean: "" || "none" is
+// "none".
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	switch node.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return left
+		}
+	case "||":
+		if isTruthy(left) {
+			return left
+		}
+	case "??":
+		if !isNullish(left) {
+			return left
+		}
+	}
+	return Eval(node.Right, env)
+}
+
+func evalConditionalExpression(node *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := Eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(node.Consequence, env)
+	}
+	return Eval(node.Alternative, env)
+}
+
+// evalSequenceExpression evaluates a, b, c from left to right and gives the
+// value of the last one.
+func evalSequenceExpression(node *ast.SequenceExpression, env *object.Environment) object.Object {
+	var result object.Object
+	for _, exp := range node.Expressions {
+		result = Eval(exp, env)
+		if isError(result) {
+			return result
+		}
+	}
+	return result
+}
+
+// brokenChain is the value of the links of an optional chain after one was
+// skipped, as in a?.b.c when a is null. It is passed along the rest of the
+// chain, which is skipped as well, and the chain as a whole gives undefined.
+var brokenChain = &marker{"broken chain"}
+
+// skipsChain reports whether a link of an optional chain on obj is skipped:
+// obj is null or undefined and the link is optional, or an earlier link
+// was skipped.
+func skipsChain(obj object.Object, optional bool) bool {
+	return obj == brokenChain || optional && isNullish(obj)
+}
+
+func evalOptionalChain(node *ast.OptionalChain, env *object.Environment) object.Object {
+	val := Eval(node.Expression, env)
+	if val == brokenChain {
+		return UNDEFINED
+	}
+	return val
+}
+
+// evalDelete evaluates delete target. Deleting a property removes it from
+// the object, and deleting an array element leaves undefined in its place.
+// Anything but a property reference is evaluated and gives true.
+func evalDelete(target ast.Expression, env *object.Environment) object.Object {
+	if chain, ok := target.(*ast.OptionalChain); ok {
+		target = chain.Expression
+		// delete a?.b does nothing if a is null or undefined
+		if member, ok := target.(*ast.InfixExpression); ok && member.Optional {
+			obj := Eval(member.Left, env)
+			if isError(obj) {
+				return obj
+			}
+			if isNullish(obj) {
+				return TRUE
+			}
+			return deleteProperty(obj, &object.String{Value: member.Right.(*ast.Identifier).Value})
+		}
+	}
+
+	isReference := false
+	switch target := target.(type) {
+	case *ast.InfixExpression:
+		if target.Operator == "." && isPrivateName(target.Right.(*ast.Identifier).Value) {
+			return newSyntaxError("private members cannot be deleted")
+		}
+		isReference = target.Operator == "."
+	case *ast.IndexExpression:
+		isReference = true
+	}
+	if isReference {
+		ref, err := evalReference(target, env)
+		if err != nil {
+			return err
+		}
+		return deleteProperty(ref.obj, ref.key)
+	}
+
+	if val := Eval(target, env); isError(val) {
+		return val
+	}
+	return TRUE
+}
+
+func deleteProperty(obj, key object.Object) object.Object {
+	if arr, ok := obj.(*object.Array); ok {
+		if n, isNumber := key.(*object.Number); isNumber {
+			if n.Value >= 0 && n.Value == math.Trunc(n.Value) && n.Value < float64(len(arr.Elements)) {
+				arr.Elements[int(n.Value)] = UNDEFINED
+			}
+			return TRUE
+		}
+	}
+
+	props, ok := properties(obj)
+	if !ok {
+		if isNullish(obj) {
+			return newTypeError("Cannot convert undefined or null to object")
+		}
+		return TRUE
+	}
+	props.Delete(propertyKey(key))
+	return TRUE
+}
+
+// toUint32 converts a number to the unsigned 32-bit integer the bitwise
+// operators work on: NaN and the infinities become 0, and other values are
+// truncated and wrapped modulo 2^32.
+func toUint32(f float64) uint32 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	f = math.Mod(math.Trunc(f), 1<<32)
+	if f < 0 {
+		f += 1 << 32
+	}
+	return uint32(f)
+}
+
+// toInt32 is toUint32 read as a signed integer.
+func toInt32(f float64) int32 {
+	return int32(toUint32(f))
+}
+
+// evalBitwiseOperator evaluates the bitwise and shift operators, whose
+// operands are 32-bit integers. Shift counts are taken modulo 32.
+func evalBitwiseOperator(operator string, left, right float64) float64 {
+	shift := toUint32(right) & 31
+	switch operator {
+	case "&":
+		return float64(toInt32(left) & toInt32(right))
+	case "|":
+		return float64(toInt32(left) | toInt32(right))
+	case "^":
+		return float64(toInt32(left) ^ toInt32(right))
+	case "<<":
+		return float64(toInt32(left) << shift)
+	case ">>":
+		return float64(toInt32(left) >> shift)
+	default: // >>>
+		return float64(toUint32(left) >> shift)
+	}
+}
+
+// exponent evaluates base ** exp. It differs from math.Pow in that 1 and
+// -1 raised to an infinite power are NaN.
+func exponent(base, exp float64) float64 {
+	if math.Abs(base) == 1 && math.IsInf(exp, 0) {
+		return math.NaN()
+	}
+	return math.Pow(base, exp)
+}
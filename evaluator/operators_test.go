@@ -0,0 +1,30 @@
+package evaluator
+
+import "testing"
+
+func TestOperators(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"comparison", `[1 <= 1, 2 >= 3, 1 < 2, 2 > 1]`, "[true, false, true, true]"},
+		{"exponent is right-associative", `2 ** 3 ** 2`, "512"},
+		{"parenthesised unary base", `(-2) ** 2`, "4"},
+		{"&& gives an operand", `null && "x"`, "null"},
+		{"|| gives an operand", `false || "fallback"`, "fallback"},
+		{"short-circuit", `let n = 0; false && n++; true || n++; n`, "0"},
+		{"??", `[null ?? 1, 0 ?? 1]`, "[1, 0]"},
+		{"conditional", `let x = 5; x > 3 ? "big" : "small"`, "big"},
+		{"nested conditional", `let x = 0; x < 0 ? "neg" : x == 0 ? "zero" : "pos"`, "zero"},
+		{"bitwise", `[5 & 3, 5 | 3, 5 ^ 3, ~5]`, "[1, 7, 6, -6]"},
+		{"shifts", `[1 << 4, -16 >> 2, -16 >>> 28]`, "[16, -4, 15]"},
+		{"in", `let o = { a: 1 }; ["a" in o, "b" in o, 0 in [1]]`, "[true, false, true]"},
+		{"typeof", `[typeof 1, typeof "s", typeof true, typeof {}, typeof function() {}, typeof nope]`,
+			"[number, string, boolean, object, function, undefined]"},
+		{"delete", `let o = { a: 1, b: 2 }; delete o.a; "a" in o`, "false"},
+		{"comma", `let x = (1, 2, 3); x`, "3"},
+		{"comma in a for update", `let s = ""; for (let i = 0; i < 3; i++, s = s + i) {} s`, "123"},
+		{"optional chaining", `let u = null; u?.address.city`, "undefined"},
+		{"optional call", `let cb; cb?.()`, "undefined"},
+		{"optional index", `let list; list?.[0]`, "undefined"},
+		{"present chain", `let u = { a: { b: [7] } }; u?.a?.b?.[0]`, "7"},
+		{"precedence", `1 + 2 * 3 - 4 / 2`, "5"},
+	})
+}
@@ -0,0 +1,195 @@
+package evaluator
+
+import (
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// binder stores the value a destructuring pattern unpacks into one of its
+// targets: a variable being declared, or any assignable expression.
+type binder func(target ast.Expression, val object.Object) object.Object
+
+// destructure unpacks val into the targets of pattern, which may be nested
+// patterns themselves. A default is used in place of a missing value.
+func destructure(pattern ast.Expression, val object.Object, env *object.Environment, bind binder) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.ObjectPattern:
+		return destructureObject(pattern, val, env, bind)
+	case *ast.ArrayPattern:
+		return destructureArray(pattern, val, env, bind)
+	}
+	return bind(pattern, val)
+}
+
+func destructureObject(pattern *ast.ObjectPattern, val object.Object, env *object.Environment, bind binder) object.Object {
+	if isNullish(val) {
+		return newTypeError("Cannot destructure '%s' as it is %s.", val.Inspect(), val.Inspect())
+	}
+
+	used := map[string]bool{}
+	for _, prop := range pattern.Properties {
+		key, err := patternKey(prop, env)
+		if err != nil {
+			return err
+		}
+		used[key] = true
+
+		v := getProperty(val, key, val)
+		if isError(v) {
+			return v
+		}
+		if result := destructureElement(prop.Target, prop.Default, v, env, bind); isError(result) {
+			return result
+		}
+	}
+
+	if pattern.Rest == nil {
+		return val
+	}
+
+	// The rest holds a copy of the own properties not already unpacked
+	rest := &object.Hash{}
+	if hash, ok := val.(*object.Hash); ok {
+		for _, key := range hash.Keys() {
+			if used[key] {
+				continue
+			}
+			v := readProperty(hash.Pairs[key], hash)
+			if isError(v) {
+				return v
+			}
+			rest.Set(key, v)
+		}
+	}
+	if result := destructure(pattern.Rest, rest, env, bind); isError(result) {
+		return result
+	}
+	return val
+}
+
+// patternKey is the name of the property a pattern property unpacks.
+func patternKey(prop *ast.PatternProperty, env *object.Environment) (string, object.Object) {
+	if prop.Computed {
+		key := Eval(prop.Key, env)
+		if isError(key) {
+			return "", key
+		}
+		return propertyKey(key), nil
+	}
+
+	switch key := prop.Key.(type) {
+	case *ast.Identifier:
+		return key.Value, nil
+	case *ast.StringLiteral:
+		return key.Value, nil
+	}
+	return propertyKey(Eval(prop.Key, env)), nil
+}
+
+func destructureArray(pattern *ast.ArrayPattern, val object.Object, env *object.Environment, bind binder) object.Object {
+	elements, ok := iterableValues(val)
+	if !ok {
+		return newTypeError("%s is not iterable", val.Type())
+	}
+
+	for i, el := range pattern.Elements {
+		if el == nil {
+			continue
+		}
+		var v object.Object = UNDEFINED
+		if i < len(elements) {
+			v = elements[i]
+		}
+		if result := destructureElement(el.Target, el.Default, v, env, bind); isError(result) {
+			return result
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := &object.Array{Elements: []object.Object{}}
+		if len(pattern.Elements) < len(elements) {
+			rest.Elements = append(rest.Elements, elements[len(pattern.Elements):]...)
+		}
+		if result := destructure(pattern.Rest, rest, env, bind); isError(result) {
+			return result
+		}
+	}
+	return val
+}
+
+// destructureElement unpacks one value of a pattern into its target,
+// evaluating the default instead if the value is missing.
+func destructureElement(target, def ast.Expression, val object.Object, env *object.Environment, bind binder) object.Object {
+	if val == UNDEFINED && def != nil {
+		val = Eval(def, env)
+		if isError(val) {
+			return val
+		}
+		nameFunction(val, target)
+	}
+	return destructure(target, val, env, bind)
+}
+
+// nameFunction gives an anonymous function or class the name of the
+// variable it is first stored in.
+func nameFunction(val object.Object, target ast.Expression) {
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	switch fn := val.(type) {
+	case *object.Function:
+		if fn.Name == "" {
+			fn.Name = ident.Value
+		}
+	case *object.Class:
+		if fn.Name == "" {
+			fn.Name = ident.Value
+		}
+	}
+}
+
+// evalDestructuringDeclaration evaluates let { a, b }: T = value, checking
+// the value against the pattern's annotation before unpacking it.
+func evalDestructuringDeclaration(node *ast.LetStatement, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if t := patternType(node.Pattern); t != nil {
+		if err := checkType(val, t, env); err != nil {
+			return err
+		}
+	}
+
+	result := destructure(node.Pattern, val, env, func(target ast.Expression, v object.Object) object.Object {
+		return declareVariable(node.Token.Type, target.(*ast.Identifier).Value, v, env)
+	})
+	if isError(result) {
+		return result
+	}
+	return nil
+}
+
+// patternType is the annotation of a whole pattern, or nil.
+func patternType(pattern ast.Expression) ast.TypeNode {
+	switch pattern := pattern.(type) {
+	case *ast.ObjectPattern:
+		return pattern.Type
+	case *ast.ArrayPattern:
+		return pattern.Type
+	}
+	return nil
+}
+
+// assignTo stores val in an assignment target, which may be a pattern.
+func assignTo(target ast.Expression, val object.Object, env *object.Environment) object.Object {
+	return destructure(target, val, env, func(target ast.Expression, v object.Object) object.Object {
+		ref, err := evalReference(target, env)
+		if err != nil {
+			return err
+		}
+		return ref.set(v)
+	})
+}
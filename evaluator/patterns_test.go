@@ -0,0 +1,24 @@
+package evaluator
+
+import "testing"
+
+func TestDestructuring(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"object pattern", `const { a, b } = { a: 1, b: 2 }; a + b`, "3"},
+		{"renaming and defaults", `const { x: why = 5, z = 1 } = { z: 2 }; why + z`, "7"},
+		{"array pattern", `const [a, , c] = [1, 2, 3]; a + c`, "4"},
+		{"array defaults", `const [a = 10, b = 20] = [1]; a + b`, "21"},
+		{"nested", `const { p: [x, { y }] } = { p: [1, { y: 2 }] }; x + y`, "3"},
+		{"object rest", `const { a, ...rest } = { a: 1, b: 2, c: 3 }; rest`, "{b: 2, c: 3}"},
+		{"array rest", `const [first, ...others] = [1, 2, 3]; others`, "[2, 3]"},
+		{"parameters", `function f({ a, b = 2 }, [c]) { return a + b + c; } f({ a: 1 }, [3])`, "6"},
+		{"for-of head", `
+			let s = "";
+			for (const [k, v] of [["a", 1], ["b", 2]]) { s = s + k + v; }
+			s`, "a1b2"},
+		{"swap by assignment", `let a = 1; let b = 2; [a, b] = [b, a]; a * 10 + b`, "21"},
+		{"assignment to properties", `let o = {}; ({ x: o.y } = { x: 4 }); o.y`, "4"},
+		{"destructuring null", `const { a } = null;`,
+			"ERROR: TypeError: Cannot destructure 'null' as it is null."},
+	})
+}
@@ -0,0 +1,426 @@
+package evaluator
+
+import (
+	"ts-engine/object"
+)
+
+var promiseClass *object.Class
+
+// newPromiseClass creates the builtin Promise class. Instances are Hashes
+// whose Internal slot holds an *object.Promise.
+func newPromiseClass() *object.Class {
+	class := &object.Class{
+		Name:      "Promise",
+		Prototype: &object.Hash{},
+		Statics:   &object.Hash{},
+		Init:      initPromise,
+	}
+	class.Prototype.Set("constructor", class)
+	class.Prototype.Set("then", &object.Builtin{Method: promiseThen})
+	class.Prototype.Set("catch", &object.Builtin{Method: promiseCatch})
+	class.Prototype.Set("finally", &object.Builtin{Method: promiseFinally})
+
+	class.Statics.Set("resolve", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return promiseResolve(argument(args, 0))
+	}})
+	class.Statics.Set("reject", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		promise := newPromise()
+		rejectPromise(promise, thrownError(argument(args, 0)))
+		return promise
+	}})
+	class.Statics.Set("all", &object.Builtin{Fn: promiseAll})
+	class.Statics.Set("allSettled", &object.Builtin{Fn: promiseAllSettled})
+	class.Statics.Set("race", &object.Builtin{Fn: promiseRace})
+	class.Statics.Set("any", &object.Builtin{Fn: promiseAny})
+
+	return class
+}
+
+// initPromise implements new Promise(executor), calling the executor with
+// the functions that resolve and reject the new promise.
+func initPromise(this *object.Hash, args []object.Object) object.Object {
+	executor := argument(args, 0)
+	if !isCallable(executor) {
+		return newTypeError("Promise resolver %s is not a function", executor.Inspect())
+	}
+
+	this.Internal = &object.Promise{}
+	r := &resolvers{promise: this}
+	resolve, reject := r.functions()
+
+	if result := applyFunction(executor, []object.Object{resolve, reject}); isError(result) {
+		r.reject(result.(*object.Error))
+	}
+	return NULL
+}
+
+func newPromise() *object.Hash {
+	return &object.Hash{Proto: promiseClass.Prototype, Internal: &object.Promise{}}
+}
+
+func promiseState(obj object.Object) (*object.Promise, bool) {
+	hash, ok := obj.(*object.Hash)
+	if !ok {
+		return nil, false
+	}
+	state, ok := hash.Internal.(*object.Promise)
+	return state, ok
+}
+
+// promiseResolve returns val if it is a promise, or else a promise
+// resolved with it.
+func promiseResolve(val object.Object) *object.Hash {
+	if _, ok := promiseState(val); ok {
+		return val.(*object.Hash)
+	}
+	promise := newPromise()
+	resolvePromise(promise, val)
+	return promise
+}
+
+// resolvers settles a promise from the resolve and reject functions given
+// to its executor or to a thenable. Only the first call has an effect.
+type resolvers struct {
+	promise *object.Hash
+	done    bool
+}
+
+func (r *resolvers) resolve(val object.Object) {
+	if !r.done {
+		r.done = true
+		resolvePromise(r.promise, val)
+	}
+}
+
+func (r *resolvers) reject(err *object.Error) {
+	if !r.done {
+		r.done = true
+		rejectPromise(r.promise, err)
+	}
+}
+
+func (r *resolvers) functions() (*object.Builtin, *object.Builtin) {
+	resolve := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		r.resolve(argument(args, 0))
+		return UNDEFINED
+	}}
+	reject := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		r.reject(thrownError(argument(args, 0)))
+		return UNDEFINED
+	}}
+	return resolve, reject
+}
+
+// resolvePromise resolves promise with val. A thenable val, such as another
+// promise, is adopted: promise settles the same way once val does.
+func resolvePromise(promise *object.Hash, val object.Object) {
+	if val == promise {
+		rejectPromise(promise, newTypeError("Chaining cycle detected for promise"))
+		return
+	}
+
+	if obj, ok := val.(*object.Hash); ok {
+		then := getProperty(obj, "then", obj)
+		if err, ok := then.(*object.Error); ok {
+			rejectPromise(promise, err)
+			return
+		}
+		if isCallable(then) {
+			loop.enqueueMicrotask(func() object.Object {
+				r := &resolvers{promise: promise}
+				resolve, reject := r.functions()
+				if result := applyMethod(then, obj, []object.Object{resolve, reject}); isError(result) {
+					r.reject(result.(*object.Error))
+				}
+				return NULL
+			})
+			return
+		}
+	}
+
+	settlePromise(promise.Internal.(*object.Promise), object.PromiseFulfilled, val, nil)
+}
+
+func rejectPromise(promise *object.Hash, err *object.Error) {
+	settlePromise(promise.Internal.(*object.Promise), object.PromiseRejected, errorValue(err), err)
+}
+
+// settlePromise records the outcome of a pending promise and queues its
+// reactions.
+func settlePromise(state *object.Promise, result object.PromiseState, val object.Object, err *object.Error) {
+	if state.State != object.PromisePending {
+		return
+	}
+	state.State, state.Value, state.Err = result, val, err
+
+	if result == object.PromiseRejected && !state.Handled {
+		loop.rejected = append(loop.rejected, state)
+	}
+	for _, reaction := range state.Reactions {
+		loop.enqueueMicrotask(reaction)
+	}
+	state.Reactions = nil
+}
+
+// onSettled registers a reaction to run as a microtask once the promise
+// settles, or right away if it already has.
+func onSettled(state *object.Promise, reaction task) {
+	state.Handled = true
+	if state.State == object.PromisePending {
+		state.Reactions = append(state.Reactions, reaction)
+		return
+	}
+	loop.enqueueMicrotask(reaction)
+}
+
+// then registers handlers for the outcome of a promise and returns a
+// promise of their result. A missing handler passes the outcome on.
+func then(state *object.Promise, onFulfilled, onRejected object.Object) *object.Hash {
+	derived := newPromise()
+
+	onSettled(state, func() object.Object {
+		handler := onFulfilled
+		if state.State == object.PromiseRejected {
+			handler = onRejected
+		}
+
+		if !isCallable(handler) {
+			adoptOutcome(derived, state)
+			return NULL
+		}
+
+		result := applyFunction(handler, []object.Object{state.Value})
+		if err, ok := result.(*object.Error); ok {
+			rejectPromise(derived, err)
+		} else {
+			if result == nil {
+				result = UNDEFINED
+			}
+			resolvePromise(derived, result)
+		}
+		return NULL
+	})
+
+	return derived
+}
+
+// adoptOutcome settles promise the same way as the settled state.
+func adoptOutcome(promise *object.Hash, state *object.Promise) {
+	if state.State == object.PromiseRejected {
+		rejectPromise(promise, state.Err)
+	} else {
+		resolvePromise(promise, state.Value)
+	}
+}
+
+func promiseThen(this object.Object, args ...object.Object) object.Object {
+	state, ok := promiseState(this)
+	if !ok {
+		return newTypeError("Promise.prototype.then called on incompatible receiver %s", this.Inspect())
+	}
+	return then(state, argument(args, 0), argument(args, 1))
+}
+
+func promiseCatch(this object.Object, args ...object.Object) object.Object {
+	state, ok := promiseState(this)
+	if !ok {
+		return newTypeError("Promise.prototype.catch called on incompatible receiver %s", this.Inspect())
+	}
+	return then(state, NULL, argument(args, 0))
+}
+
+// promiseFinally calls its handler whatever the outcome and passes the
+// outcome on, after waiting for any promise the handler returns. Only an
+// exception from the handler replaces the outcome.
+func promiseFinally(this object.Object, args ...object.Object) object.Object {
+	state, ok := promiseState(this)
+	if !ok {
+		return newTypeError("Promise.prototype.finally called on incompatible receiver %s", this.Inspect())
+	}
+	onFinally := argument(args, 0)
+	if !isCallable(onFinally) {
+		return then(state, NULL, NULL)
+	}
+
+	derived := newPromise()
+	onSettled(state, func() object.Object {
+		result := applyFunction(onFinally, nil)
+		if err, ok := result.(*object.Error); ok {
+			rejectPromise(derived, err)
+			return NULL
+		}
+		if result == nil {
+			result = UNDEFINED
+		}
+
+		waited := promiseResolve(result).Internal.(*object.Promise)
+		onSettled(waited, func() object.Object {
+			if waited.State == object.PromiseRejected {
+				rejectPromise(derived, waited.Err)
+			} else {
+				adoptOutcome(derived, state)
+			}
+			return NULL
+		})
+		return NULL
+	})
+
+	return derived
+}
+
+// promiseInputs converts the array passed to Promise.all and friends to
+// promise states, resolving values that are not promises.
+func promiseInputs(name string, args []object.Object) ([]*object.Promise, *object.Error) {
+	arr, ok := argument(args, 0).(*object.Array)
+	if !ok {
+		return nil, newTypeError("%s is not iterable (cannot read property Symbol(Symbol.iterator)) in Promise.%s",
+			argument(args, 0).Inspect(), name)
+	}
+
+	states := make([]*object.Promise, len(arr.Elements))
+	for i, el := range arr.Elements {
+		states[i] = promiseResolve(el).Internal.(*object.Promise)
+	}
+	return states, nil
+}
+
+// promiseAll fulfills with the values of all the promises, in order, or
+// rejects as soon as one of them does.
+func promiseAll(args ...object.Object) object.Object {
+	states, err := promiseInputs("all", args)
+	if err != nil {
+		return err
+	}
+
+	result := newPromise()
+	values := make([]object.Object, len(states))
+	remaining := len(states)
+	if remaining == 0 {
+		resolvePromise(result, &object.Array{Elements: values})
+	}
+
+	for i, state := range states {
+		onSettled(state, func() object.Object {
+			if state.State == object.PromiseRejected {
+				rejectPromise(result, state.Err)
+				return NULL
+			}
+			values[i] = state.Value
+			if remaining--; remaining == 0 {
+				resolvePromise(result, &object.Array{Elements: values})
+			}
+			return NULL
+		})
+	}
+
+	return result
+}
+
+// promiseAllSettled waits for all the promises and fulfills with an
+// outcome object for each: {status, value} or {status, reason}.
+func promiseAllSettled(args ...object.Object) object.Object {
+	states, err := promiseInputs("allSettled", args)
+	if err != nil {
+		return err
+	}
+
+	result := newPromise()
+	outcomes := make([]object.Object, len(states))
+	remaining := len(states)
+	if remaining == 0 {
+		resolvePromise(result, &object.Array{Elements: outcomes})
+	}
+
+	for i, state := range states {
+		onSettled(state, func() object.Object {
+			outcome := &object.Hash{}
+			if state.State == object.PromiseRejected {
+				outcome.Set("status", &object.String{Value: "rejected"})
+				outcome.Set("reason", state.Value)
+			} else {
+				outcome.Set("status", &object.String{Value: "fulfilled"})
+				outcome.Set("value", state.Value)
+			}
+			outcomes[i] = outcome
+			if remaining--; remaining == 0 {
+				resolvePromise(result, &object.Array{Elements: outcomes})
+			}
+			return NULL
+		})
+	}
+
+	return result
+}
+
+// promiseRace settles the same way as the first promise to settle.
+func promiseRace(args ...object.Object) object.Object {
+	states, err := promiseInputs("race", args)
+	if err != nil {
+		return err
+	}
+
+	result := newPromise()
+	for _, state := range states {
+		onSettled(state, func() object.Object {
+			adoptOutcome(result, state)
+			return NULL
+		})
+	}
+
+	return result
+}
+
+// promiseAny fulfills with the first promise to fulfill, or rejects with
+// an AggregateError holding every reason if they all reject.
+func promiseAny(args ...object.Object) object.Object {
+	states, err := promiseInputs("any", args)
+	if err != nil {
+		return err
+	}
+
+	result := newPromise()
+	reasons := make([]object.Object, len(states))
+	remaining := len(states)
+	if remaining == 0 {
+		rejectPromise(result, aggregateError(reasons))
+	}
+
+	for i, state := range states {
+		onSettled(state, func() object.Object {
+			if state.State == object.PromiseFulfilled {
+				resolvePromise(result, state.Value)
+				return NULL
+			}
+			reasons[i] = state.Value
+			if remaining--; remaining == 0 {
+				rejectPromise(result, aggregateError(reasons))
+			}
+			return NULL
+		})
+	}
+
+	return result
+}
+
+func aggregateError(reasons []object.Object) *object.Error {
+	err := &object.Error{Name: "AggregateError", Message: "All promises were rejected"}
+	errorValue(err).(*object.Hash).Set("errors", &object.Array{Elements: reasons})
+	return err
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	}
+	return false
+}
+
+// argument returns the i'th argument of a builtin, or undefined if it
+// was not passed.
+func argument(args []object.Object, i int) object.Object {
+	if i < len(args) {
+		return args[i]
+	}
+	return UNDEFINED
+}
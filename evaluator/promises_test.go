@@ -0,0 +1,81 @@
+package evaluator
+
+import (
+	"testing"
+
+	"ts-engine/lexer"
+	"ts-engine/object"
+	"ts-engine/parser"
+)
+
+// testRun runs a .js program and its event loop to completion, returning
+// the final value of its result variable.
+func testRun(t *testing.T, src string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(src), false)
+	program := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Fatalf("parse error: %s\n%s", err, src)
+	}
+	env := object.NewEnvironment()
+	if result := Run(program, env); isError(result) {
+		return result
+	}
+	result, _ := env.Get("result")
+	return result
+}
+
+func TestPromises(t *testing.T) {
+	tests := []evalTest{
+		{"then", `let result; Promise.resolve(1).then(v => { result = v + 1; });`, "2"},
+		{"reactions are microtasks", `
+			let result = "a";
+			Promise.resolve().then(() => { result = result + "c"; });
+			result = result + "b";`, "abc"},
+		{"chaining", `
+			let result;
+			new Promise(resolve => resolve(2))
+				.then(v => v * 3)
+				.then(v => { result = v; });`, "6"},
+		{"catch", `let result; Promise.reject(new Error("no")).catch(e => { result = e.message; });`, "no"},
+		{"finally", `let result = ""; Promise.resolve(1).finally(() => { result = "done"; });`, "done"},
+		{"all", `let result; Promise.all([1, Promise.resolve(2)]).then(v => { result = v; });`, "[1, 2]"},
+		{"race", `
+			let result;
+			Promise.race([new Promise(() => {}), Promise.resolve("fast")]).then(v => { result = v; });`, "fast"},
+		{"any rejects with AggregateError", `
+			let result;
+			Promise.any([Promise.reject(1)]).catch(e => { result = e.name; });`, "AggregateError"},
+		{"allSettled", `
+			let result;
+			Promise.allSettled([Promise.reject(1)]).then(r => { result = r[0].status + ":" + r[0].reason; });`, "rejected:1"},
+		{"async function", `
+			let result;
+			async function f() { return 5; }
+			f().then(v => { result = v; });`, "5"},
+		{"await suspends", `
+			let result = "";
+			async function f() {
+				result = result + "1";
+				await 0;
+				result = result + "3";
+			}
+			f();
+			result = result + "2";`, "123"},
+		{"await a rejection throws", `
+			let result;
+			async function f() {
+				try { await Promise.reject(new Error("bad")); } catch (e) { result = e.message; }
+			}
+			f();`, "bad"},
+		{"top-level await", `let result = await Promise.resolve(4);`, "4"},
+		{"unhandled rejection", `let result; Promise.reject(new TypeError("lost"));`, "ERROR: TypeError: lost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testRun(t, tt.src).Inspect(); got != tt.want {
+				t.Errorf("got %s, want %s\n%s", got, tt.want, tt.src)
+			}
+		})
+	}
+}
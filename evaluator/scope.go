@@ -0,0 +1,177 @@
+package evaluator
+
+import (
+	"ts-engine/ast"
+	"ts-engine/object"
+	"ts-engine/token"
+)
+
+// marker is a value the evaluator uses internally, which programs never
+// see. Markers are told apart from undefined, and from each other, by
+// identity, which needs them not to be empty structs.
+type marker struct{ name string }
+
+func (m *marker) Type() object.ObjectType { return object.UNDEFINED_OBJ }
+func (m *marker) Inspect() string         { return "undefined" }
+
+// uninitialized is the value of a let, const or class binding between the
+// start of its block and its declaration, the temporal dead zone, where
+// using it is an error.
+var uninitialized = &marker{"uninitialized"}
+
+// hoistDeclarations declares the names a block declares when it is
+// entered. Function declarations are defined right away, so that they can
+// be called from further up; let, const and class names are reserved,
+// shadowing outer variables of the same name, but cannot be used until
+// their declaration runs.
+//
+// A let or const declaring a name twice in the block is an error, found
+// here before any of the block runs.
+func hoistDeclarations(stmts []ast.Statement, env *object.Environment) *object.Error {
+	declared := map[string]bool{}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement, *ast.DeclarationList:
+			for _, decl := range declarations(stmt) {
+				if decl.Token.Type == token.VAR {
+					continue
+				}
+				for _, name := range declaredNames(decl) {
+					if declared[name] {
+						err := newSyntaxError("cannot redeclare block-scoped variable '%s'", name)
+						err.Pos, err.End = decl.Pos(), decl.End()
+						return err
+					}
+					declared[name] = true
+					env.Set(name, uninitialized)
+				}
+			}
+		case *ast.ExpressionStatement:
+			if lit := functionDeclaration(stmt); lit != nil {
+				Eval(lit, env)
+			} else if lit, ok := stmt.Expression.(*ast.ClassLiteral); ok && lit.Name != nil {
+				env.Set(lit.Name.Value, uninitialized)
+			}
+		}
+	}
+	return nil
+}
+
+// functionDeclaration returns the function a statement declares, or nil if
+// it is not a function declaration. A named function expression such as
+// (function f() {}) is not a declaration.
+func functionDeclaration(stmt ast.Statement) *ast.FunctionLiteral {
+	exprStmt, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	lit, ok := exprStmt.Expression.(*ast.FunctionLiteral)
+	if !ok || lit.Name == "" {
+		return nil
+	}
+	switch exprStmt.Token.Type {
+	case token.FUNCTION, token.ASYNC:
+		return lit
+	}
+	return nil
+}
+
+// declaredNames returns the names a let, const or var statement declares.
+func declaredNames(stmt *ast.LetStatement) []string {
+	return bindingNames(stmt.Name, stmt.Pattern)
+}
+
+// declarations returns the declarations of a let, const or var statement
+// as statements, whether it declares one variable or several.
+// Other statements declare nothing.
+func declarations(stmt ast.Statement) []*ast.LetStatement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return []*ast.LetStatement{stmt}
+	case *ast.DeclarationList:
+		return stmt.Declarations
+	}
+	return nil
+}
+
+// bindingNames returns the names bound by a declaration of either a single
+// name or a destructuring pattern.
+func bindingNames(name *ast.Identifier, pattern ast.Expression) []string {
+	if pattern == nil {
+		return []string{name.Value}
+	}
+	var names []string
+	for _, ident := range ast.PatternNames(pattern) {
+		names = append(names, ident.Value)
+	}
+	return names
+}
+
+// hoistVars declares the var variables of a function body or program as
+// undefined in its scope, env, wherever they are declared in it: var is
+// scoped to the function, not to the block. Parameters of the same name
+// keep their values.
+func hoistVars(stmts []ast.Statement, env *object.Environment) {
+	for _, name := range ast.VarNames(stmts) {
+		if _, ok := env.GetCurrent(name.Value); !ok {
+			env.Set(name.Value, UNDEFINED)
+		}
+	}
+}
+
+// declareVariable binds name to val in env for a let, const or var
+// declaration. let and const may only declare a name once per block, and
+// var assigns the variable hoisted to the function scope.
+func declareVariable(kind token.TokenType, name string, val object.Object, env *object.Environment) object.Object {
+	if kind == token.VAR {
+		if !env.Assign(name, val) {
+			env.Set(name, val)
+		}
+		return val
+	}
+
+	if current, ok := env.GetCurrent(name); ok && current != uninitialized {
+		return newSyntaxError("cannot redeclare block-scoped variable '%s'", name)
+	}
+	if kind == token.CONST {
+		return env.SetConst(name, val)
+	}
+	return env.Set(name, val)
+}
+
+// lookupVariable reads a variable, failing if it is in its temporal dead
+// zone or not declared at all.
+func lookupVariable(name string, env *object.Environment) object.Object {
+	if val, ok := env.Get(name); ok {
+		if val == uninitialized {
+			return newReferenceError("Cannot access '%s' before initialization", name)
+		}
+		return val
+	}
+	if builtin, ok := builtins[name]; ok {
+		return builtin
+	}
+	return newReferenceError("identifier not found: %s", name)
+}
+
+// assignVariable assigns a declared variable in the scope that declares
+// it.
+func assignVariable(name string, val object.Object, env *object.Environment) object.Object {
+	current, ok := env.Get(name)
+	switch {
+	case !ok:
+		return newReferenceError("identifier not found: %s", name)
+	case current == uninitialized:
+		return newReferenceError("Cannot access '%s' before initialization", name)
+	case env.IsConst(name):
+		return newTypeError("Assignment to constant variable.")
+	}
+	// Annotated variables keep to their type
+	if t, declEnv, ok := env.Annotation(name); ok {
+		if err := checkType(val, t, declEnv); err != nil {
+			return err
+		}
+	}
+	env.Assign(name, val)
+	return val
+}
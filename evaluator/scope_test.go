@@ -0,0 +1,36 @@
+package evaluator
+
+import "testing"
+
+func TestBlockScoping(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"block shadows", `let x = 1; { let x = 2; } x`, "1"},
+		{"if block", `let x = 1; if (true) { let x = 2; } x`, "1"},
+		{"var is function scoped", `function f() { if (true) { var v = 3; } return v; } f()`, "3"},
+		{"assignment updates the declaring scope", `let n = 0; function inc() { n = n + 1; } inc(); inc(); n`, "2"},
+		{"const reassignment", `const c = 1; c = 2;`,
+			"ERROR: TypeError: Assignment to constant variable."},
+		{"const compound assignment", `const c = 1; c += 1;`,
+			"ERROR: TypeError: Assignment to constant variable."},
+		{"temporal dead zone", `{ x; let x = 1; }`,
+			"ERROR: ReferenceError: Cannot access 'x' before initialization"},
+		{"TDZ in a closure", `function f() { return y; } let r; try { f(); } catch (e) { r = e.name; } let y = 1; r`,
+			"ReferenceError"},
+		{"function hoisting", `let r = hoisted(); function hoisted() { return "up"; } r`, "up"},
+		{"var hoisting", `let r = typeof v; var v = 1; r`, "undefined"},
+		{"several declarators", `let a = 1, b = a + 1, c; const d = 4, e = 5; [a, b, c, d, e]`,
+			"[1, 2, undefined, 4, 5]"},
+		{"declarators are hoisted together", `{ let r = typeof b; let a = 1, b = 2; }`,
+			"ERROR: ReferenceError: Cannot access 'b' before initialization"},
+		{"redeclaration", `let a = 1; let a = 2;`,
+			"ERROR: SyntaxError: cannot redeclare block-scoped variable 'a'"},
+		{"redeclaration is found before the block runs", `
+			let log = [];
+			function f() { log.push(1); const a = 1; let a = 2; }
+			try { f(); } catch (e) { log.push(e.name); }
+			log`, "[SyntaxError]"},
+		{"redeclaration in a destructuring pattern", `{ let [a, b] = [1, 2]; const { b: a } = { b: 3 }; }`,
+			"ERROR: SyntaxError: cannot redeclare block-scoped variable 'a'"},
+		{"class is block scoped", `{ class K {} } typeof K`, "undefined"},
+	})
+}
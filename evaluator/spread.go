@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// iterableValues returns the values spreading or destructuring obj yields:
+// the elements of an array or the characters of a string. It reports false
+// if obj is not iterable.
+func iterableValues(obj object.Object) ([]object.Object, bool) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return append([]object.Object{}, obj.Elements...), true
+	case *object.String:
+		var chars []object.Object
+		for _, ch := range obj.Value {
+			chars = append(chars, &object.String{Value: string(ch)})
+		}
+		return chars, true
+	}
+	return nil, false
+}
+
+// spreadValues evaluates ...value in a call's arguments or an array
+// literal.
+func spreadValues(node *ast.SpreadElement, env *object.Environment) ([]object.Object, object.Object) {
+	val := Eval(node.Argument, env)
+	if isError(val) {
+		return nil, val
+	}
+	values, ok := iterableValues(val)
+	if !ok {
+		return nil, newTypeError("%s is not iterable", val.Type())
+	}
+	return values, nil
+}
+
+// spreadProperties copies the own properties of the value of ...value in
+// an object literal to hash. Spreading null copies nothing, and arrays and
+// strings contribute their indexes.
+func spreadProperties(hash *object.Hash, node *ast.SpreadElement, env *object.Environment) object.Object {
+	val := Eval(node.Argument, env)
+	if isError(val) {
+		return val
+	}
+
+	switch val := val.(type) {
+	case *object.Hash:
+		for _, key := range val.Keys() {
+			v := readProperty(val.Pairs[key], val)
+			if isError(v) {
+				return v
+			}
+			hash.Set(key, v)
+		}
+	case *object.Array, *object.String:
+		values, _ := iterableValues(val)
+		for i, v := range values {
+			hash.Set(propertyKey(&object.Number{Value: float64(i)}), v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package evaluator
+
+import "testing"
+
+func TestSpreadAndRest(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"rest parameter", `function f(a, ...rest) { return rest; } f(1, 2, 3)`, "[2, 3]"},
+		{"empty rest", `function f(...rest) { return rest; } f()`, "[]"},
+		{"spread in a call", `function add(a, b, c) { return a + b + c; } let xs = [1, 2, 3]; add(...xs)`, "6"},
+		{"spread a string", `function f(...cs) { return cs; } f(..."ab")`, "[a, b]"},
+		{"array spread", `let xs = [2, 3]; [1, ...xs, 4]`, "[1, 2, 3, 4]"},
+		{"object spread", `let d = { host: "x", port: 1 }; let o = { ...d, port: 80 }; o.host + o.port`, "x80"},
+		{"later properties win", `let o = { a: 1, ...{ a: 2 } }; o.a`, "2"},
+		{"missing arguments", `function f(a, b) { return b; } f(1)`, "undefined"},
+		{"extra arguments", `function f(a) { return a; } f(1, 2, 3)`, "1"},
+		{"spreading a non-iterable", `function f() {} f(...5);`,
+			"ERROR: TypeError: NUMBER is not iterable"},
+	})
+}
@@ -0,0 +1,71 @@
+package evaluator
+
+import (
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// evalSwitchStatement runs a switch from the first case whose value is
+// strictly equal to the discriminant, or from the default clause if none
+// is, through the clauses after it until a break. The clauses share one
+// block scope.
+func evalSwitchStatement(node *ast.SwitchStatement, env *object.Environment) object.Object {
+	discriminant := Eval(node.Discriminant, env)
+	if isError(discriminant) {
+		return discriminant
+	}
+
+	switchEnv := object.NewEnclosedEnvironment(env)
+	var stmts []ast.Statement
+	for _, clause := range node.Cases {
+		stmts = append(stmts, clause.Consequent...)
+	}
+	declareTypes(stmts, switchEnv)
+	if err := hoistDeclarations(stmts, switchEnv); err != nil {
+		return err
+	}
+
+	start := -1
+	for i, clause := range node.Cases {
+		if clause.Test == nil {
+			continue
+		}
+		val := Eval(clause.Test, switchEnv)
+		if isError(val) {
+			return val
+		}
+		if strictEquals(discriminant, val) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i, clause := range node.Cases {
+			if clause.Test == nil {
+				start = i
+			}
+		}
+		if start < 0 {
+			return NULL
+		}
+	}
+
+	for _, clause := range node.Cases[start:] {
+		for _, stmt := range clause.Consequent {
+			// Defined when the switch was entered
+			if functionDeclaration(stmt) != nil {
+				continue
+			}
+			switch result := Eval(stmt, switchEnv).(type) {
+			case *object.Break:
+				if result.Label == "" {
+					return NULL
+				}
+				return result
+			case *object.Continue, *object.ReturnValue, *object.Error:
+				return result
+			}
+		}
+	}
+	return NULL
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import "testing"
+
+func TestSwitch(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"matching case", `
+			let r;
+			switch (2) { case 1: r = "one"; break; case 2: r = "two"; break; }
+			r`, "two"},
+		{"strict matching", `
+			let r = "none";
+			switch ("1") { case 1: r = "number"; break; }
+			r`, "none"},
+		{"fallthrough", `
+			let s = "";
+			switch (1) { case 1: s = s + "a"; case 2: s = s + "b"; break; case 3: s = s + "c"; }
+			s`, "ab"},
+		{"default anywhere", `
+			let s = "";
+			switch (9) { default: s = s + "d"; case 1: s = s + "1"; break; case 2: s = s + "2"; }
+			s`, "d1"},
+		{"no match and no default", `let s = "x"; switch (0) { case 1: s = "1"; } s`, "x"},
+		{"return from a case", `
+			function name(n) {
+				switch (n) {
+					case 0: return "zero";
+					default: return "many";
+				}
+			}
+			name(0) + name(5)`, "zeromany"},
+		{"shared block scope", `
+			let r;
+			switch (1) { case 1: let v = "in"; case 2: r = v; }
+			r`, "in"},
+		{"break inside a loop", `
+			let s = "";
+			for (const x of [1, 2, 3]) {
+				switch (x) { case 2: continue; default: s = s + x; }
+			}
+			s`, "13"},
+	})
+}
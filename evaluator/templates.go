@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+func evalTemplateLiteral(node *ast.TemplateLiteral, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for i, quasi := range node.Quasis {
+		out.WriteString(quasi.Cooked)
+		if i == len(node.Expressions) {
+			break
+		}
+
+		val := Eval(node.Expressions[i], env)
+		if isError(val) {
+			return val
+		}
+		str := toJSString(val)
+		if isError(str) {
+			return str
+		}
+		out.WriteString(str.(*object.String).Value)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// evalTaggedTemplate calls the tag with an array of the cooked strings,
+// whose 'raw' property holds the strings as written, followed by the
+// values of the substitutions.
+func evalTaggedTemplate(node *ast.TaggedTemplateExpression, env *object.Environment) object.Object {
+	this, tag := evalCallee(node.Tag, env)
+	if isError(tag) {
+		return tag
+	}
+
+	values := evalExpressions(node.Template.Expressions, env)
+	if len(values) == 1 && isError(values[0]) {
+		return values[0]
+	}
+
+	cooked := &object.Array{}
+	raw := &object.Array{}
+	for _, quasi := range node.Template.Quasis {
+		if quasi.Invalid {
+			cooked.Elements = append(cooked.Elements, UNDEFINED)
+		} else {
+			cooked.Elements = append(cooked.Elements, &object.String{Value: quasi.Cooked})
+		}
+		raw.Elements = append(raw.Elements, &object.String{Value: quasi.Raw})
+	}
+	cooked.Props = &object.Hash{Proto: arrayClass.Prototype}
+	cooked.Props.Set("raw", raw)
+
+	args := append([]object.Object{cooked}, values...)
+	return callWithFrame(tag, this, args, node.Pos())
+}
+
+// stringRaw implements String.raw, which joins the raw strings of a
+// template with the substitution values: String.raw`C:\dir\${name}`
+func stringRaw(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newTypeError("String.raw requires a template strings array")
+	}
+	strs, ok := getProperty(args[0], "raw", args[0]).(*object.Array)
+	if !ok {
+		return newTypeError("String.raw requires a template strings array")
+	}
+
+	var out strings.Builder
+	for i, s := range strs.Elements {
+		parts := []object.Object{s}
+		if i+1 < len(strs.Elements) && i+1 < len(args) {
+			parts = append(parts, args[i+1])
+		}
+		for _, part := range parts {
+			str := toJSString(part)
+			if isError(str) {
+				return str
+			}
+			out.WriteString(str.(*object.String).Value)
+		}
+	}
+
+	return &object.String{Value: out.String()}
+}
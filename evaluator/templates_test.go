@@ -0,0 +1,22 @@
+package evaluator
+
+import "testing"
+
+func TestTemplateLiterals(t *testing.T) {
+	runEvalTests(t, []evalTest{
+		{"plain", "`hello`", "hello"},
+		{"interpolation", "let name = \"Ann\"; `Hi, ${name}!`", "Hi, Ann!"},
+		{"expressions", "`${1 + 2} and ${true}`", "3 and true"},
+		{"nested template", "let x = 2; `a${`b${x}`}c`", "ab2c"},
+		{"braces inside a substitution", "`${{ a: 1 }.a}`", "1"},
+		{"multiple lines", "`a\nb`", "a\nb"},
+		{"escapes", "`tab\\there \\u0041 \\x42 \\`q\\``", "tab\there A B `q`"},
+		{"string escapes match", "`\\u0041\\x42\\u{43}` === \"\\u0041\\x42\\u{43}\"", "true"},
+		{"tagged", `
+			function tag(strings, a, b) { return strings[0] + "|" + strings[1] + "|" + strings[2] + ":" + a + "," + b; }
+			let x = 1;
+			tag` + "`a${x}b${x + 1}`", "a|b|:1,2"},
+		{"raw strings", "function raw(s) { return s.raw[0]; } raw`\\n`", "\\n"},
+		{"String.raw", "String.raw`C:\\dir\\file${1 + 1}`", "C:\\dir\\file2"},
+	})
+}
@@ -0,0 +1,266 @@
+package evaluator
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"time"
+	"ts-engine/object"
+)
+
+// Clock is the event loop's source of time. The default follows the wall
+// clock; SetClock installs another, such as a VirtualClock in tests.
+type Clock interface {
+	Now() time.Time
+
+	// Sleep blocks until deadline or until wake is signalled, whichever
+	// comes first. A zero deadline means there is no timer to wait for.
+	Sleep(deadline time.Time, wake <-chan struct{})
+}
+
+// SetClock sets the clock timers are scheduled by. It must be called
+// before the program starts.
+func SetClock(c Clock) {
+	loop.clock = c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(deadline time.Time, wake <-chan struct{}) {
+	if deadline.IsZero() {
+		<-wake
+		return
+	}
+
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-wake:
+	}
+}
+
+// VirtualClock is a Clock that only moves when told to. When the event
+// loop has nothing to do but wait for a timer, the clock jumps straight to
+// the timer's deadline, so programs run without sleeping and timers fire
+// in a deterministic order.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *VirtualClock) Sleep(deadline time.Time, wake <-chan struct{}) {
+	if deadline.IsZero() {
+		// Only host operations are pending, which take real time
+		<-wake
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if deadline.After(c.now) {
+		c.now = deadline
+	}
+}
+
+// timer is a callback scheduled by setTimeout, setInterval or
+// setImmediate.
+type timer struct {
+	id       int
+	seq      int // scheduling order, for timers due at the same time
+	deadline time.Time
+	interval time.Duration // period of setInterval timers
+	repeat   bool
+	callback object.Object
+	args     []object.Object
+	index    int // position in the heap, or -1 when not in it
+}
+
+// timerHeap orders timers by deadline, then by when they were scheduled.
+type timerHeap []*timer
+
+func (h timerHeap) Len() int { return len(h) }
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].deadline.Equal(h[j].deadline) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].deadline.Before(h[j].deadline)
+}
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *timerHeap) Push(x any) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	t.index = -1
+	return t
+}
+
+// schedule adds a timer due after delay and returns its id.
+func (l *eventLoop) schedule(t *timer, delay time.Duration) int {
+	l.lastID++
+	t.id = l.lastID
+	l.active[t.id] = t
+	l.enqueueTimer(t, delay)
+	return t.id
+}
+
+func (l *eventLoop) enqueueTimer(t *timer, delay time.Duration) {
+	l.lastSeq++
+	t.seq = l.lastSeq
+	t.deadline = l.clock.Now().Add(delay)
+	heap.Push(&l.timers, t)
+}
+
+func (l *eventLoop) scheduleImmediate(t *timer) int {
+	l.lastID++
+	t.id = l.lastID
+	t.index = -1
+	l.active[t.id] = t
+	l.immediates = append(l.immediates, t)
+	return t.id
+}
+
+// cancel removes a timer or immediate. Unknown ids are ignored.
+func (l *eventLoop) cancel(id int) {
+	t, ok := l.active[id]
+	if !ok {
+		return
+	}
+	delete(l.active, id)
+	if t.index >= 0 {
+		heap.Remove(&l.timers, t.index)
+	}
+}
+
+// runTimer calls a timer's callback. An interval is scheduled again
+// afterwards, unless the callback cleared it.
+func (l *eventLoop) runTimer(t *timer) object.Object {
+	if l.active[t.id] != t {
+		return NULL // cleared while waiting to run
+	}
+	if !t.repeat {
+		delete(l.active, t.id)
+	}
+
+	result := applyFunction(t.callback, t.args)
+	if isError(result) {
+		return result
+	}
+
+	if t.repeat && l.active[t.id] == t {
+		l.enqueueTimer(t, t.interval)
+	}
+	return NULL
+}
+
+// timerDelay converts the delay argument of setTimeout and setInterval to
+// a duration. As in Node, delays that are missing, below 1ms or too large
+// for a 32-bit integer become 1ms.
+func timerDelay(args []object.Object) time.Duration {
+	ms := 1.0
+	if n, ok := argument(args, 1).(*object.Number); ok && n.Value >= 1 && n.Value <= math.MaxInt32 {
+		ms = math.Trunc(n.Value)
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func timerCallback(name string, args []object.Object) (object.Object, *object.Error) {
+	callback := argument(args, 0)
+	if !isCallable(callback) {
+		return nil, newTypeError("The \"callback\" argument of %s must be of type function. Received %s",
+			name, callback.Inspect())
+	}
+	return callback, nil
+}
+
+// newTimerBuiltin creates setTimeout or setInterval, which take a callback,
+// a delay in milliseconds and arguments for the callback, and return an id
+// for clearTimeout or clearInterval.
+func newTimerBuiltin(name string, repeat bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			callback, err := timerCallback(name, args)
+			if err != nil {
+				return err
+			}
+
+			delay := timerDelay(args)
+			t := &timer{callback: callback, repeat: repeat, interval: delay}
+			if len(args) > 2 {
+				t.args = args[2:]
+			}
+			return &object.Number{Value: float64(loop.schedule(t, delay))}
+		},
+	}
+}
+
+// setImmediate runs a callback once the tasks that are ready have run,
+// without waiting for any time to pass.
+func setImmediate(args ...object.Object) object.Object {
+	callback, err := timerCallback("setImmediate", args)
+	if err != nil {
+		return err
+	}
+
+	t := &timer{callback: callback}
+	if len(args) > 1 {
+		t.args = args[1:]
+	}
+	return &object.Number{Value: float64(loop.scheduleImmediate(t))}
+}
+
+// clearTimer implements clearTimeout, clearInterval and clearImmediate,
+// which, as in Node, accept each other's ids.
+func clearTimer(args ...object.Object) object.Object {
+	if n, ok := argument(args, 0).(*object.Number); ok {
+		loop.cancel(int(n.Value))
+	}
+	return UNDEFINED
+}
+
+// queueMicrotask runs a callback after the current code, before any
+// other task. An exception it throws is uncaught.
+func queueMicrotask(args ...object.Object) object.Object {
+	callback, err := timerCallback("queueMicrotask", args)
+	if err != nil {
+		return err
+	}
+
+	loop.enqueueMicrotask(func() object.Object {
+		result := applyFunction(callback, nil)
+		if isError(result) {
+			return result
+		}
+		return NULL
+	})
+	return UNDEFINED
+}
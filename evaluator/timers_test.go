@@ -0,0 +1,161 @@
+package evaluator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ts-engine/lexer"
+	"ts-engine/object"
+	"ts-engine/parser"
+)
+
+// useVirtualClock installs a VirtualClock for the duration of a test.
+func useVirtualClock(t *testing.T) *VirtualClock {
+	clock := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	t.Cleanup(func() { SetClock(realClock{}) })
+	return clock
+}
+
+// evalScript evaluates a .js program without running the event loop.
+func evalScript(t *testing.T, src string) *object.Environment {
+	t.Helper()
+	p := parser.New(lexer.New(src), false)
+	program := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Fatalf("parse error: %s", err)
+	}
+	env := object.NewEnvironment()
+	if result := Eval(program, env); isError(result) {
+		t.Fatalf("uncaught exception: %s", result.Inspect())
+	}
+	return env
+}
+
+// logged returns the strings the program has pushed onto its order array.
+func logged(t *testing.T, env *object.Environment) []string {
+	t.Helper()
+	val, _ := env.Get("order")
+	arr, ok := val.(*object.Array)
+	if !ok {
+		t.Fatalf("order is %v, not an array", val)
+	}
+	entries := []string{}
+	for _, el := range arr.Elements {
+		entries = append(entries, el.Inspect())
+	}
+	return entries
+}
+
+// runDue runs the event loop for as long as there is work that does not
+// need the clock to move: microtasks, immediates and timers that are due.
+func runDue(t *testing.T) {
+	t.Helper()
+	for len(loop.microtasks) > 0 || len(loop.immediates) > 0 ||
+		len(loop.timers) > 0 && !loop.timers[0].deadline.After(loop.clock.Now()) {
+		if _, result := loop.runOnce(); isError(result) {
+			t.Fatalf("uncaught exception: %s", result.Inspect())
+		}
+	}
+}
+
+// runAll runs the event loop until no work is left, the virtual clock
+// jumping to each timer in turn.
+func runAll(t *testing.T) {
+	t.Helper()
+	for {
+		more, result := loop.runOnce()
+		if isError(result) {
+			t.Fatalf("uncaught exception: %s", result.Inspect())
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func TestTimerOrdering(t *testing.T) {
+	useVirtualClock(t)
+	env := evalScript(t, `
+		let order = [];
+		setTimeout(() => order.push("timeout 10"), 10);
+		setTimeout(() => {
+			order.push("timeout 0");
+			Promise.resolve().then(() => order.push("microtask from timeout"));
+		}, 0);
+		let n = 0;
+		let id = setInterval(() => {
+			n++;
+			order.push("interval " + n);
+			if (n == 3) {
+				clearInterval(id);
+			}
+		}, 4);
+		setImmediate(() => {
+			order.push("immediate");
+			queueMicrotask(() => order.push("microtask from immediate"));
+		});
+		queueMicrotask(() => order.push("microtask"));
+		Promise.resolve().then(() => order.push("promise"));
+		order.push("sync");
+	`)
+	runAll(t)
+
+	want := []string{
+		"sync",
+		"microtask",
+		"promise",
+		// A delay of 0 is 1ms, so the immediate runs first
+		"immediate",
+		"microtask from immediate",
+		"timeout 0",
+		"microtask from timeout",
+		"interval 1", // 4ms
+		"interval 2", // 8ms
+		"timeout 10",
+		"interval 3", // 12ms
+	}
+	if got := logged(t, env); !reflect.DeepEqual(got, want) {
+		t.Errorf("callbacks ran in the wrong order\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestVirtualClockAdvance(t *testing.T) {
+	clock := useVirtualClock(t)
+	env := evalScript(t, `
+		let order = [];
+		setTimeout(() => order.push("timeout 100"), 100);
+		setTimeout(() => order.push("timeout 50"), 50);
+		let ticks = 0;
+		let id = setInterval(() => {
+			ticks++;
+			order.push("tick " + ticks);
+			if (ticks == 2) {
+				clearInterval(id);
+			}
+		}, 30);
+		queueMicrotask(() => order.push("microtask"));
+	`)
+
+	steps := []struct {
+		advance time.Duration
+		want    []string
+	}{
+		{0, []string{"microtask"}},
+		{29 * time.Millisecond, []string{"microtask"}},
+		{time.Millisecond, []string{"microtask", "tick 1"}},
+		{30 * time.Millisecond, []string{"microtask", "tick 1", "timeout 50", "tick 2"}},
+		{40 * time.Millisecond, []string{"microtask", "tick 1", "timeout 50", "tick 2", "timeout 100"}},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		runDue(t)
+		if got := logged(t, env); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("at %s: got %q, want %q", clock.Now().Format("15:04:05.000"), got, step.want)
+		}
+	}
+	if len(loop.timers) != 0 || len(loop.active) != 0 {
+		t.Errorf("timers left after the last one fired: %d scheduled", len(loop.active))
+	}
+}
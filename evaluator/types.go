@@ -0,0 +1,353 @@
+package evaluator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/object"
+)
+
+// strictTypes is set while running a .ts program. Only then are values
+// checked against interfaces and type aliases.
+var strictTypes bool
+
+// declareTypes declares the interfaces, type aliases and enums of a block,
+// which may be used as types anywhere in it, even before their
+// declarations.
+func declareTypes(stmts []ast.Statement, env *object.Environment) {
+	for _, stmt := range stmts {
+		switch decl := stmt.(type) {
+		case *ast.InterfaceDeclaration:
+			env.SetType(decl.Name.Value, decl)
+		case *ast.TypeAliasDeclaration:
+			env.SetType(decl.Name.Value, decl)
+		case *ast.EnumDeclaration:
+			env.SetType(decl.Name.Value, decl)
+		}
+	}
+}
+
+// checkType checks that obj is a value of type t, resolving interfaces and
+// type aliases in env. Types the runtime cannot tell apart, such as class
+// names and types from modules, accept any value.
+func checkType(obj object.Object, t ast.TypeNode, env *object.Environment) *object.Error {
+	c := &valueChecker{visiting: map[visit]bool{}, bound: map[ast.Statement]bool{}}
+	return c.check(obj, t, env, "")
+}
+
+// valueChecker checks a value against a type. Errors name the path of the
+// offending property within the value, such as address.zip or tags[2].
+type valueChecker struct {
+	// visiting holds the values being checked against interfaces and type
+	// aliases further up, so that cyclic values terminate.
+	visiting map[visit]bool
+
+	// bound holds the type aliases standing for the type parameters of
+	// generic types being checked.
+	bound map[ast.Statement]bool
+}
+
+type visit struct {
+	obj  object.Object
+	decl ast.Statement
+}
+
+func (c *valueChecker) check(obj object.Object, t ast.TypeNode, env *object.Environment, path string) *object.Error {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		return c.checkNamed(obj, t, env, path)
+
+	case *ast.GenericType:
+		switch t.Base.Name {
+		case "Array":
+			if len(t.Args) == 1 {
+				return c.checkArray(obj, t, t.Args[0], env, path)
+			}
+		case "Promise":
+			if _, ok := promiseState(obj); !ok {
+				return mismatch(path, "expected %s, got %s", t, obj.Type())
+			}
+		default:
+			return c.checkDeclared(obj, t.Base.Name, t.Args, env, path)
+		}
+
+	case *ast.ArrayType:
+		return c.checkArray(obj, t, t.Elem, env, path)
+
+	case *ast.TupleType:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return mismatch(path, "expected tuple %s, got %s", t, obj.Type())
+		}
+		if len(array.Elements) != len(t.Elems) {
+			return mismatch(path, "expected tuple length %d, got %d", len(t.Elems), len(array.Elements))
+		}
+		for i, el := range array.Elements {
+			if err := c.check(el, t.Elems[i], env, indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+
+	case *ast.UnionType:
+		for _, member := range t.Types {
+			if c.check(obj, member, env, path) == nil {
+				return nil
+			}
+		}
+		return mismatch(path, "expected %s, got %s", t, obj.Type())
+
+	case *ast.IntersectionType:
+		for _, member := range t.Types {
+			if err := c.check(obj, member, env, path); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LiteralType:
+		if !literalMatches(obj, t.Value) {
+			return mismatch(path, "expected %s, got %s", t, describeValue(obj))
+		}
+
+	case *ast.FunctionType:
+		if !isCallable(obj) {
+			return mismatch(path, "expected function, got %s", obj.Type())
+		}
+
+	case *ast.ObjectType:
+		return c.checkObject(obj, t, t.String(), env, path)
+	}
+
+	// Indexed access, keyof and typeof types need the static types of the
+	// program, which only the type checker has.
+	return nil
+}
+
+func (c *valueChecker) checkNamed(obj object.Object, t *ast.NamedType, env *object.Environment, path string) *object.Error {
+	switch t.Name {
+	case "number":
+		if obj.Type() != object.NUMBER_OBJ {
+			return mismatch(path, "expected number, got %s", obj.Type())
+		}
+		return nil
+	case "string":
+		if obj.Type() != object.STRING_OBJ {
+			return mismatch(path, "expected string, got %s", obj.Type())
+		}
+		return nil
+	case "boolean":
+		if obj.Type() != object.BOOLEAN_OBJ {
+			return mismatch(path, "expected boolean, got %s", obj.Type())
+		}
+		return nil
+	case "null":
+		if obj != NULL {
+			return mismatch(path, "expected null, got %s", obj.Type())
+		}
+		return nil
+	case "undefined":
+		if obj != UNDEFINED {
+			return mismatch(path, "expected undefined, got %s", obj.Type())
+		}
+		return nil
+	case "never":
+		return mismatch(path, "cannot assign to never")
+	}
+
+	// Color.Red, a member of an enum
+	if enum, member, ok := strings.Cut(t.Name, "."); ok {
+		decl, declEnv, ok := env.GetType(enum)
+		if enum, isEnum := decl.(*ast.EnumDeclaration); ok && isEnum && strictTypes && !checkEnum(obj, enum, member, declEnv) {
+			return mismatch(path, "expected %s, got %s", t, describeValue(obj))
+		}
+		return nil
+	}
+
+	return c.checkDeclared(obj, t.Name, nil, env, path)
+}
+
+// checkDeclared checks obj against the interface or type alias called
+// name, with the type arguments args if it is generic. Its type
+// parameters are bound to them as type aliases.
+func (c *valueChecker) checkDeclared(obj object.Object, name string, args []ast.TypeNode, env *object.Environment, path string) *object.Error {
+	decl, declEnv, ok := env.GetType(name)
+	if !ok || !strictTypes {
+		return nil
+	}
+
+	if params := typeParams(decl); len(params) > 0 {
+		declEnv = object.NewEnclosedEnvironment(declEnv)
+		for i, param := range params {
+			var arg ast.TypeNode = &ast.NamedType{Token: param.Token, Name: "any"}
+			switch {
+			case i < len(args):
+				arg = args[i]
+			case param.Default != nil:
+				arg = param.Default
+			}
+			// A type parameter passed on, as T in Box<T>, stands for its own
+			// argument
+			if named, ok := arg.(*ast.NamedType); ok {
+				if outer, _, ok := env.GetType(named.Name); ok && c.bound[outer] {
+					arg = outer.(*ast.TypeAliasDeclaration).Type
+				}
+			}
+			binding := &ast.TypeAliasDeclaration{
+				Token: param.Token,
+				Name:  &ast.Identifier{Token: param.Token, Value: param.Name},
+				Type:  arg,
+			}
+			c.bound[binding] = true
+			declEnv.SetType(param.Name, binding)
+		}
+	}
+
+	key := visit{obj: obj, decl: decl}
+	if c.visiting[key] {
+		return nil
+	}
+	c.visiting[key] = true
+	defer delete(c.visiting, key)
+
+	switch decl := decl.(type) {
+	case *ast.TypeAliasDeclaration:
+		return c.check(obj, decl.Type, declEnv, path)
+	case *ast.InterfaceDeclaration:
+		for _, base := range decl.Extends {
+			if err := c.check(obj, base, declEnv, path); err != nil {
+				return err
+			}
+		}
+		return c.checkObject(obj, decl.Body, decl.Name.Value, declEnv, path)
+	case *ast.EnumDeclaration:
+		if !checkEnum(obj, decl, "", declEnv) {
+			return mismatch(path, "expected %s, got %s", name, describeValue(obj))
+		}
+	}
+	return nil
+}
+
+func typeParams(decl ast.Statement) []*ast.TypeParam {
+	switch decl := decl.(type) {
+	case *ast.InterfaceDeclaration:
+		return decl.TypeParams
+	case *ast.TypeAliasDeclaration:
+		return decl.TypeParams
+	}
+	return nil
+}
+
+func (c *valueChecker) checkArray(obj object.Object, t, elem ast.TypeNode, env *object.Environment, path string) *object.Error {
+	array, ok := obj.(*object.Array)
+	if !ok {
+		return mismatch(path, "expected %s, got %s", t, obj.Type())
+	}
+	for i, el := range array.Elements {
+		// Unset elements, as in new Array<number>(3), are undefined, which
+		// T[] allows as TypeScript does
+		if el == UNDEFINED {
+			continue
+		}
+		if err := c.check(el, elem, env, indexPath(path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkObject checks the properties of obj against an object type or the
+// body of an interface called name. Accessors are not called to check
+// them. Strings and arrays are checked by their properties such as
+// length, so that they fit { length: number }.
+func (c *valueChecker) checkObject(obj object.Object, t *ast.ObjectType, name string, env *object.Environment, path string) *object.Error {
+	hash, _ := obj.(*object.Hash)
+	get := func(key string) (object.Object, bool) { return hash.Get(key) }
+	switch obj.(type) {
+	case *object.Hash:
+	case *object.String, *object.Array:
+		get = func(key string) (object.Object, bool) {
+			val := getProperty(obj, key, obj)
+			return val, val != UNDEFINED && !isError(val)
+		}
+	default:
+		return mismatch(path, "expected %s, got %s", name, obj.Type())
+	}
+
+	listed := map[string]bool{}
+	for _, member := range t.Members {
+		listed[member.Name] = true
+
+		val, ok := get(member.Name)
+		if !ok {
+			if member.Optional {
+				continue
+			}
+			return newTypeError("type mismatch: property '%s' is missing, required by %s", propertyPath(path, member.Name), name)
+		}
+		if _, isAccessor := val.(*object.Accessor); isAccessor || (member.Optional && val == UNDEFINED) {
+			continue
+		}
+		if err := c.check(val, member.Type, env, propertyPath(path, member.Name)); err != nil {
+			return err
+		}
+	}
+
+	if t.Index != nil && hash != nil {
+		for _, key := range hash.Keys() {
+			if listed[key] {
+				continue
+			}
+			if err := c.check(hash.Pairs[key], t.Index.Value, env, propertyPath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mismatch reports a value that does not fit its type, at path within the
+// value being checked.
+func mismatch(path, format string, a ...interface{}) *object.Error {
+	if path == "" {
+		return newTypeError("type mismatch: "+format, a...)
+	}
+	return newTypeError("type mismatch at '%s': %s", path, fmt.Sprintf(format, a...))
+}
+
+func propertyPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// literalMatches reports whether obj is the value of a literal type.
+func literalMatches(obj object.Object, lit ast.Expression) bool {
+	switch lit := lit.(type) {
+	case *ast.StringLiteral:
+		s, ok := obj.(*object.String)
+		return ok && s.Value == lit.Value
+	case *ast.NumberLiteral:
+		n, ok := obj.(*object.Number)
+		return ok && n.Value == lit.Value
+	case *ast.PrefixExpression:
+		n, ok := obj.(*object.Number)
+		return ok && n.Value == -lit.Right.(*ast.NumberLiteral).Value
+	case *ast.Boolean:
+		b, ok := obj.(*object.Boolean)
+		return ok && b.Value == lit.Value
+	}
+	return false
+}
+
+// describeValue renders a value for a message, quoting strings.
+func describeValue(obj object.Object) string {
+	if s, ok := obj.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"testing"
+
+	"ts-engine/lexer"
+	"ts-engine/object"
+	"ts-engine/parser"
+)
+
+// testEvalStrict evaluates a .ts program, which checks the values of
+// annotated variables at runtime, without type checking it first.
+func testEvalStrict(t *testing.T, src string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(src), true)
+	program := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Fatalf("parse error: %s\n%s", err, src)
+	}
+	return Eval(program, object.NewEnvironment())
+}
+
+func TestRuntimeTypeChecks(t *testing.T) {
+	tests := []evalTest{
+		{"nested tuple", `let t: [[number, string], boolean] = [[1, "a"], true]; t[0][1]`, "a"},
+		{"nested tuple mismatch", `let t: [[number, string], boolean] = [[1, 2], true];`,
+			"ERROR: TypeError: type mismatch at '[0][1]': expected string, got NUMBER"},
+		{"array of numbers", `let a: number[] = [1, "x"];`,
+			"ERROR: TypeError: type mismatch at '[1]': expected number, got STRING"},
+		{"interface", `
+			interface User { name: string; age?: number }
+			let u: User = { name: "Ann" };
+			u.name`, "Ann"},
+		{"missing property", `
+			interface User { name: string; id: number }
+			let u: User = { name: "Ann" };`,
+			"ERROR: TypeError: type mismatch: property 'id' is missing, required by User"},
+		{"nested property path", `
+			type Address = { zip: number };
+			interface User extends Named { address: Address }
+			interface Named { name: string }
+			let u: User = { name: "Ann", address: { zip: "x" } };`,
+			"ERROR: TypeError: type mismatch at 'address.zip': expected number, got STRING"},
+		{"index signature", `
+			interface Dict { [key: string]: number }
+			let d: Dict = { a: 1, b: "2" };`,
+			"ERROR: TypeError: type mismatch at 'b': expected number, got STRING"},
+		{"union", `let v: string | null = null; v`, "null"},
+		{"union mismatch", `let v: string | null = 1;`,
+			"ERROR: TypeError: type mismatch: expected string | null, got NUMBER"},
+		{"undefined initializer", `let a: any = undefined; const v: number = a;`,
+			"ERROR: TypeError: type mismatch: expected number, got UNDEFINED"},
+		{"optional union", `let v: number | undefined = undefined; v`, "undefined"},
+		{"no initializer", `let v: number; v`, "undefined"},
+		{"assignment", `let n: number = 1; let s: any = "x"; n = s;`,
+			"ERROR: TypeError: type mismatch: expected number, got STRING"},
+		{"assignment after a bare declaration", `let n: number; n = 2; n`, "2"},
+		{"assignment to an annotated var", `var v: string; v = 1;`,
+			"ERROR: TypeError: type mismatch: expected string, got NUMBER"},
+		{"compound assignment", `let n: number | null = 1; n += 1; n = null; n`, "null"},
+		{"assignment in a loop", `for (let i: number = 0; i < 2; i++) { const x: any = "s"; if (i == 1) i = x; }`,
+			"ERROR: TypeError: type mismatch: expected number, got STRING"},
+		{"a shadowing variable has its own type", `let n: number = 1; { let n: any = "a"; n = "b"; } n`, "1"},
+		{"literal union", `let code: 200 | 404 = 500;`,
+			"ERROR: TypeError: type mismatch: expected 200 | 404, got NUMBER"},
+		{"intersection", `
+			type A = { a: number };
+			type B = { b: string };
+			let ab: A & B = { a: 1, b: 2 };`,
+			"ERROR: TypeError: type mismatch at 'b': expected string, got NUMBER"},
+		{"generic interface", `
+			interface Box<T> { value: T }
+			let b: Box<number> = { value: "x" };`,
+			"ERROR: TypeError: type mismatch at 'value': expected number, got STRING"},
+		{"enum type", `
+			enum Color { Red, Green }
+			let c: Color = 5;`,
+			"ERROR: TypeError: type mismatch: expected Color, got 5"},
+		{"enum member type", `
+			enum Dir { Up = "UP", Down = "DOWN" }
+			let d: Dir.Up = Dir.Down;`,
+			"ERROR: TypeError: type mismatch: expected Dir.Up, got \"DOWN\""},
+		{"string as an object type", `let o: { length: number } = "abc"; o.length`, "3"},
+		{"array as an object type", `let o: { length: number } = [1, 2]; o.length`, "2"},
+		{"string missing a property", `let o: { size: number } = "abc";`,
+			"ERROR: TypeError: type mismatch: property 'size' is missing, required by { size: number; }"},
+		{"unset elements", `let a: number[] = new Array<number>(2); a.length`, "2"},
+		{"dotted types are any", `let s: http.Server = 5; s`, "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testEvalStrict(t, tt.src).Inspect(); got != tt.want {
+				t.Errorf("got %s, want %s\n%s", got, tt.want, tt.src)
+			}
+		})
+	}
+}
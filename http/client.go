@@ -20,7 +20,7 @@ func Fetch(args ...object.Object) object.Object {
 
 	resp, err := http.Get(url.Value)
 	if err != nil {
-		return &object.Error{Message: "http error: " + err.Error()}
+		return &object.Error{Name: "TypeError", Message: "http error: " + err.Error()}
 	}
 	defer resp.Body.Close()
 
@@ -31,7 +31,7 @@ func Fetch(args ...object.Object) object.Object {
 	bodyString := string(bodyBytes)
 
 	pairs := make(map[string]object.Object)
-	pairs["status"] = &object.Integer{Value: int64(resp.StatusCode)}
+	pairs["status"] = &object.Number{Value: float64(resp.StatusCode)}
 	pairs["ok"] = &object.Boolean{Value: resp.StatusCode >= 200 && resp.StatusCode < 300}
 	pairs["statusText"] = &object.String{Value: resp.Status}
 
@@ -47,7 +47,7 @@ func Fetch(args ...object.Object) object.Object {
 		Fn: func(args ...object.Object) object.Object {
 			var result interface{}
 			if err := json.Unmarshal([]byte(bodyString), &result); err != nil {
-				return &object.Error{Message: "failed to parse JSON: " + err.Error()}
+				return &object.Error{Name: "SyntaxError", Message: "failed to parse JSON: " + err.Error()}
 			}
 			return convertJsonToObject(result)
 		},
@@ -63,7 +63,7 @@ func convertJsonToObject(v interface{}) object.Object {
 	case bool:
 		return &object.Boolean{Value: val}
 	case float64:
-		return &object.Integer{Value: int64(val)} // Simplified: JSON numbers are floats, we treat as int for now
+		return &object.Number{Value: val}
 	case string:
 		return &object.String{Value: val}
 	case []interface{}:
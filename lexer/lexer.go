@@ -1,21 +1,36 @@
 package lexer
 
-import "ts-engine/token"
+import (
+	"strconv"
+	"strings"
+	"ts-engine/token"
+	"unicode"
+)
 
 type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char
+
+	// Brace depth within each open ${ substitution of a template literal,
+	// innermost last. The '}' closing a substitution resumes the template.
+	templateDepths []int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -23,13 +38,34 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
+	// Only count the first byte of a UTF-8 sequence as a new column
+	if l.ch&0xC0 != 0x80 {
+		l.column++
+	}
 }
 
-func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+// currentPosition returns the position of the current char.
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{Line: l.line, Column: l.column, Offset: l.position}
+}
 
+// Input is the source text being tokenized.
+func (l *Lexer) Input() string { return l.input }
+
+func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
 
+	start := l.currentPosition()
+	tok := l.readToken()
+	tok.Pos = start
+	tok.End = l.currentPosition()
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -44,13 +80,31 @@ func (l *Lexer) NextToken() token.Token {
 				literal := string(ch) + string(l.ch)
 				tok = token.Token{Type: token.EQ, Literal: literal}
 			}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		switch l.peekChar() {
+		case '+':
+			tok = l.readOperator(token.INCREMENT, 2)
+		case '=':
+			tok = l.readOperator(token.PLUS_ASSIGN, 2)
+		default:
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		switch l.peekChar() {
+		case '-':
+			tok = l.readOperator(token.DECREMENT, 2)
+		case '=':
+			tok = l.readOperator(token.MINUS_ASSIGN, 2)
+		default:
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -68,35 +122,103 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		if l.peekChar() == '/' {
-			l.skipSingleLineComment()
-			return l.NextToken()
-		} else if l.peekChar() == '*' {
-			l.skipMultiLineComment()
-			return l.NextToken()
+		if l.peekChar() == '=' {
+			tok = l.readOperator(token.SLASH_ASSIGN, 2)
 		} else {
 			tok = newToken(token.SLASH, l.ch)
 		}
 	case '%':
-		tok = newToken(token.MOD, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readOperator(token.MOD_ASSIGN, 2)
+		} else {
+			tok = newToken(token.MOD, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		switch {
+		case l.peekChar() == '*' && l.peekCharAt(1) == '=':
+			tok = l.readOperator(token.EXPONENT_ASSIGN, 3)
+		case l.peekChar() == '*':
+			tok = l.readOperator(token.EXPONENT, 2)
+		case l.peekChar() == '=':
+			tok = l.readOperator(token.ASTERISK_ASSIGN, 2)
+		default:
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch {
+		case l.peekChar() == '<' && l.peekCharAt(1) == '=':
+			tok = l.readOperator(token.SHIFT_LEFT_ASSIGN, 3)
+		case l.peekChar() == '<':
+			tok = l.readOperator(token.SHIFT_LEFT, 2)
+		case l.peekChar() == '=':
+			tok = l.readOperator(token.LT_EQ, 2)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
+		// Always a single '>': see token.GT_EQ
 		tok = newToken(token.GT, l.ch)
+	case '^':
+		if l.peekChar() == '=' {
+			tok = l.readOperator(token.BIT_XOR_ASSIGN, 2)
+		} else {
+			tok = newToken(token.CARET, l.ch)
+		}
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '.':
-		tok = newToken(token.DOT, l.ch)
+		if isDigit(l.peekChar()) {
+			tok.Type = token.NUMBER
+			tok.Literal = l.readNumber()
+			return tok
+		}
+		if l.peekChar() == '.' && l.peekCharAt(1) == '.' {
+			tok = l.readOperator(token.ELLIPSIS, 3)
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case '{':
+		if n := len(l.templateDepths); n > 0 {
+			l.templateDepths[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		if n := len(l.templateDepths); n > 0 {
+			if l.templateDepths[n-1] == 0 {
+				l.templateDepths = l.templateDepths[:n-1]
+				return l.readTemplate(token.TEMPLATE_TAIL, token.TEMPLATE_MIDDLE)
+			}
+			l.templateDepths[n-1]--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '?':
+		switch {
+		case l.peekChar() == '?' && l.peekCharAt(1) == '=':
+			tok = l.readOperator(token.NULLISH_ASSIGN, 3)
+		case l.peekChar() == '?':
+			tok = l.readOperator(token.NULLISH, 2)
+		case l.peekChar() == '.' && !isDigit(l.peekCharAt(1)):
+			// a?.5:0 is a conditional with the number .5
+			tok = l.readOperator(token.OPTIONAL_CHAIN, 2)
+		default:
+			tok = newToken(token.QUESTION, l.ch)
+		}
+	case '#':
+		if isLetter(l.peekChar()) {
+			position := l.position
+			l.readChar() // consume '#'
+			l.readIdentifier()
+			tok.Type = token.PRIVATE_NAME
+			tok.Literal = l.input[position:l.position]
+			return tok
+		}
+		tok = newToken(token.ILLEGAL, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -106,32 +228,35 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '&':
-		if l.peekChar() == '&' {
+		if l.peekChar() == '&' && l.peekCharAt(1) == '=' {
+			tok = l.readOperator(token.AND_ASSIGN, 3)
+		} else if l.peekChar() == '&' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.AND, Literal: literal}
+		} else if l.peekChar() == '=' {
+			tok = l.readOperator(token.BIT_AND_ASSIGN, 2)
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = newToken(token.AMPERSAND, l.ch)
 		}
 	case '|':
-		if l.peekChar() == '|' {
+		if l.peekChar() == '|' && l.peekCharAt(1) == '=' {
+			tok = l.readOperator(token.OR_ASSIGN, 3)
+		} else if l.peekChar() == '|' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.OR, Literal: literal}
+		} else if l.peekChar() == '=' {
+			tok = l.readOperator(token.BIT_OR_ASSIGN, 2)
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			tok = newToken(token.PIPE, l.ch)
 		}
-	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString('"')
-	case '\'':
-		tok.Type = token.STRING
-		tok.Literal = l.readString('\'')
+	case '"', '\'':
+		tok = l.readString(l.ch)
 	case '`':
-		tok.Type = token.STRING
-		tok.Literal = l.readString('`')
+		return l.readTemplate(token.TEMPLATE, token.TEMPLATE_HEAD)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -141,7 +266,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
+			tok.Type = token.NUMBER
 			tok.Literal = l.readNumber()
 			return tok
 		} else {
@@ -153,9 +278,19 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// skipWhitespace skips whitespace and comments.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.skipSingleLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			l.skipMultiLineComment()
+		default:
+			return
+		}
 	}
 }
 
@@ -163,7 +298,6 @@ func (l *Lexer) skipSingleLineComment() {
 	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
 		l.readChar()
 	}
-	l.skipWhitespace() // Consume the newline
 }
 
 func (l *Lexer) skipMultiLineComment() {
@@ -178,7 +312,6 @@ func (l *Lexer) skipMultiLineComment() {
 		}
 		l.readChar()
 	}
-	l.skipWhitespace() // Consume any whitespace after comment
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -189,85 +322,122 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// readNumber reads a numeric literal: decimal integers and fractions with
+// optional exponent, or 0x/0o/0b prefixed integers. Digits may be grouped
+// with '_' separators; they are kept in the literal and stripped by the parser.
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isDigit(l.ch) {
-		l.readChar()
-	}
-	return l.input[position:l.position]
-}
 
-func (l *Lexer) readString(quote byte) string {
-	var out []byte
-	// Skip the opening quote
-	// We are already called after NextToken consumed the opening quote?
-	// Wait, NextToken case '"': calls l.readString('"').
-	// position was l.position + 1 (start of content).
-	// But l.readChar() is called inside loop.
-
-	// Correct logic:
-	// 1. We start just after the opening quote?
-	// NextToken logic:
-	/*
-		case '"':
-			tok.Type = token.STRING
-			tok.Literal = l.readString('"')
-	*/
-	// Lexer is at '"'.
-	// readString should consume content until closing quote.
-
-	// My previous readString used slicing:
-	/*
-		position := l.position + 1
-		for {
+	if l.ch == '0' && isRadixPrefix(l.peekChar()) {
+		l.readChar() // consume '0'
+		l.readChar() // consume radix letter
+		for isHexDigit(l.ch) || l.ch == '_' {
 			l.readChar()
-			...
 		}
 		return l.input[position:l.position]
-	*/
-	// This implies l.position was at '"' when called.
+	}
+
+	l.readDigits()
+
+	// The fraction may be empty, as in 5.
+	if l.ch == '.' {
+		l.readChar() // consume '.'
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) {
+			l.readChar()
+			l.readDigits()
+		} else if (next == '+' || next == '-') && l.readPosition+1 < len(l.input) && isDigit(l.input[l.readPosition+1]) {
+			l.readChar() // consume 'e'
+			l.readChar() // consume sign
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position]
+}
 
-	// New logic:
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || (l.ch == '_' && isDigit(l.peekChar())) {
+		l.readChar()
+	}
+}
+
+// readString reads a string literal from its opening quote, the current
+// char, up to the closing one, and decodes its escapes as CookTemplate
+// does for templates.
+func (l *Lexer) readString(quote byte) token.Token {
+	start := l.position + 1
 	for {
 		l.readChar()
 		if l.ch == quote || l.ch == 0 {
 			break
 		}
-		if l.ch == '\\' {
-			l.readChar()
-			if l.ch == 0 {
-				break // trailing backslash at EOF
-			}
-			// Unescape standard sequences
-			switch l.ch {
-			case 'n':
-				out = append(out, '\n')
-			case 'r':
-				out = append(out, '\r')
-			case 't':
-				out = append(out, '\t')
-			case '"':
-				out = append(out, '"')
-			case '\'':
-				out = append(out, '\'')
-			case '\\':
-				out = append(out, '\\')
-			default:
-				// Unknown escape, just keep the character
-				out = append(out, l.ch)
+		if l.ch == '\\' && l.peekChar() != 0 {
+			l.readChar() // an escaped quote does not end the string
+		}
+	}
+
+	value, ok := CookTemplate(l.input[start:l.position])
+	if !ok {
+		return token.Token{Type: token.ILLEGAL, Literal: "invalid escape sequence in string literal"}
+	}
+	return token.Token{Type: token.STRING, Literal: value}
+}
+
+// readTemplate reads a piece of a template literal, from the current '`'
+// or '}' up to and including the closing '`' (giving a token of type end)
+// or the next '${' (giving substitution). The literal is the raw text in
+// between; the parser cooks its escapes with CookTemplate.
+func (l *Lexer) readTemplate(end, substitution token.TokenType) token.Token {
+	start := l.position + 1
+
+	for {
+		l.readChar()
+		switch {
+		case l.ch == 0:
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated template literal"}
+		case l.ch == '\\':
+			if l.peekChar() != 0 {
+				l.readChar() // an escaped '`' or '$' does not end the text
 			}
-		} else {
-			out = append(out, l.ch)
+		case l.ch == '`':
+			literal := l.input[start:l.position]
+			l.readChar()
+			return token.Token{Type: end, Literal: literal}
+		case l.ch == '$' && l.peekChar() == '{':
+			literal := l.input[start:l.position]
+			l.readChar()
+			l.readChar()
+			l.templateDepths = append(l.templateDepths, 0)
+			return token.Token{Type: substitution, Literal: literal}
 		}
 	}
-	return string(out)
 }
 
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+	return l.peekCharAt(0)
+}
+
+// peekCharAt returns the character n places after the next one.
+func (l *Lexer) peekCharAt(n int) byte {
+	if l.readPosition+n >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	return l.input[l.readPosition+n]
+}
+
+// readOperator returns an operator token of the given length starting at
+// the current char, leaving the last char of the operator current.
+func (l *Lexer) readOperator(t token.TokenType, length int) token.Token {
+	position := l.position
+	for i := 1; i < length; i++ {
+		l.readChar()
+	}
+	return token.Token{Type: t, Literal: l.input[position : l.position+1]}
 }
 
 func isLetter(ch byte) bool {
@@ -278,6 +448,105 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isRadixPrefix(ch byte) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
 func newToken(tokenType token.TokenType, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
+
+// CookTemplate processes the escape sequences in the raw text of a template
+// or string literal. It reports false for a malformed escape such as \x or
+// \u{zz}, which is only allowed in tagged templates.
+func CookTemplate(raw string) (string, bool) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+
+	var out strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			out.WriteByte(raw[i])
+			continue
+		}
+
+		i++
+		if i == len(raw) {
+			return "", false
+		}
+		switch c := raw[i]; c {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'v':
+			out.WriteByte('\v')
+		case '\n':
+			// Line continuation
+		case '0':
+			if i+1 < len(raw) && isDigit(raw[i+1]) {
+				return "", false
+			}
+			out.WriteByte(0)
+		case 'x':
+			if i+2 >= len(raw) || !isHexDigit(raw[i+1]) || !isHexDigit(raw[i+2]) {
+				return "", false
+			}
+			r, _ := strconv.ParseUint(raw[i+1:i+3], 16, 8)
+			out.WriteRune(rune(r))
+			i += 2
+		case 'u':
+			r, n, ok := readUnicodeEscape(raw[i+1:])
+			if !ok {
+				return "", false
+			}
+			out.WriteRune(r)
+			i += n
+		default:
+			if isDigit(c) {
+				return "", false // octal escapes are not allowed
+			}
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String(), true
+}
+
+// readUnicodeEscape decodes the XXXX or {X...} following \u and returns
+// the rune and the number of bytes used.
+func readUnicodeEscape(s string) (rune, int, bool) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 2 {
+			return 0, 0, false
+		}
+		r, err := strconv.ParseUint(s[1:end], 16, 32)
+		if err != nil || r > unicode.MaxRune {
+			return 0, 0, false
+		}
+		return rune(r), end + 1, true
+	}
+
+	if len(s) < 4 {
+		return 0, 0, false
+	}
+	r, err := strconv.ParseUint(s[:4], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return rune(r), 4, true
+}
@@ -0,0 +1,68 @@
+package lexer
+
+import (
+	"testing"
+
+	"ts-engine/token"
+)
+
+func TestPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"é\";\n"
+	tests := []struct {
+		typ      token.TokenType
+		literal  string
+		pos, end token.Position
+	}{
+		{token.LET, "let", token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{token.IDENT, "x", token.Position{Line: 1, Column: 5, Offset: 4}, token.Position{Line: 1, Column: 6, Offset: 5}},
+		{token.ASSIGN, "=", token.Position{Line: 1, Column: 7, Offset: 6}, token.Position{Line: 1, Column: 8, Offset: 7}},
+		{token.NUMBER, "5", token.Position{Line: 1, Column: 9, Offset: 8}, token.Position{Line: 1, Column: 10, Offset: 9}},
+		{token.SEMICOLON, ";", token.Position{Line: 1, Column: 10, Offset: 9}, token.Position{Line: 1, Column: 11, Offset: 10}},
+		{token.IDENT, "x", token.Position{Line: 2, Column: 3, Offset: 13}, token.Position{Line: 2, Column: 4, Offset: 14}},
+		{token.PLUS, "+", token.Position{Line: 2, Column: 5, Offset: 15}, token.Position{Line: 2, Column: 6, Offset: 16}},
+		// Columns count characters, so the two-byte é is one column wide
+		{token.STRING, "é", token.Position{Line: 2, Column: 7, Offset: 17}, token.Position{Line: 2, Column: 10, Offset: 21}},
+		{token.SEMICOLON, ";", token.Position{Line: 2, Column: 10, Offset: 21}, token.Position{Line: 2, Column: 11, Offset: 22}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.typ || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d]: got %s %q, want %s %q", i, tok.Type, tok.Literal, tt.typ, tt.literal)
+		}
+		if tok.Pos != tt.pos || tok.End != tt.end {
+			t.Errorf("tests[%d] %q: got span %+v-%+v, want %+v-%+v", i, tok.Literal, tok.Pos, tok.End, tt.pos, tt.end)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"a\nb\tc"`, "a\nb\tc"},
+		{`'it\'s'`, "it's"},
+		{`"\"q\" \\"`, `"q" \`},
+		{`"\u0041"`, "A"},
+		{`"\u{1F600}"`, "\U0001F600"},
+		{`"\x41\x62"`, "Ab"},
+		{`"\b\f\v\0"`, "\b\f\v\x00"},
+		{`"\q"`, "q"},
+		{"\"line\\\ncontinued\"", "linecontinued"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != token.STRING || tok.Literal != tt.want {
+			t.Errorf("%s: got %s %q, want STRING %q", tt.input, tok.Type, tok.Literal, tt.want)
+		}
+	}
+
+	for _, input := range []string{`"\x4"`, `"\u00"`, `"\u{zz}"`, `"\01"`} {
+		if tok := New(input).NextToken(); tok.Type != token.ILLEGAL {
+			t.Errorf("%s: got %s %q, want ILLEGAL", input, tok.Type, tok.Literal)
+		}
+	}
+}
@@ -7,10 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 	"ts-engine/evaluator"
 	"ts-engine/lexer"
 	"ts-engine/object"
 	"ts-engine/parser"
+	"ts-engine/token"
+	"ts-engine/typecheck"
+	"unicode/utf8"
 )
 
 const magicHeaderStart = "#####"
@@ -38,7 +42,10 @@ func main() {
 		// The last part is the source code
 		if len(parts) > 1 {
 			sourceCode := string(parts[len(parts)-1])
-			runCode(sourceCode, true) // Embedded code is assumed to be TS/Strict
+			// Embedded code is assumed to be TS/Strict
+			if !runCode("<embedded>", sourceCode, true) {
+				os.Exit(1)
+			}
 			return
 		}
 	}
@@ -68,26 +75,46 @@ func main() {
 		return
 	}
 	isStrict := strings.HasSuffix(filename, ".ts")
-	runCode(string(code), isStrict)
+	if !runCode(filename, string(code), isStrict) {
+		os.Exit(1)
+	}
 }
 
-func runCode(code string, isStrict bool) {
+// runCode runs a program, reporting whether it ran to completion: it
+// fails on parse and type errors, which stop it from running, and on an
+// uncaught error.
+func runCode(filename, code string, isStrict bool) bool {
+	// With a virtual clock, timers fire as soon as nothing else is left to
+	// run, so scripts under test never sleep.
+	if os.Getenv("TSE_VIRTUAL_CLOCK") != "" {
+		evaluator.SetClock(evaluator.NewVirtualClock(time.Now()))
+	}
+
+	evaluator.SetFilename(filename)
+
 	env := object.NewEnvironment()
 	l := lexer.New(code)
 	p := parser.New(l, isStrict)
 
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
-		return
+		printParserErrors(filename, code, p.Errors())
+		return false
 	}
 
-	evaluated := evaluator.Eval(program, env)
-	if evaluated != nil && evaluated.Type() != object.NULL_OBJ {
-		if evaluated.Type() == object.ERROR_OBJ {
-			fmt.Println(evaluated.Inspect())
+	if isStrict {
+		if errors := typecheck.Check(program); len(errors) != 0 {
+			printTypeErrors(filename, code, errors)
+			return false
 		}
 	}
+
+	evaluated := evaluator.Run(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Println(formatDiagnostic(filename, code, errObj.Pos, errObj.End, errObj.Inspect()))
+		return false
+	}
+	return true
 }
 
 func buildExecutable(selfPath, sourcePath string, magicMarker []byte) {
@@ -136,9 +163,57 @@ func buildExecutable(selfPath, sourcePath string, magicMarker []byte) {
 	fmt.Printf("Built %s successfully.\n", outName)
 }
 
-func printParserErrors(errors []string) {
+func printParserErrors(filename, code string, errors []*parser.Error) {
 	fmt.Println("Parser errors:")
-	for _, msg := range errors {
-		fmt.Printf("\t%s\n", msg)
+	for _, err := range errors {
+		fmt.Println(formatDiagnostic(filename, code, err.Pos, err.End, err.Message))
+	}
+}
+
+func printTypeErrors(filename, code string, errors []*typecheck.Error) {
+	fmt.Println("Type errors:")
+	for _, err := range errors {
+		fmt.Println(formatDiagnostic(filename, code, err.Pos, err.End, err.Message))
+	}
+}
+
+// formatDiagnostic renders msg as "file:line:col: msg" followed by the
+// offending source line with the range [pos, end) underlined by carets.
+func formatDiagnostic(filename, code string, pos, end token.Position, msg string) string {
+	if !pos.IsValid() {
+		return fmt.Sprintf("%s: %s", filename, msg)
+	}
+
+	lines := strings.Split(code, "\n")
+	if pos.Line > len(lines) {
+		return fmt.Sprintf("%s:%s: %s", filename, pos, msg)
 	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+
+	// Indent the caret line with the same whitespace as the source line so
+	// tabs line up, then underline to the end of the span (or of the line).
+	var indent strings.Builder
+	col := 1
+	for _, r := range line {
+		if col >= pos.Column {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+		col++
+	}
+
+	width := utf8.RuneCountInString(line) - pos.Column + 1
+	if end.Line == pos.Line && end.Column > pos.Column {
+		width = end.Column - pos.Column
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	return fmt.Sprintf("%s:%s: %s\n    %s\n    %s%s",
+		filename, pos, msg, line, indent.String(), strings.Repeat("^", width))
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"ts-engine/token"
+)
+
+func TestFormatDiagnostic(t *testing.T) {
+	code := "let a = 1;\n\tlet b = a + missing;\n"
+	pos := token.Position{Line: 2, Column: 14, Offset: 24}
+	end := token.Position{Line: 2, Column: 21, Offset: 31}
+
+	got := formatDiagnostic("app.ts", code, pos, end, "identifier not found: missing")
+	want := "app.ts:2:14: identifier not found: missing\n" +
+		"    \tlet b = a + missing;\n" +
+		"    \t            ^^^^^^^"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if got := formatDiagnostic("app.ts", code, token.Position{}, token.Position{}, "failed"); got != "app.ts: failed" {
+		t.Errorf("without a position got %q", got)
+	}
+}
@@ -3,23 +3,33 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"ts-engine/ast"
+	"ts-engine/token"
 )
 
 type ObjectType string
 
 const (
-	INTEGER_OBJ      = "INTEGER"
+	NUMBER_OBJ       = "NUMBER"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	STRING_OBJ       = "STRING"
 	NULL_OBJ         = "NULL"
+	UNDEFINED_OBJ    = "UNDEFINED"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	BUILTIN_OBJ      = "BUILTIN"
 	HASH_OBJ         = "HASH"
 	ARRAY_OBJ        = "ARRAY"
+	CLASS_OBJ        = "CLASS"
+	ACCESSOR_OBJ     = "ACCESSOR"
+	PROMISE_OBJ      = "PROMISE"
 )
 
 type Object interface {
@@ -27,12 +37,64 @@ type Object interface {
 	Inspect() string
 }
 
-type Integer struct {
-	Value int64
+// Number is a JavaScript number: an IEEE 754 double.
+type Number struct {
+	Value float64
 }
 
-func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
-func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+func (n *Number) Type() ObjectType { return NUMBER_OBJ }
+func (n *Number) Inspect() string  { return FormatNumber(n.Value) }
+
+// FormatNumber converts f to a string the way JavaScript's Number#toString
+// does: integers print without a fraction, and exponent notation is only
+// used for very large or very small magnitudes.
+func FormatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0" // also -0
+	}
+
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+
+	// Shortest round-tripping digits, as d.ddde±x
+	sci := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, expStr, _ := strings.Cut(sci, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(expStr)
+
+	// The value is 0.digits × 10^n
+	k := len(digits)
+	n := exp + 1
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+
+	expSign := "+"
+	if n-1 < 0 {
+		expSign = "-"
+	}
+	e := strconv.Itoa(int(math.Abs(float64(n - 1))))
+	if k == 1 {
+		return sign + digits + "e" + expSign + e
+	}
+	return sign + digits[:1] + "." + digits[1:] + "e" + expSign + e
+}
 
 type Boolean struct {
 	Value bool
@@ -53,6 +115,29 @@ type Null struct{}
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
+// Undefined is the value of a missing property, element or argument, and
+// of a variable that has not been assigned.
+type Undefined struct{}
+
+func (u *Undefined) Type() ObjectType { return UNDEFINED_OBJ }
+func (u *Undefined) Inspect() string  { return "undefined" }
+
+// Break and Continue unwind statements up to the enclosing loop (or the
+// statement carrying Label), the way ReturnValue unwinds a function body.
+type Break struct {
+	Label string
+}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break " + b.Label }
+
+type Continue struct {
+	Label string
+}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue " + c.Label }
+
 type ReturnValue struct {
 	Value Object
 }
@@ -60,16 +145,39 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Error is an exception unwinding the evaluator. Value is the thrown
+// JavaScript value; errors raised by the engine itself leave it nil until
+// the exception is caught, when an Error object of kind Name is built.
 type Error struct {
 	Message string
+	Name    string // e.g. "TypeError"; empty for a plain Error
+	Value   Object
+	Pos     token.Position // where the error was raised, if known
+	End     token.Position
+	Stack   []CallFrame // calls active when the error was raised
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Name != "" {
+		return "ERROR: " + e.Name + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// CallFrame records a function call: the callee's name and the position of
+// the call in the caller.
+type CallFrame struct {
+	Function string
+	Call     token.Position
+}
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool          // names declared with const
+	types  map[string]ast.Statement // interfaces and type aliases
+	annots map[string]ast.TypeNode  // type annotations of variables
+	outer  *Environment
 }
 
 func NewEnvironment() *Environment {
@@ -101,20 +209,102 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// SetConst declares name as a constant holding val, which Assign will not
+// change.
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts == nil {
+		e.consts = map[string]bool{}
+	}
+	e.consts[name] = true
+	return e.Set(name, val)
+}
+
+// IsConst reports whether the innermost scope that declares name declares
+// it as a constant.
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.consts[name]
+		}
+	}
+	return false
+}
+
+// Assign updates name in the innermost scope that declares it and reports
+// whether such a scope was found.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
+// Annotate records the type annotation of the variable name, in the
+// innermost scope that declares it.
+func (e *Environment) Annotate(name string, t ast.TypeNode) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			if env.annots == nil {
+				env.annots = map[string]ast.TypeNode{}
+			}
+			env.annots[name] = t
+			return
+		}
+	}
+}
+
+// Annotation looks up the type annotation of the variable name, returning
+// it with the environment declaring the variable, where the names it uses
+// are resolved.
+func (e *Environment) Annotation(name string) (ast.TypeNode, *Environment, bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			t, annotated := env.annots[name]
+			return t, env, annotated
+		}
+	}
+	return nil, nil, false
+}
+
+// SetType declares an interface or type alias, which live in a namespace
+// of their own.
+func (e *Environment) SetType(name string, decl ast.Statement) {
+	if e.types == nil {
+		e.types = map[string]ast.Statement{}
+	}
+	e.types[name] = decl
+}
+
+// GetType looks up an interface or type alias, returning it with the
+// environment it was declared in, where the names it uses are resolved.
+func (e *Environment) GetType(name string) (ast.Statement, *Environment, bool) {
+	for env := e; env != nil; env = env.outer {
+		if decl, ok := env.types[name]; ok {
+			return decl, env, true
+		}
+	}
+	return nil, nil, false
+}
+
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Arrow      bool // arrow functions take 'this' from Env instead of the call
+	Async      bool
+	Rest       bool // the last parameter collects the remaining arguments
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
+	params := ast.ParameterList(f.Parameters, f.Rest)
 
 	out.WriteString("fn")
 	out.WriteString("(")
@@ -146,24 +336,54 @@ type BuiltinFunction func(args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
+
+	// Method, when set, is called instead of Fn with the receiver of the
+	// call, for builtin methods such as Promise.prototype.then.
+	Method func(this Object, args ...Object) Object
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Hash is a JavaScript object. Properties missing from Pairs are looked up
+// on Proto, which for class instances is the class prototype.
 type Hash struct {
-	Pairs map[string]Object
+	Pairs   map[string]Object
+	Order   []string               // insertion order of the keys in Pairs
+	Proto   *Hash                  // prototype, or nil
+	Private map[PrivateName]Object // #private members of class instances
+
+	// Internal holds the state of instances of builtin classes, such as a
+	// Promise's result.
+	Internal Object
 }
 
+// inspecting holds the objects and arrays being inspected further up the
+// stack, so that one that contains itself prints as [Circular] there.
+var inspecting = map[Object]bool{}
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
+	if inspecting[h] {
+		return "[Circular]"
+	}
+	inspecting[h] = true
+	defer delete(inspecting, h)
+
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", key, value.Inspect()))
+	for _, key := range h.Keys() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", key, h.Pairs[key].Inspect()))
 	}
 
+	// Instances are prefixed with their class name, as Node does
+	if class := h.Class(); class != nil && class.Name != "" {
+		out.WriteString(class.Name + " ")
+	}
+	if h.Internal != nil {
+		pairs = append([]string{h.Internal.Inspect()}, pairs...)
+	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
@@ -171,12 +391,162 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Get looks key up on h and then along its prototype chain.
+func (h *Hash) Get(key string) (Object, bool) {
+	for o := h; o != nil; o = o.Proto {
+		if val, ok := o.Pairs[key]; ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// GetOwn looks key up on h only.
+func (h *Hash) GetOwn(key string) (Object, bool) {
+	val, ok := h.Pairs[key]
+	return val, ok
+}
+
+// PrivateName identifies a #private member. Members of different classes
+// with the same spelling are different members.
+type PrivateName struct {
+	Class *Class // the class declaring the member
+	Name  string
+}
+
+// GetPrivate looks up a #private member. Fields are stored on the instance
+// itself and methods on the class prototype.
+func (h *Hash) GetPrivate(name PrivateName) (Object, bool) {
+	for o := h; o != nil; o = o.Proto {
+		if val, ok := o.Private[name]; ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// Class returns the class h is an instance of, if any.
+func (h *Hash) Class() *Class {
+	if h.Proto == nil {
+		return nil
+	}
+	class, _ := h.Proto.Pairs["constructor"].(*Class)
+	return class
+}
+
+// Set stores val under key, appending key to the iteration order if it is new.
+func (h *Hash) Set(key string, val Object) {
+	if h.Pairs == nil {
+		h.Pairs = make(map[string]Object)
+	}
+	if _, ok := h.Pairs[key]; !ok {
+		h.Order = append(h.Order, key)
+	}
+	h.Pairs[key] = val
+}
+
+func (h *Hash) Delete(key string) {
+	if _, ok := h.Pairs[key]; !ok {
+		return
+	}
+	delete(h.Pairs, key)
+	for i, k := range h.Order {
+		if k == key {
+			h.Order = append(h.Order[:i:i], h.Order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys in insertion order. Keys placed directly into Pairs
+// without going through Set come last, sorted.
+func (h *Hash) Keys() []string {
+	keys := make([]string, 0, len(h.Pairs))
+	seen := make(map[string]bool, len(h.Pairs))
+	for _, key := range h.Order {
+		if _, ok := h.Pairs[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var rest []string
+	for key := range h.Pairs {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// Accessor is a property defined by a getter and/or a setter, such as
+// 'get area()' in a class body.
+type Accessor struct {
+	Getter Object
+	Setter Object
+}
+
+func (a *Accessor) Type() ObjectType { return ACCESSOR_OBJ }
+func (a *Accessor) Inspect() string {
+	switch {
+	case a.Getter != nil && a.Setter != nil:
+		return "[Getter/Setter]"
+	case a.Getter != nil:
+		return "[Getter]"
+	default:
+		return "[Setter]"
+	}
+}
+
+// Class is a class constructor. Instances are Hashes whose Proto is
+// Prototype, which holds the methods and accessors; Statics holds static
+// members and inherits from the superclass's Statics.
+type Class struct {
+	Name        string
+	Super       *Class
+	Constructor *Function // nil when the class declares no constructor
+	Prototype   *Hash
+	Statics     *Hash
+	Env         *Environment // scope of the class body
+
+	// Instance fields, initialized on each new instance, and constructor
+	// parameters that are also fields.
+	Fields              []*ast.ClassMember
+	ParameterProperties []string
+
+	// Init constructs instances of builtin classes such as Error in
+	// place of a Constructor. Like a constructor, it may return a
+	// *ReturnValue holding an object to use instead of this.
+	Init func(this *Hash, args []Object) Object
+}
+
+func (c *Class) Type() ObjectType { return CLASS_OBJ }
+func (c *Class) Inspect() string {
+	name := c.Name
+	if name == "" {
+		name = "(anonymous)"
+	}
+	if c.Super != nil {
+		return "[class " + name + " extends " + c.Super.Name + "]"
+	}
+	return "[class " + name + "]"
+}
+
 type Array struct {
 	Elements []Object
+	Props    *Hash // named properties, such as the raw strings of a template
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
 func (ao *Array) Inspect() string {
+	if inspecting[ao] {
+		return "[Circular]"
+	}
+	inspecting[ao] = true
+	defer delete(inspecting, ao)
+
 	var out bytes.Buffer
 
 	elements := []string{}
@@ -190,3 +560,36 @@ func (ao *Array) Inspect() string {
 
 	return out.String()
 }
+
+type PromiseState int
+
+const (
+	PromisePending PromiseState = iota
+	PromiseFulfilled
+	PromiseRejected
+)
+
+// Promise is the state of a Promise instance, kept in the Internal slot of
+// its Hash. Reactions run, as microtasks, once the promise settles.
+type Promise struct {
+	State PromiseState
+	Value Object // the result, or the rejection reason
+	Err   *Error // the exception that rejected the promise
+
+	// Handled is set once a reaction is registered, so that rejecting the
+	// promise is not reported as unhandled.
+	Handled   bool
+	Reactions []func() Object
+}
+
+func (p *Promise) Type() ObjectType { return PROMISE_OBJ }
+func (p *Promise) Inspect() string {
+	switch p.State {
+	case PromiseFulfilled:
+		return p.Value.Inspect()
+	case PromiseRejected:
+		return "<rejected> " + p.Value.Inspect()
+	default:
+		return "<pending>"
+	}
+}
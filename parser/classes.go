@@ -0,0 +1,323 @@
+package parser
+
+import (
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// classScope collects the #private names declared in a class body and the
+// ones referenced in it. References are resolved when the body ends, since
+// a method may use a field declared further down.
+type classScope struct {
+	declared map[string]bool
+	used     []token.Token
+}
+
+// parseClassLiteral parses a class declaration or expression:
+// class Name extends Base implements Shape { ... }
+func (p *Parser) parseClassLiteral() ast.Expression {
+	class := &ast.ClassLiteral{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal != "implements" {
+		p.nextToken()
+		class.Name = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if class.TypeParams = p.parseTypeParams(); class.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+		p.nextToken()
+		// The heritage is a member or call expression: Base, ns.Base, mixin(Base)
+		class.SuperClass = p.parseExpression(PREFIX)
+		if class.SuperClass == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.LT) {
+			p.nextToken()
+			if class.SuperArgs = p.parseTypeArguments(); class.SuperArgs == nil {
+				return nil
+			}
+		}
+	}
+
+	// Implemented interfaces only matter to the type checker
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "implements" {
+		p.nextToken()
+		p.parseTypeAnnotation()
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.parseTypeAnnotation()
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	scope := &classScope{declared: map[string]bool{}}
+	p.classes = append(p.classes, scope)
+	members := p.parseClassBody()
+	p.classes = p.classes[:len(p.classes)-1]
+
+	if members == nil {
+		return nil
+	}
+	class.Members = members
+	p.resolvePrivateNames(scope)
+
+	return class
+}
+
+// parseClassBody parses the members up to and including the closing brace.
+func (p *Parser) parseClassBody() []*ast.ClassMember {
+	members := []*ast.ClassMember{}
+	hasConstructor := false
+
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		if p.curTokenIs(token.SEMICOLON) {
+			continue
+		}
+
+		member := p.parseClassMember()
+		if member == nil {
+			return nil
+		}
+
+		if member.Kind == ast.ClassConstructor {
+			if hasConstructor {
+				p.errorAt(member.Token, "a class may only have one constructor")
+				return nil
+			}
+			hasConstructor = true
+		}
+		members = append(members, member)
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return members
+}
+
+func (p *Parser) parseClassMember() *ast.ClassMember {
+	member := &ast.ClassMember{Token: p.curToken}
+	start := p.curToken.Pos
+
+	// Modifiers, unless the word is the member's own name as in static() {}
+	for isMemberModifier(p.curToken) && p.startsMemberName(p.peekToken) {
+		switch p.curToken.Literal {
+		case "static":
+			member.Static = true
+		case "readonly":
+			member.Readonly = true
+		case "private", "protected":
+			member.Access = p.curToken.Literal
+		}
+		p.nextToken()
+	}
+
+	async := false
+	if p.curTokenIs(token.ASYNC) && p.startsMemberName(p.peekToken) {
+		async = true
+		p.nextToken()
+	}
+
+	member.Kind = ast.ClassMethod
+	if p.curTokenIs(token.IDENT) && (p.curToken.Literal == "get" || p.curToken.Literal == "set") &&
+		p.startsMemberName(p.peekToken) {
+		if p.curToken.Literal == "get" {
+			member.Kind = ast.ClassGetter
+		} else {
+			member.Kind = ast.ClassSetter
+		}
+		p.nextToken()
+	}
+
+	if !p.startsMemberName(p.curToken) {
+		p.errorAt(p.curToken, "unexpected %s in class body", p.curToken.Type)
+		return nil
+	}
+	nameToken := p.curToken
+	member.Name = nameToken.Literal
+	if nameToken.Type == token.PRIVATE_NAME {
+		p.classes[len(p.classes)-1].declared[member.Name] = true
+	}
+
+	if p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.LT) {
+		if member.Kind == ast.ClassMethod && !member.Static && member.Name == "constructor" &&
+			nameToken.Type != token.STRING {
+			member.Kind = ast.ClassConstructor
+		}
+		member.Value = p.parseMethod(member, async)
+		if member.Value == nil {
+			return nil
+		}
+	} else {
+		if async || member.Kind != ast.ClassMethod {
+			p.peekError(token.LPAREN)
+			return nil
+		}
+		member.Kind = ast.ClassField
+		if !p.parseClassField(member) {
+			return nil
+		}
+	}
+
+	member.SetSpan(start, p.curToken.End)
+	return member
+}
+
+// parseMethod parses the parameters and body of a method, accessor or
+// constructor, with curToken on the member's name.
+func (p *Parser) parseMethod(member *ast.ClassMember, async bool) *ast.FunctionLiteral {
+	lit := &ast.FunctionLiteral{Token: p.curToken, Name: member.Name, Async: async}
+	start := p.curToken.Pos
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if lit.TypeParams = p.parseTypeParams(); lit.TypeParams == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.openScope(true)
+	defer p.closeScope()
+
+	p.inConstructor = member.Kind == ast.ClassConstructor
+	p.paramProps, p.paramModifiers = nil, nil
+	ok := p.parseFunctionParameters(lit)
+	member.ParameterProperties, member.ParameterModifiers = p.paramProps, p.paramModifiers
+	p.inConstructor = false
+	p.paramProps, p.paramModifiers = nil, nil
+
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case member.Kind == ast.ClassGetter && len(lit.Parameters) != 0:
+		p.errorAt(member.Token, "a 'get' accessor cannot have parameters")
+		return nil
+	case member.Kind == ast.ClassSetter && (len(lit.Parameters) != 1 || lit.Rest):
+		p.errorAt(member.Token, "a 'set' accessor must have exactly one parameter")
+		return nil
+	case member.Kind == ast.ClassSetter && lit.Parameters[0].Optional:
+		p.errorAt(member.Token, "a 'set' accessor cannot have an optional parameter")
+		return nil
+	case member.Kind == ast.ClassSetter && lit.Parameters[0].Default != nil:
+		p.errorAt(member.Token, "a 'set' accessor parameter cannot have an initializer")
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		lit.ReturnType = p.parseReturnType()
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	lit.Body = p.parseFunctionBody(async)
+	p.finishNode(lit, start)
+
+	return lit
+}
+
+// parseClassField parses the rest of a field declaration after its name:
+// count?: number = 0;
+func (p *Parser) parseClassField(member *ast.ClassMember) bool {
+	// Optional (count?) and definitely assigned (count!) markers
+	if p.peekTokenIs(token.QUESTION) || p.peekTokenIs(token.BANG) {
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		member.Type = p.parseTypeAnnotation()
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		member.Init = p.parseExpression(LOWEST)
+		if member.Init == nil {
+			return false
+		}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return true
+}
+
+func isMemberModifier(tok token.Token) bool {
+	if tok.Type != token.IDENT {
+		return false
+	}
+	switch tok.Literal {
+	case "static", "public", "private", "protected", "readonly", "abstract", "override":
+		return true
+	}
+	return false
+}
+
+// startsMemberName reports whether tok can be the name of a class member.
+func (p *Parser) startsMemberName(tok token.Token) bool {
+	switch tok.Type {
+	case token.IDENT, token.PRIVATE_NAME, token.STRING, token.NUMBER:
+		return true
+	}
+	return token.IsKeyword(tok.Literal)
+}
+
+func (p *Parser) parseSuperExpression() ast.Expression {
+	if len(p.classes) == 0 {
+		p.errorAt(p.curToken, "'super' is only valid inside a class body")
+		return nil
+	}
+	if !p.peekTokenIs(token.LPAREN) && !p.peekTokenIs(token.DOT) && !p.peekTokenIs(token.LBRACKET) {
+		p.errorAt(p.curToken, "'super' must be followed by an argument list or member access")
+		return nil
+	}
+	return &ast.SuperExpression{Token: p.curToken}
+}
+
+// usePrivateName records a reference to a #private name, to be checked
+// when the innermost enclosing class body ends.
+func (p *Parser) usePrivateName(tok token.Token) {
+	if len(p.classes) == 0 {
+		p.errorAt(tok, "private name %s must be declared in an enclosing class", tok.Literal)
+		return
+	}
+	scope := p.classes[len(p.classes)-1]
+	scope.used = append(scope.used, tok)
+}
+
+// resolvePrivateNames checks the #private names used in a class body that
+// has just been popped. Names it does not declare may belong to an outer
+// class.
+func (p *Parser) resolvePrivateNames(scope *classScope) {
+	for _, tok := range scope.used {
+		if scope.declared[tok.Literal] {
+			continue
+		}
+		if n := len(p.classes); n > 0 {
+			p.classes[n-1].used = append(p.classes[n-1].used, tok)
+			continue
+		}
+		p.errorAt(tok, "private name %s must be declared in an enclosing class", tok.Literal)
+	}
+}
@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// parseEnumDeclaration parses enum Name { A, B = 2, C = "c" }, with
+// curToken on 'enum'. Members' values are worked out here where they are
+// constant, so that const enum members can be inlined where they are used.
+func (p *Parser) parseEnumDeclaration(isConst bool) ast.Statement {
+	decl := &ast.EnumDeclaration{Token: p.curToken, Const: isConst}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	decl.Name = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	var prev interface{} = -1.0
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) && !token.IsKeyword(p.curToken.Literal) {
+			p.errorAt(p.curToken, "expected enum member name, got %s instead", p.curToken.Type)
+			return nil
+		}
+		member := &ast.EnumMember{Token: p.curToken, Name: p.curToken.Literal}
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			init := p.parseExpression(LOWEST)
+			if init == nil {
+				return nil
+			}
+			value, ok := enumValue(init, decl.Name.Value, values)
+			switch {
+			case ok:
+				member.Value = enumLiteral(value, init)
+			case isConst:
+				p.errorAt(member.Token, "const enum member initializers must be constant expressions.")
+				return nil
+			default:
+				member.Value, member.Computed = init, true
+			}
+			prev = value
+		} else {
+			// Members count up from the one before, or from 0
+			n, ok := prev.(float64)
+			if !ok {
+				p.errorAt(member.Token, "Enum member must have initializer.")
+				return nil
+			}
+			prev = n + 1
+			member.Value = enumLiteral(prev, nil)
+		}
+		if !member.Computed {
+			values[member.Name] = prev
+		}
+		p.finishNode(member, member.Token.Pos)
+		decl.Members = append(decl.Members, member)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if isConst {
+		p.declare(decl.Name.Value, decl, false)
+	} else {
+		p.declare(decl.Name.Value, nil, false)
+	}
+	return decl
+}
+
+// scope holds the names declared in a block or function so far, mapped to
+// the const enum they name, or to nil for any other declaration, which
+// shadows a const enum of the same name further out.
+type scope struct {
+	names    map[string]*ast.EnumDeclaration
+	function bool // whether var declarations inside land here
+}
+
+// openScope starts the scope of a block, or of a function and its
+// parameters. Every openScope is paired with a closeScope.
+func (p *Parser) openScope(function bool) {
+	p.scopes = append(p.scopes, &scope{names: map[string]*ast.EnumDeclaration{}, function: function})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records a declaration of name in the innermost scope, or for
+// var in the innermost function scope. enum is the const enum declared,
+// if that is what it is.
+func (p *Parser) declare(name string, enum *ast.EnumDeclaration, isVar bool) {
+	i := len(p.scopes) - 1
+	for isVar && i > 0 && !p.scopes[i].function {
+		i--
+	}
+	p.scopes[i].names[name] = enum
+}
+
+// declareBinding declares the variables of a let, const or var declaration.
+func (p *Parser) declareBinding(stmt *ast.LetStatement) {
+	isVar := stmt.Token.Type == token.VAR
+	if stmt.Pattern != nil {
+		for _, ident := range ast.PatternNames(stmt.Pattern) {
+			p.declare(ident.Value, nil, isVar)
+		}
+		return
+	}
+	p.declare(stmt.Name.Value, nil, isVar)
+}
+
+// constEnum returns the const enum name refers to in the current scope,
+// or nil if it refers to something else.
+func (p *Parser) constEnum(name string) *ast.EnumDeclaration {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if enum, ok := p.scopes[i].names[name]; ok {
+			return enum
+		}
+	}
+	return nil
+}
+
+// enumValue works out the value of a constant enum member initializer: a
+// literal, a previous member, or arithmetic on them. It reports false if
+// the initializer must be computed at runtime.
+func enumValue(expr ast.Expression, enum string, members map[string]interface{}) (interface{}, bool) {
+	switch expr := expr.(type) {
+	case *ast.NumberLiteral:
+		return expr.Value, true
+	case *ast.StringLiteral:
+		return expr.Value, true
+	case *ast.InlinedEnumMember:
+		return enumValue(expr.Value, enum, members)
+
+	case *ast.Identifier:
+		value, ok := members[expr.Value]
+		return value, ok
+
+	case *ast.PrefixExpression:
+		right, ok := enumValue(expr.Right, enum, members)
+		n, isNumber := right.(float64)
+		if !ok || !isNumber {
+			return nil, false
+		}
+		switch expr.Operator {
+		case "-":
+			return -n, true
+		case "+":
+			return n, true
+		case "~":
+			return float64(^toInt32(n)), true
+		}
+
+	case *ast.InfixExpression:
+		// Enum.Member, referring to a member of the enum being declared
+		if expr.Operator == "." {
+			if ident, ok := expr.Left.(*ast.Identifier); ok && ident.Value == enum {
+				return enumValue(expr.Right, enum, members)
+			}
+			return nil, false
+		}
+
+		left, ok := enumValue(expr.Left, enum, members)
+		if !ok {
+			return nil, false
+		}
+		right, ok := enumValue(expr.Right, enum, members)
+		if !ok {
+			return nil, false
+		}
+		if l, ok := left.(string); ok && expr.Operator == "+" {
+			if r, ok := right.(string); ok {
+				return l + r, true
+			}
+		}
+		l, lok := left.(float64)
+		r, rok := right.(float64)
+		if !lok || !rok {
+			return nil, false
+		}
+		switch expr.Operator {
+		case "+":
+			return l + r, true
+		case "-":
+			return l - r, true
+		case "*":
+			return l * r, true
+		case "/":
+			return l / r, true
+		case "%":
+			return math.Mod(l, r), true
+		case "**":
+			return math.Pow(l, r), true
+		case "&":
+			return float64(toInt32(l) & toInt32(r)), true
+		case "|":
+			return float64(toInt32(l) | toInt32(r)), true
+		case "^":
+			return float64(toInt32(l) ^ toInt32(r)), true
+		case "<<":
+			return float64(toInt32(l) << (uint32(toInt32(r)) & 31)), true
+		case ">>":
+			return float64(toInt32(l) >> (uint32(toInt32(r)) & 31)), true
+		case ">>>":
+			return float64(uint32(toInt32(l)) >> (uint32(toInt32(r)) & 31)), true
+		}
+	}
+	return nil, false
+}
+
+// toInt32 converts n to the 32-bit integer the bitwise operators work on,
+// as flag members like Write = 1 << 1 need.
+func toInt32(n float64) int32 {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0
+	}
+	return int32(uint32(int64(math.Mod(math.Trunc(n), 1<<32))))
+}
+
+// enumLiteral is the literal for an enum member's value, spanning the
+// initializer it was worked out from, if any.
+func enumLiteral(value interface{}, from ast.Expression) ast.Expression {
+	var lit ast.Expression
+	switch value := value.(type) {
+	case string:
+		lit = &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+	case float64:
+		literal := strconv.FormatFloat(value, 'f', -1, 64)
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			literal = strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		lit = &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: literal}, Value: value}
+	}
+	if from != nil {
+		lit.(spanned).SetSpan(from.Pos(), from.End())
+	}
+	return lit
+}
+
+// inlineEnumMember replaces Enum.Member, or Enum["Member"], with the
+// member's value if Enum is a const enum. It returns nil if it is not.
+func (p *Parser) inlineEnumMember(left ast.Expression, member token.Token) ast.Expression {
+	ident, ok := left.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	decl := p.constEnum(ident.Value)
+	if decl == nil {
+		return nil
+	}
+	for _, m := range decl.Members {
+		if m.Name == member.Literal {
+			return &ast.InlinedEnumMember{Token: ident.Token, Enum: ident.Value, Member: m.Name, Value: m.Value}
+		}
+	}
+	p.errorAt(member, "Property '%s' does not exist on type 'typeof %s'.", member.Literal, ident.Value)
+	return nil
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"ts-engine/ast"
@@ -13,35 +15,76 @@ const (
 	_ int = iota
 	LOWEST
 	ASSIGN      // =
+	CONDITIONAL // a ? b : c
+	NULLISH     // ??
 	LOGICAL_OR  // ||
 	LOGICAL_AND // &&
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	SHIFT       // << >> >>>
 	SUM         // +
 	PRODUCT     // *
+	EXPONENT    // **
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:            EQUALS,
-	token.NOT_EQ:        EQUALS,
-	token.LT:            LESSGREATER,
-	token.GT:            LESSGREATER,
-	token.PLUS:          SUM,
-	token.MINUS:         SUM,
-	token.SLASH:         PRODUCT,
-	token.ASTERISK:      PRODUCT,
-	token.MOD:           PRODUCT,
-	token.EQ_STRICT:     EQUALS,
-	token.NOT_EQ_STRICT: EQUALS,
-	token.AND:           LOGICAL_AND,
-	token.OR:            LOGICAL_OR,
-	token.LPAREN:        CALL,
-	token.DOT:           CALL,
-	token.LBRACKET:      INDEX,
-	token.ASSIGN:        ASSIGN,
+	token.EQ:                   EQUALS,
+	token.NOT_EQ:               EQUALS,
+	token.LT:                   LESSGREATER,
+	token.GT:                   LESSGREATER,
+	token.LT_EQ:                LESSGREATER,
+	token.GT_EQ:                LESSGREATER,
+	token.INSTANCEOF:           LESSGREATER,
+	token.IN:                   LESSGREATER,
+	token.SHIFT_LEFT:           SHIFT,
+	token.SHIFT_RIGHT:          SHIFT,
+	token.UNSIGNED_SHIFT_RIGHT: SHIFT,
+	token.PLUS:                 SUM,
+	token.MINUS:                SUM,
+	token.SLASH:                PRODUCT,
+	token.ASTERISK:             PRODUCT,
+	token.MOD:                  PRODUCT,
+	token.EXPONENT:             EXPONENT,
+	token.EQ_STRICT:            EQUALS,
+	token.NOT_EQ_STRICT:        EQUALS,
+	token.AMPERSAND:            BITWISE_AND,
+	token.CARET:                BITWISE_XOR,
+	token.PIPE:                 BITWISE_OR,
+	token.AND:                  LOGICAL_AND,
+	token.OR:                   LOGICAL_OR,
+	token.NULLISH:              NULLISH,
+	token.QUESTION:             CONDITIONAL,
+	token.LPAREN:               CALL,
+	token.DOT:                  CALL,
+	token.OPTIONAL_CHAIN:       CALL,
+	token.LBRACKET:             INDEX,
+	token.ASSIGN:               ASSIGN,
+
+	token.PLUS_ASSIGN:                 ASSIGN,
+	token.MINUS_ASSIGN:                ASSIGN,
+	token.ASTERISK_ASSIGN:             ASSIGN,
+	token.SLASH_ASSIGN:                ASSIGN,
+	token.MOD_ASSIGN:                  ASSIGN,
+	token.EXPONENT_ASSIGN:             ASSIGN,
+	token.BIT_AND_ASSIGN:              ASSIGN,
+	token.BIT_OR_ASSIGN:               ASSIGN,
+	token.BIT_XOR_ASSIGN:              ASSIGN,
+	token.SHIFT_LEFT_ASSIGN:           ASSIGN,
+	token.SHIFT_RIGHT_ASSIGN:          ASSIGN,
+	token.UNSIGNED_SHIFT_RIGHT_ASSIGN: ASSIGN,
+	token.AND_ASSIGN:                  ASSIGN,
+	token.OR_ASSIGN:                   ASSIGN,
+	token.NULLISH_ASSIGN:              ASSIGN,
+	token.INCREMENT:                   CALL,
+	token.TEMPLATE:                    CALL,
+	token.TEMPLATE_HEAD:               CALL,
+	token.DECREMENT:                   CALL,
 }
 
 type (
@@ -49,9 +92,26 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// Error is a syntax error found while parsing, with the source range of the
+// offending token.
+type Error struct {
+	Pos     token.Position
+	End     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*Error
+
+	// tokens buffers everything read from the lexer so the parser can look
+	// ahead arbitrarily far and backtrack; pos is the index of curToken.
+	tokens []token.Token
+	pos    int
 
 	curToken  token.Token
 	peekToken token.Token
@@ -59,31 +119,86 @@ type Parser struct {
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 	Strict         bool
+
+	// Enclosing loops, switches and labels, used to validate break and
+	// continue. All are reset at function boundaries.
+	loops    int
+	switches int
+	labels   []label
+
+	// Enclosing class bodies, used to resolve #private names, and the
+	// parameter properties of the constructor being parsed.
+	classes        []*classScope
+	inConstructor  bool
+	paramProps     []string
+	paramModifiers map[string]ast.Modifiers
+
+	// Declarations unpacking the destructured parameters of the function
+	// being parsed, to be put at the start of its body.
+	patternParams []ast.Statement
+
+	// Whether the body being parsed belongs to a function, and to an async
+	// one, where 'await' is allowed. asyncNext marks the function literal
+	// about to be created as async, after parseAsyncFunction has consumed
+	// the 'async' keyword.
+	inFunction bool
+	inAsync    bool
+	asyncNext  bool
+
+	// The scopes enclosing the code being parsed, innermost last, which
+	// tell whether a name refers to a const enum whose members are inlined.
+	scopes []*scope
+
+	// The expressions written in parentheses, which may be operands that
+	// would be ambiguous without them, as in (-2) ** 2.
+	grouped map[ast.Expression]bool
+}
+
+type label struct {
+	name string
+	loop bool // whether the label can be the target of continue
 }
 
 func New(l *lexer.Lexer, strict bool) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*Error{},
 		Strict: strict,
 	}
+	p.openScope(true)
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
-	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.NUMBER, p.parseNumberLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral)
+	p.registerPrefix(token.TEMPLATE_HEAD, p.parseTemplateLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TYPEOF, p.parsePrefixExpression)
+	p.registerPrefix(token.VOID, p.parsePrefixExpression)
+	p.registerPrefix(token.DELETE, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.PLUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
-	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.NULL, p.parseNull)
+	p.registerPrefix(token.LPAREN, p.parseParenExpression)
+	p.registerPrefix(token.LT, p.parseGenericArrowFunction)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
-	p.registerPrefix(token.AWAIT, p.parsePrefixExpression)
+	p.registerPrefix(token.ASYNC, p.parseAsyncFunction)
+	p.registerPrefix(token.THIS, p.parseThisExpression)
+	p.registerPrefix(token.NEW, p.parseNewExpression)
+	p.registerPrefix(token.CLASS, p.parseClassLiteral)
+	p.registerPrefix(token.SUPER, p.parseSuperExpression)
+	p.registerPrefix(token.AWAIT, p.parseAwaitExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.INCREMENT, p.parseUpdateExpression)
+	p.registerPrefix(token.DECREMENT, p.parseUpdateExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -96,31 +211,84 @@ func New(l *lexer.Lexer, strict bool) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.EQ_STRICT, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ_STRICT, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
+	p.registerInfix(token.LT, p.parseLessThan)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	for _, t := range []token.TokenType{
+		token.LT_EQ, token.GT_EQ, token.SHIFT_LEFT, token.SHIFT_RIGHT, token.UNSIGNED_SHIFT_RIGHT,
+		token.EXPONENT, token.AMPERSAND, token.CARET, token.PIPE, token.NULLISH,
+	} {
+		p.registerInfix(t, p.parseInfixExpression)
+	}
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.OPTIONAL_CHAIN, p.parseOptionalChain)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
-	p.registerInfix(token.DOT, p.parseInfixExpression)
+	p.registerInfix(token.DOT, p.parseDotExpression)
+	p.registerInfix(token.INSTANCEOF, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignmentExpression)
+	for _, t := range []token.TokenType{
+		token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN,
+		token.MOD_ASSIGN, token.AND_ASSIGN, token.OR_ASSIGN, token.NULLISH_ASSIGN,
+		token.EXPONENT_ASSIGN, token.BIT_AND_ASSIGN, token.BIT_OR_ASSIGN, token.BIT_XOR_ASSIGN,
+		token.SHIFT_LEFT_ASSIGN, token.SHIFT_RIGHT_ASSIGN, token.UNSIGNED_SHIFT_RIGHT_ASSIGN,
+	} {
+		p.registerInfix(t, p.parseAssignmentExpression)
+	}
+	p.registerInfix(token.INCREMENT, p.parsePostfixExpression)
+	p.registerInfix(token.TEMPLATE, p.parseTaggedTemplate)
+	p.registerInfix(token.TEMPLATE_HEAD, p.parseTaggedTemplate)
+	p.registerInfix(token.DECREMENT, p.parsePostfixExpression)
 
-	// Read two tokens, so curToken and peekToken are both set
-	p.nextToken()
+	// Read the first token, so curToken and peekToken are both set
+	p.pos = -1
 	p.nextToken()
 
 	return p
 }
 
 func (p *Parser) nextToken() {
-	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.pos++
+	p.curToken = p.tokenAt(p.pos)
+	p.peekToken = p.tokenAt(p.pos + 1)
+}
+
+// tokenAt returns the i-th token of the input, reading from the lexer as
+// needed. Reading past the end keeps returning the EOF token.
+func (p *Parser) tokenAt(i int) token.Token {
+	for len(p.tokens) <= i {
+		if n := len(p.tokens); n > 0 && p.tokens[n-1].Type == token.EOF {
+			return p.tokens[n-1]
+		}
+		p.tokens = append(p.tokens, p.l.NextToken())
+	}
+	return p.tokens[i]
+}
+
+// state is a saved parser position used to backtrack after a speculative
+// parse.
+type state struct {
+	pos    int
+	errors int
+}
+
+func (p *Parser) save() state {
+	return state{pos: p.pos, errors: len(p.errors)}
+}
+
+func (p *Parser) restore(s state) {
+	p.pos = s.pos - 1
+	p.nextToken()
+	p.errors = p.errors[:s.errors]
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
-	program := &ast.Program{}
+	program := &ast.Program{Strict: p.Strict, Source: p.l.Input()}
 	program.Statements = []ast.Statement{}
 
+	start := p.curToken.Pos
 	for p.curToken.Type != token.EOF {
 		stmt := p.parseStatement()
 		if stmt != nil {
@@ -128,38 +296,112 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+	program.SetSpan(start, p.curToken.End)
 
 	return program
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*Error {
 	return p.errors
 }
 
+// errorAt records a syntax error located at tok.
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	p.errors = append(p.errors, &Error{
+		Pos:     tok.Pos,
+		End:     tok.End,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+}
+
+// spanned is implemented by every AST node via the embedded ast.Span.
+type spanned interface {
+	SetSpan(start, end token.Position)
+}
+
+// finishNode sets the span of node to run from start to the end of the
+// current token, which by convention is the last token of the node.
+func (p *Parser) finishNode(node ast.Node, start token.Position) {
+	if n, ok := node.(spanned); ok {
+		n.SetSpan(start, p.curToken.End)
+	}
+}
+
+// tokenSpan returns the span covering a single token.
+func tokenSpan(tok token.Token) ast.Span {
+	return ast.Span{Start: tok.Pos, Stop: tok.End}
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken.Pos
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
-	case token.LET, token.CONST, token.VAR:
-		return p.parseLetStatement()
+	case token.CONST:
+		if p.peekTokenIs(token.ENUM) {
+			p.nextToken()
+			stmt = p.parseEnumDeclaration(true)
+		} else {
+			stmt = p.parseLetStatement()
+		}
+	case token.LET, token.VAR:
+		stmt = p.parseLetStatement()
+	case token.ENUM:
+		stmt = p.parseEnumDeclaration(false)
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
 	case token.EXPORT:
-		return p.parseExportStatement()
+		stmt = p.parseExportStatement()
 	case token.DECLARE:
-		return p.parseDeclareStatement()
+		stmt = p.parseDeclareStatement()
 	case token.IMPORT:
-		return p.parseImportStatement()
+		stmt = p.parseImportStatement()
+	case token.LBRACE:
+		stmt = p.parseBlockStatement()
+	case token.WHILE:
+		stmt = p.parseWhileStatement()
+	case token.DO:
+		stmt = p.parseDoWhileStatement()
+	case token.FOR:
+		stmt = p.parseForStatement()
+	case token.SWITCH:
+		stmt = p.parseSwitchStatement()
+	case token.BREAK:
+		stmt = p.parseBreakStatement()
+	case token.CONTINUE:
+		stmt = p.parseContinueStatement()
+	case token.THROW:
+		stmt = p.parseThrowStatement()
+	case token.TRY:
+		stmt = p.parseTryStatement()
+	case token.IDENT:
+		switch {
+		case p.peekTokenIs(token.COLON):
+			stmt = p.parseLabeledStatement()
+		case p.curToken.Literal == "interface" && p.peekTokenIs(token.IDENT):
+			stmt = p.parseInterfaceDeclaration()
+		case p.curToken.Literal == "type" && p.peekTokenIs(token.IDENT) &&
+			(p.tokenAt(p.pos+2).Type == token.ASSIGN || p.tokenAt(p.pos+2).Type == token.LT):
+			stmt = p.parseTypeAliasDeclaration()
+		default:
+			stmt = p.parseExpressionStatement()
+		}
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if stmt != nil {
+		p.finishNode(stmt, start)
 	}
+	return stmt
 }
 
-func (p *Parser) parseExportStatement() *ast.ExportStatement {
+func (p *Parser) parseExportStatement() ast.Statement {
 	stmt := &ast.ExportStatement{Token: p.curToken}
 
 	if !p.expectPeek(token.LBRACE) {
@@ -177,28 +419,68 @@ func (p *Parser) parseExportStatement() *ast.ExportStatement {
 	return stmt
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+// parseLetStatement parses a let, const or var statement. A statement
+// declaring several variables, let i = 0, j = n, becomes a DeclarationList.
+func (p *Parser) parseLetStatement() ast.Statement {
+	kind := p.curToken
+	first := p.parseDeclarator(kind)
+	if first == nil {
+		return nil
+	}
+	p.declareBinding(first)
+
+	var stmt ast.Statement = first
+	if p.peekTokenIs(token.COMMA) {
+		list := &ast.DeclarationList{Token: kind, Declarations: []*ast.LetStatement{first}}
+		p.finishNode(first, kind.Pos)
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			start := p.peekToken.Pos
+			decl := p.parseDeclarator(kind)
+			if decl == nil {
+				return nil
+			}
+			p.finishNode(decl, start)
+			p.declareBinding(decl)
+			list.Declarations = append(list.Declarations, decl)
+		}
+		stmt = list
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseDeclarator parses one variable of a let, const or var statement,
+// x: number = 1 or { a, b } = point, with peekToken on its name.
+func (p *Parser) parseDeclarator(kind token.Token) *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: kind}
+
+	// let { a, b } = point, let [first, second] = pair
+	if p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.LBRACKET) {
+		return p.parseDestructuringDeclaration(stmt)
+	}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
 
 	// Optional type annotation: let x: number = ...
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // consume COLON
 		stmt.Name.Type = p.parseTypeAnnotation()
 	} else if p.Strict {
-		msg := fmt.Sprintf("missing type annotation for variable '%s' in strict mode (.ts file)", stmt.Name.Value)
-		p.errors = append(p.errors, msg)
+		p.errorAt(stmt.Name.Token, "missing type annotation for variable '%s' in strict mode (.ts file)", stmt.Name.Value)
 		return nil
 	}
 
 	// Declaration without assignment: let x: number;
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.COMMA) {
 		return stmt
 	}
 
@@ -209,15 +491,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
-
-	if p.peekToken.Type == token.SEMICOLON {
-		p.nextToken()
-	}
-
 	return stmt
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
 	p.nextToken()
@@ -227,7 +504,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 		return stmt
 	}
 
-	stmt.ReturnValue = p.parseExpression(LOWEST)
+	stmt.ReturnValue = p.parseSequence()
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -236,10 +513,22 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
-	stmt.Expression = p.parseExpression(LOWEST)
+	stmt.Expression = p.parseSequence()
+
+	// Function and class declarations declare their name in the block
+	switch exp := stmt.Expression.(type) {
+	case *ast.FunctionLiteral:
+		if exp.Name != "" && (stmt.Token.Type == token.FUNCTION || stmt.Token.Type == token.ASYNC) {
+			p.declare(exp.Name, nil, false)
+		}
+	case *ast.ClassLiteral:
+		if exp.Name != nil && stmt.Token.Type == token.CLASS {
+			p.declare(exp.Name.Value, nil, false)
+		}
+	}
 
 	if p.peekToken.Type == token.SEMICOLON {
 		p.nextToken()
@@ -248,231 +537,1376 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpression(precedence int) ast.Expression {
-	prefix := p.prefixParseFns[p.curToken.Type]
-	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	start := p.curToken.Pos
+
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken)
+		return nil
+	}
+	leftExp := prefix()
+	if leftExp != nil {
+		p.finishNode(leftExp, start)
+	}
+
+	return p.parseOperators(leftExp, start, precedence)
+}
+
+// parseOperators parses the infix and postfix operators binding tighter
+// than precedence that follow left, which starts at start.
+func (p *Parser) parseOperators(left ast.Expression, start token.Position, precedence int) ast.Expression {
+	p.joinGreaterThan()
+	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return left
+		}
+
+		p.nextToken()
+
+		left = infix(left)
+		if left != nil {
+			p.finishNode(left, start)
+		}
+		p.joinGreaterThan()
+	}
+
+	return left
+}
+
+// joinGreaterThan joins the '>' at peekToken with the '>' and '=' tokens
+// right after it into >=, >>, >>>, >>= or >>>=. The lexer leaves them apart
+// so that they can close type arguments; in an expression they are one
+// operator.
+func (p *Parser) joinGreaterThan() {
+	if !p.peekTokenIs(token.GT) {
+		return
+	}
+
+	first := p.pos + 1
+	joined := p.peekToken
+	n := 1
+	for next := p.tokenAt(first + n); next.Pos.Offset == joined.End.Offset; next = p.tokenAt(first + n) {
+		if next.Type == token.GT && len(joined.Literal) < 3 && !strings.HasSuffix(joined.Literal, "=") {
+			joined.Literal += ">"
+		} else if next.Type == token.ASSIGN && !strings.HasSuffix(joined.Literal, "=") {
+			joined.Literal += "="
+		} else {
+			break
+		}
+		joined.End = next.End
+		n++
+	}
+	if n == 1 {
+		return
+	}
+
+	switch joined.Literal {
+	case ">=":
+		joined.Type = token.GT_EQ
+	case ">>":
+		joined.Type = token.SHIFT_RIGHT
+	case ">>>":
+		joined.Type = token.UNSIGNED_SHIFT_RIGHT
+	case ">>=":
+		joined.Type = token.SHIFT_RIGHT_ASSIGN
+	case ">>>=":
+		joined.Type = token.UNSIGNED_SHIFT_RIGHT_ASSIGN
+	}
+	p.tokens = append(p.tokens[:first+1], p.tokens[first+n:]...)
+	p.tokens[first] = joined
+	p.peekToken = joined
+}
+
+// parseSequence parses an expression in which the comma operator may join
+// several, a, b: a statement, a return value or a parenthesized
+// expression. Elsewhere commas separate the elements of a list.
+func (p *Parser) parseSequence() ast.Expression {
+	exp := p.parseExpression(LOWEST)
+	if exp == nil || !p.peekTokenIs(token.COMMA) {
+		return exp
+	}
+
+	seq := &ast.SequenceExpression{Token: p.peekToken, Expressions: []ast.Expression{exp}}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		next := p.parseExpression(LOWEST)
+		if next == nil {
+			return nil
+		}
+		seq.Expressions = append(seq.Expressions, next)
+	}
+	p.finishNode(seq, exp.Pos())
+	return seq
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	ident := &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	// Single-parameter arrow function: x => ...
+	if p.peekTokenIs(token.ARROW) {
+		lit := &ast.FunctionLiteral{Token: p.curToken, Arrow: true, Async: p.takeAsync()}
+		lit.Parameters = []*ast.Identifier{ident}
+		p.openScope(true)
+		defer p.closeScope()
+		p.declare(ident.Value, nil, false)
+		p.nextToken()
+		return p.parseArrowBody(lit)
+	}
+
+	return ident
+}
+
+func (p *Parser) parseThisExpression() ast.Expression {
+	return &ast.ThisExpression{Token: p.curToken}
+}
+
+func (p *Parser) parseNumberLiteral() ast.Expression {
+	lit := &ast.NumberLiteral{Token: p.curToken}
+
+	value, ok := parseNumber(p.curToken.Literal)
+	if !ok {
+		p.errorAt(p.curToken, "could not parse %q as number", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// parseNumber converts a numeric literal to its float64 value. Literals too
+// large to represent become Infinity, as in JavaScript.
+func parseNumber(literal string) (float64, bool) {
+	digits := strings.ReplaceAll(literal, "_", "")
+
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		i, ok := new(big.Int).SetString(digits, 0)
+		if !ok {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, true
+	}
+
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return f, true
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	lit := p.parseTemplate()
+	if lit == nil {
+		return nil
+	}
+
+	// Only tagged templates may contain malformed escapes
+	for _, quasi := range lit.Quasis {
+		if quasi.Invalid {
+			p.errorAt(lit.Token, "invalid escape sequence in template literal")
+			return nil
+		}
+	}
+
+	return lit
+}
+
+func (p *Parser) parseTaggedTemplate(tag ast.Expression) ast.Expression {
+	exp := &ast.TaggedTemplateExpression{Token: p.curToken, Tag: tag}
+
+	start := p.curToken.Pos
+	exp.Template = p.parseTemplate()
+	if exp.Template == nil {
+		return nil
+	}
+	p.finishNode(exp.Template, start)
+
+	return exp
+}
+
+// parseTemplate parses the template starting at curToken, which is either
+// a whole template or the head of one with substitutions.
+func (p *Parser) parseTemplate() *ast.TemplateLiteral {
+	lit := &ast.TemplateLiteral{Token: p.curToken}
+	lit.Quasis = append(lit.Quasis, templateQuasi(p.curToken.Literal))
+
+	for !p.curTokenIs(token.TEMPLATE) && !p.curTokenIs(token.TEMPLATE_TAIL) {
+		p.nextToken()
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		lit.Expressions = append(lit.Expressions, exp)
+
+		p.nextToken()
+		if p.curTokenIs(token.ILLEGAL) {
+			p.errorAt(p.curToken, "%s", p.curToken.Literal)
+			return nil
+		}
+		if !p.curTokenIs(token.TEMPLATE_MIDDLE) && !p.curTokenIs(token.TEMPLATE_TAIL) {
+			p.errorAt(p.curToken, "expected } to close template substitution, got %s instead", p.curToken.Type)
+			return nil
+		}
+		lit.Quasis = append(lit.Quasis, templateQuasi(p.curToken.Literal))
+	}
+
+	return lit
+}
+
+func templateQuasi(raw string) ast.TemplateQuasi {
+	cooked, ok := lexer.CookTemplate(raw)
+	return ast.TemplateQuasi{Cooked: cooked, Raw: strings.ReplaceAll(raw, "\r\n", "\n"), Invalid: !ok}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(PREFIX)
+
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	// ** is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if expression.Operator == "**" {
+		precedence--
+		if p.unaryOperand(left) {
+			p.errorAt(expression.Token, "unary operator used immediately before exponentiation expression; parentheses must be used to disambiguate operator precedence")
+		}
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	if expression.Operator == "??" && (p.logicalOperand(left) || p.logicalOperand(expression.Right)) {
+		p.errorAt(expression.Token, "'||' and '&&' operations cannot be mixed with '??' without parentheses")
+	}
+
+	return expression
+}
+
+// unaryOperand reports whether exp is a unary operation not in
+// parentheses, which cannot be the base of **: -2 ** 2 is an error, and
+// (-2) ** 2 is 4.
+func (p *Parser) unaryOperand(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.PrefixExpression, *ast.AwaitExpression:
+		return !p.grouped[exp]
+	}
+	return false
+}
+
+// logicalOperand reports whether exp is an && or || operation not in
+// parentheses, which cannot be an operand of ??.
+func (p *Parser) logicalOperand(exp ast.Expression) bool {
+	infix, ok := exp.(*ast.InfixExpression)
+	return ok && (infix.Operator == "&&" || infix.Operator == "||") && !p.grouped[exp]
+}
+
+// parseConditionalExpression parses cond ? a : b with curToken on '?'.
+// Either branch may be an assignment or another conditional.
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	if exp.Consequence = p.parseExpression(LOWEST); exp.Consequence == nil {
+		return nil
+	}
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	if exp.Alternative = p.parseExpression(LOWEST); exp.Alternative == nil {
+		return nil
+	}
+
+	return exp
+}
+
+// parseOptionalChain parses a?.b, a?.[key] or a?.(args) with curToken on
+// '?.', together with the rest of the chain, which is skipped if a is null
+// or undefined: a?.b.c() gives undefined rather than failing then.
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	chain := &ast.OptionalChain{Token: p.curToken}
+
+	var link ast.Expression
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		link = p.parseIndexExpression(left)
+		if index, ok := link.(*ast.IndexExpression); ok {
+			index.Optional = true
+		}
+	case p.peekTokenIs(token.LPAREN):
+		p.nextToken()
+		call := p.parseCallExpression(left).(*ast.CallExpression)
+		call.Optional = true
+		link = call
+	default:
+		link = p.parseDotExpression(left)
+		if member, ok := link.(*ast.InfixExpression); ok {
+			member.Optional = true
+		}
+	}
+	if link == nil {
+		return nil
+	}
+	p.finishNode(link, left.Pos())
+
+	if chain.Expression = p.parseOperators(link, left.Pos(), PREFIX); chain.Expression == nil {
+		return nil
+	}
+	return chain
+}
+
+// parseLessThan parses a < b, or the type arguments and arguments of a
+// call to a generic function: f<number>(x).
+func (p *Parser) parseLessThan(left ast.Expression) ast.Expression {
+	if args := p.callTypeArguments(); args != nil {
+		p.nextToken()
+		call := p.parseCallExpression(left).(*ast.CallExpression)
+		call.TypeArgs = args
+		return call
+	}
+	return p.parseInfixExpression(left)
+}
+
+func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
+	exp := &ast.AssignmentExpression{Token: p.curToken, Operator: p.curToken.Literal, Left: left}
+
+	if !isAssignable(left) && !(isPattern(left) && exp.Operator == "=") {
+		p.errorAt(p.curToken, "invalid left-hand side in assignment")
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+// parseDotExpression parses obj.name. Keywords are valid property names, and
+// #private names are checked against the enclosing classes.
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	exp := &ast.InfixExpression{Token: p.curToken, Operator: ".", Left: left}
+
+	p.nextToken()
+	switch {
+	case p.curTokenIs(token.PRIVATE_NAME):
+		p.usePrivateName(p.curToken)
+	case p.curTokenIs(token.IDENT), token.IsKeyword(p.curToken.Literal):
+	default:
+		p.errorAt(p.curToken, "expected property name after '.', got %s instead", p.curToken.Type)
+		return nil
+	}
+	exp.Right = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	if inlined := p.inlineEnumMember(left, p.curToken); inlined != nil {
+		return inlined
+	}
+	return exp
+}
+
+// parseUpdateExpression parses prefix ++x and --x.
+func (p *Parser) parseUpdateExpression() ast.Expression {
+	exp := &ast.UpdateExpression{Token: p.curToken, Operator: p.curToken.Literal, Prefix: true}
+
+	p.nextToken()
+	exp.Target = p.parseExpression(PREFIX)
+	if exp.Target == nil {
+		return nil
+	}
+	if !isAssignable(exp.Target) {
+		p.errorAt(exp.Token, "invalid operand for %s", exp.Operator)
+		return nil
+	}
+
+	return exp
+}
+
+// parsePostfixExpression parses postfix x++ and x--.
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	exp := &ast.UpdateExpression{Token: p.curToken, Operator: p.curToken.Literal, Target: left}
+
+	if !isAssignable(left) {
+		p.errorAt(exp.Token, "invalid operand for %s", exp.Operator)
+		return nil
+	}
+
+	return exp
+}
+
+// isAssignable reports whether exp can be the target of an assignment: a
+// variable, obj.prop or obj[key].
+func isAssignable(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+		return true
+	case *ast.InfixExpression:
+		return exp.Operator == "."
+	}
+	return false
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
+// parseParenExpression parses either a parenthesized expression or the
+// parameter list of an arrow function, which cannot be told apart until the
+// closing parenthesis.
+func (p *Parser) parseParenExpression() ast.Expression {
+	if next := p.tokenAt(p.closingParen() + 1); next.Type == token.ARROW || next.Type == token.COLON {
+		saved := p.save()
+		if fn := p.parseArrowFunction(); fn != nil && len(p.errors) == saved.errors {
+			return fn
+		}
+		p.restore(saved)
+	}
+
+	return p.parseGroupedExpression()
+}
+
+// closingParen returns the index of the token matching the '(', '[' or '{'
+// at curToken.
+func (p *Parser) closingParen() int {
+	return p.closingBracket(p.pos)
+}
+
+// closingBracket returns the index of the token matching the bracket at
+// index start.
+func (p *Parser) closingBracket(start int) int {
+	depth := 0
+	for i := start; ; i++ {
+		switch p.tokenAt(i).Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case token.EOF:
+			return i
+		}
+	}
+}
+
+// parseArrowFunction parses (params): type => body with curToken on '('.
+func (p *Parser) parseArrowFunction() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken, Arrow: true, Async: p.takeAsync()}
+	p.openScope(true)
+	defer p.closeScope()
+
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+
+	// Optional return type: (x: number): number => ...
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		lit.ReturnType = p.parseReturnType()
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	return p.parseArrowBody(lit)
+}
+
+// parseGenericArrowFunction parses <T>(x: T): T => x with curToken on
+// '<'.
+func (p *Parser) parseGenericArrowFunction() ast.Expression {
+	params := p.parseTypeParams()
+	if params == nil || !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lit, ok := p.parseArrowFunction().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+	lit.TypeParams = params
+	return lit
+}
+
+// parseArrowBody parses the body after '=>' (the current token). A
+// concise expression body is wrapped in a block with a single return.
+func (p *Parser) parseArrowBody(lit *ast.FunctionLiteral) ast.Expression {
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		lit.Body = p.parseFunctionBody(lit.Async)
+		return lit
+	}
+
+	p.nextToken()
+	start := p.curToken
+	params := p.patternParams
+	p.patternParams = nil
+	inFunction, inAsync := p.inFunction, p.inAsync
+	p.inFunction, p.inAsync = true, lit.Async
+	value := p.parseExpression(LOWEST)
+	p.inFunction, p.inAsync = inFunction, inAsync
+	if value == nil {
+		return nil
+	}
+
+	ret := &ast.ReturnStatement{Token: start, ReturnValue: value}
+	ret.SetSpan(value.Pos(), value.End())
+	lit.Body = &ast.BlockStatement{Token: start, Statements: []ast.Statement{ret}}
+	lit.Body.SetSpan(value.Pos(), value.End())
+	p.unpackParameters(lit.Body, params)
+
+	return lit
+}
+
+// parseAsyncFunction parses 'async function ...' and async arrow functions.
+func (p *Parser) parseAsyncFunction() ast.Expression {
+	var fn ast.Expression
+
+	p.asyncNext = true
+	defer func() { p.asyncNext = false }()
+
+	switch {
+	case p.peekTokenIs(token.FUNCTION):
+		p.nextToken()
+		fn = p.parseFunctionLiteral()
+	case p.peekTokenIs(token.LPAREN):
+		p.nextToken()
+		fn = p.parseArrowFunction()
+	case p.peekTokenIs(token.IDENT):
+		p.nextToken()
+		if p.peekTokenIs(token.ARROW) {
+			fn = p.parseIdentifier()
+		} else {
+			p.errorAt(p.peekToken, "expected => after async arrow parameter, got %s instead", p.peekToken.Type)
+			return nil
+		}
+	default:
+		p.errorAt(p.peekToken, "expected function or arrow function after async, got %s instead", p.peekToken.Type)
+		return nil
+	}
+
+	if lit, ok := fn.(*ast.FunctionLiteral); ok {
+		return lit
+	}
+	return nil
+}
+
+// takeAsync reports whether the function literal being created follows
+// 'async', clearing the mark so nested functions are not affected.
+func (p *Parser) takeAsync() bool {
+	async := p.asyncNext
+	p.asyncNext = false
+	return async
+}
+
+// parseAwaitExpression parses 'await value', which may appear in async
+// functions and at the top level, but not in other functions.
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	exp := &ast.AwaitExpression{Token: p.curToken}
+	if p.inFunction && !p.inAsync {
+		p.errorAt(p.curToken, "'await' expressions are only allowed within async functions and at the top levels of modules")
+		return nil
+	}
+
+	p.nextToken()
+	exp.Argument = p.parseExpression(PREFIX)
+	if exp.Argument == nil {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+
+	exp := p.parseSequence()
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if p.grouped == nil {
+		p.grouped = map[ast.Expression]bool{}
+	}
+	p.grouped[exp] = true
+	return exp
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseCallArguments()
+	return exp
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken, Async: p.takeAsync()}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = p.curToken.Literal
+	}
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if lit.TypeParams = p.parseTypeParams(); lit.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// The name of a function expression is only visible inside it
+	p.openScope(true)
+	defer p.closeScope()
+	if lit.Name != "" {
+		p.declare(lit.Name, nil, false)
+	}
+
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+
+	// Optional return type: function(): void { ... }
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		lit.ReturnType = p.parseReturnType()
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseFunctionBody(lit.Async)
+
+	return lit
+}
+
+// parseFunctionBody parses a function's block. Loops and labels outside
+// the function are not visible to break and continue inside it, and
+// 'await' is only allowed if the function is async.
+func (p *Parser) parseFunctionBody(async bool) *ast.BlockStatement {
+	loops, switches, labels := p.loops, p.switches, p.labels
+	inFunction, inAsync := p.inFunction, p.inAsync
+	params := p.patternParams
+	p.loops, p.switches, p.labels = 0, 0, nil
+	p.inFunction, p.inAsync = true, async
+	p.patternParams = nil
+
+	body := p.parseBlockStatement()
+	p.unpackParameters(body, params)
+
+	p.loops, p.switches, p.labels = loops, switches, labels
+	p.inFunction, p.inAsync = inFunction, inAsync
+	return body
+}
+
+// parseFunctionParameters parses the parameter list of lit, which may end
+// with a rest parameter: (first, ...others). It reports false if the list
+// is malformed.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	identifiers := []*ast.Identifier{}
+	p.patternParams = nil
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		lit.Parameters = identifiers
+		return true
+	}
+
+	optional := false
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			lit.Rest = true
+		}
+
+		var param *ast.Identifier
+		// A destructured parameter: ({ x, y }: Point)
+		if p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.LBRACKET) {
+			p.nextToken()
+			param = p.parsePatternParameter()
+		} else if p.expectPeek(token.IDENT) {
+			param = p.parseParameter()
+		}
+		if param == nil {
+			return false
+		}
+
+		switch {
+		case lit.Rest && param.Optional:
+			p.errorAt(param.Token, "A rest parameter cannot be optional.")
+		case lit.Rest && param.Default != nil:
+			p.errorAt(param.Token, "A rest parameter cannot have an initializer.")
+		case param.Optional && param.Default != nil:
+			p.errorAt(param.Token, "Parameter cannot have question mark and initializer.")
+		case optional && !param.Optional && param.Default == nil && !lit.Rest:
+			p.errorAt(param.Token, "A required parameter cannot follow an optional parameter.")
+		}
+		optional = optional || param.Optional
+		identifiers = append(identifiers, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		if lit.Rest {
+			p.errorAt(p.peekToken, "A rest parameter must be last in a parameter list.")
+			return false
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return false
+	}
+
+	for _, param := range identifiers {
+		p.declare(param.Value, nil, false)
+	}
+	for _, decl := range p.patternParams {
+		p.declareBinding(decl.(*ast.LetStatement))
+	}
+
+	lit.Parameters = identifiers
+	return true
+}
+
+// parseParameter parses a parameter name, its optional type annotation
+// and default value: (x: number = 1), or (x?: number) if it may be left
+// out. In a constructor the name may be preceded by modifiers that
+// make it a parameter property: (private readonly x: number)
+func (p *Parser) parseParameter() *ast.Identifier {
+	property := false
+	var modifiers ast.Modifiers
+	for isParameterModifier(p.curToken) && p.peekTokenIs(token.IDENT) {
+		if !p.inConstructor {
+			p.errorAt(p.curToken, "a parameter property is only allowed in a constructor")
+		}
+		property = true
+		switch p.curToken.Literal {
+		case "readonly":
+			modifiers.Readonly = true
+		case "private", "protected":
+			modifiers.Access = p.curToken.Literal
+		}
+		p.nextToken()
+	}
+	if property {
+		p.paramProps = append(p.paramProps, p.curToken.Literal)
+		if p.paramModifiers == nil {
+			p.paramModifiers = map[string]ast.Modifiers{}
+		}
+		p.paramModifiers[p.curToken.Literal] = modifiers
+	}
+
+	ident := &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.QUESTION) {
+		p.nextToken()
+		ident.Optional = true
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		ident.Type = p.parseTypeAnnotation()
+	}
+
+	var ok bool
+	if ident.Default, ok = p.parsePatternDefault(); !ok {
+		return nil
+	}
+
+	return ident
+}
+
+func isParameterModifier(tok token.Token) bool {
+	if tok.Type != token.IDENT {
+		return false
+	}
+	switch tok.Literal {
+	case "public", "private", "protected", "readonly":
+		return true
+	}
+	return false
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	expression.Consequence = p.parseIfBranch()
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			start := p.curToken.Pos
+			expression.Alternative = p.parseIfExpression()
+			p.finishNode(expression.Alternative, start)
+		} else {
+			alternative := p.parseIfBranch()
+			if alternative == nil {
+				return nil
+			}
+			expression.Alternative = alternative
+		}
+	}
+
+	return expression
+}
+
+// parseIfBranch parses the consequence or alternative of an if. A single
+// statement without braces is wrapped in a block.
+func (p *Parser) parseIfBranch() *ast.BlockStatement {
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		return p.parseBlockStatement()
+	}
+
+	start := p.curToken
+	stmt := p.parseStatement()
+	if stmt == nil {
+		return nil
+	}
+
+	block := &ast.BlockStatement{Token: start, Statements: []ast.Statement{stmt}}
+	p.finishNode(block, start.Pos)
+	return block
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	p.openScope(false)
+	defer p.closeScope()
+
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+	p.finishNode(block, block.Token.Pos)
+
+	return block
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseDoWhileStatement() ast.Statement {
+	stmt := &ast.DoWhileStatement{Token: p.curToken}
+
+	stmt.Body = p.parseLoopBody()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.WHILE) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseForStatement parses the three-clause for loop as well as for-of and
+// for-in, which are recognized by the 'of' or 'in' after the loop variable.
+func (p *Parser) parseForStatement() ast.Statement {
+	forToken := p.curToken
+
+	// Variables declared in the head are scoped to the loop
+	p.openScope(false)
+	defer p.closeScope()
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// for (const x of ...), for (x in ...)
+	var decl token.Token
+	offset := 1
+	switch p.peekToken.Type {
+	case token.LET, token.CONST, token.VAR:
+		decl = p.peekToken
+		offset = 2
+	}
+	end := p.pos + offset
+	switch p.tokenAt(end).Type {
+	case token.LBRACE, token.LBRACKET:
+		// for (const [key, value] of entries)
+		end = p.closingBracket(end)
+		if next := p.tokenAt(end + 1); next.Type == token.IDENT && next.Literal == "of" {
+			p.pos += offset - 1
+			p.nextToken()
+			return p.parseForInOfStatement(forToken, decl)
+		}
+	case token.IDENT:
+		next := p.tokenAt(end + 1)
+		if next.Type == token.IN || (next.Type == token.IDENT && next.Literal == "of") {
+			p.pos += offset - 1
+			p.nextToken()
+			return p.parseForInOfStatement(forToken, decl)
+		}
+	}
+
+	stmt := &ast.ForStatement{Token: forToken}
+
+	p.nextToken()
+	if !p.curTokenIs(token.SEMICOLON) {
+		start := p.curToken.Pos
+		switch p.curToken.Type {
+		case token.LET, token.CONST, token.VAR:
+			stmt.Init = p.parseLetStatement()
+		default:
+			stmt.Init = p.parseExpressionStatement()
+		}
+		if stmt.Init == nil {
+			return nil
+		}
+		p.finishNode(stmt.Init, start)
+		if !p.curTokenIs(token.SEMICOLON) {
+			p.peekError(token.SEMICOLON)
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Update = p.parseSequence()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+// parseForInOfStatement parses the rest of a for-of or for-in loop with
+// curToken on the loop variable, or the pattern a for-of loop unpacks each
+// value into.
+func (p *Parser) parseForInOfStatement(forToken, decl token.Token) ast.Statement {
+	var variable *ast.Identifier
+	var pattern ast.Expression
+	if p.curTokenIs(token.IDENT) {
+		variable = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+	} else if pattern = p.parsePattern(decl.Literal == ""); pattern == nil {
 		return nil
 	}
-	leftExp := prefix()
-
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
-		infix := p.infixParseFns[p.peekToken.Type]
-		if infix == nil {
-			return leftExp
-		}
-
-		p.nextToken()
 
-		leftExp = infix(leftExp)
+	if decl.Literal != "" {
+		p.declareBinding(&ast.LetStatement{Token: decl, Name: variable, Pattern: pattern})
 	}
 
-	return leftExp
-}
-
-func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-}
+	p.nextToken() // 'of' or 'in'
+	isIn := p.curTokenIs(token.IN)
 
-func (p *Parser) parseIntegerLiteral() ast.Expression {
-	lit := &ast.IntegerLiteral{Token: p.curToken}
+	p.nextToken()
+	iterable := p.parseExpression(LOWEST)
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	lit.Value = value
-
-	return lit
-}
-
-func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
-}
+	body := p.parseLoopBody()
+	if body == nil {
+		return nil
+	}
 
-func (p *Parser) parsePrefixExpression() ast.Expression {
-	expression := &ast.PrefixExpression{
-		Token:    p.curToken,
-		Operator: p.curToken.Literal,
+	if isIn {
+		return &ast.ForInStatement{Token: forToken, Declaration: decl, Variable: variable, Object: iterable, Body: body}
 	}
+	return &ast.ForOfStatement{Token: forToken, Declaration: decl, Variable: variable, Pattern: pattern, Iterable: iterable, Body: body}
+}
 
+// parseLoopBody parses the statement following a loop header.
+func (p *Parser) parseLoopBody() ast.Statement {
 	p.nextToken()
 
-	expression.Right = p.parseExpression(PREFIX)
+	p.loops++
+	body := p.parseStatement()
+	p.loops--
 
-	return expression
+	return body
 }
 
-func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	expression := &ast.InfixExpression{
-		Token:    p.curToken,
-		Operator: p.curToken.Literal,
-		Left:     left,
-	}
+// parseSwitchStatement parses a switch and its case clauses, each of which
+// runs until the next clause or the closing brace.
+func (p *Parser) parseSwitchStatement() ast.Statement {
+	stmt := &ast.SwitchStatement{Token: p.curToken}
 
-	precedence := p.curPrecedence()
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
 	p.nextToken()
-	expression.Right = p.parseExpression(precedence)
+	stmt.Discriminant = p.parseSequence()
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 
-	return expression
-}
+	p.switches++
+	defer func() { p.switches-- }()
+	p.openScope(false)
+	defer p.closeScope()
 
-func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
-	exp := &ast.AssignmentExpression{Token: p.curToken, Left: left}
+	hasDefault := false
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		clause := &ast.SwitchCase{Token: p.curToken}
+		switch p.curToken.Type {
+		case token.CASE:
+			p.nextToken()
+			clause.Test = p.parseExpression(LOWEST)
+		case token.DEFAULT:
+			if hasDefault {
+				p.errorAt(p.curToken, "more than one default clause in switch statement")
+				return nil
+			}
+			hasDefault = true
+		default:
+			p.errorAt(p.curToken, "expected case or default, got %s instead", p.curToken.Type)
+			return nil
+		}
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
 
+		for !p.peekTokenIs(token.CASE) && !p.peekTokenIs(token.DEFAULT) && !p.peekTokenIs(token.RBRACE) {
+			if p.peekTokenIs(token.EOF) {
+				p.peekError(token.RBRACE)
+				return nil
+			}
+			p.nextToken()
+			if s := p.parseStatement(); s != nil {
+				clause.Consequent = append(clause.Consequent, s)
+			}
+		}
+		p.finishNode(clause, clause.Token.Pos)
+		stmt.Cases = append(stmt.Cases, clause)
+	}
 	p.nextToken()
-	exp.Value = p.parseExpression(LOWEST)
-
-	return exp
-}
 
-func (p *Parser) parseBoolean() ast.Expression {
-	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+	return stmt
 }
 
-func (p *Parser) parseGroupedExpression() ast.Expression {
-	p.nextToken()
-
-	exp := p.parseExpression(LOWEST)
+func (p *Parser) parseBreakStatement() ast.Statement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	stmt.Label = p.parseJumpLabel()
 
-	if !p.expectPeek(token.RPAREN) {
+	switch {
+	case stmt.Label != nil:
+		if _, ok := p.findLabel(stmt.Label.Value); !ok {
+			p.errorAt(stmt.Label.Token, "undefined label '%s'", stmt.Label.Value)
+			return nil
+		}
+	case p.loops == 0 && p.switches == 0:
+		p.errorAt(stmt.Token, "illegal break statement")
 		return nil
 	}
 
-	return exp
-}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
-	return exp
+	return stmt
 }
 
-func (p *Parser) parseFunctionLiteral() ast.Expression {
-	lit := &ast.FunctionLiteral{Token: p.curToken}
-
-	if p.peekTokenIs(token.IDENT) {
-		p.nextToken()
-		lit.Name = p.curToken.Literal
-	}
+func (p *Parser) parseContinueStatement() ast.Statement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	stmt.Label = p.parseJumpLabel()
 
-	if !p.expectPeek(token.LPAREN) {
+	switch {
+	case stmt.Label != nil:
+		l, ok := p.findLabel(stmt.Label.Value)
+		if !ok {
+			p.errorAt(stmt.Label.Token, "undefined label '%s'", stmt.Label.Value)
+			return nil
+		}
+		if !l.loop {
+			p.errorAt(stmt.Label.Token, "continue target '%s' is not a loop", stmt.Label.Value)
+			return nil
+		}
+	case p.loops == 0:
+		p.errorAt(stmt.Token, "illegal continue statement")
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
-
-	// Optional return type: function(): void { ... }
-	if p.peekTokenIs(token.COLON) {
-		p.nextToken() // consume COLON
-		lit.ReturnType = p.parseTypeAnnotation()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
 	}
 
-	if !p.expectPeek(token.LBRACE) {
+	return stmt
+}
+
+// parseJumpLabel parses the optional label after break or continue, which
+// must be on the same line as the keyword.
+func (p *Parser) parseJumpLabel() *ast.Identifier {
+	if !p.peekTokenIs(token.IDENT) || p.peekToken.Pos.Line != p.curToken.Pos.Line {
 		return nil
 	}
+	p.nextToken()
+	return &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+}
 
-	lit.Body = p.parseBlockStatement()
-
-	return lit
+func (p *Parser) findLabel(name string) (label, bool) {
+	for _, l := range p.labels {
+		if l.name == name {
+			return l, true
+		}
+	}
+	return label{}, false
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseLabeledStatement() ast.Statement {
+	stmt := &ast.LabeledStatement{Token: p.curToken}
+	stmt.Label = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
 
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return identifiers
+	if _, ok := p.findLabel(stmt.Label.Value); ok {
+		p.errorAt(stmt.Token, "label '%s' has already been declared", stmt.Label.Value)
+		return nil
 	}
 
+	p.nextToken() // consume COLON
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
-	// Optional type annotation: (x: number)
-	if p.peekTokenIs(token.COLON) {
-		p.nextToken() // consume COLON
-		ident.Type = p.parseTypeAnnotation()
+	isLoop := false
+	for i := p.pos; ; i += 2 {
+		// A label on a label on a loop still labels the loop
+		switch tok := p.tokenAt(i); tok.Type {
+		case token.WHILE, token.DO, token.FOR:
+			isLoop = true
+		case token.IDENT:
+			if p.tokenAt(i+1).Type == token.COLON {
+				continue
+			}
+		}
+		break
 	}
 
-	identifiers = append(identifiers, ident)
+	p.labels = append(p.labels, label{name: stmt.Label.Value, loop: isLoop})
+	stmt.Body = p.parseStatement()
+	p.labels = p.labels[:len(p.labels)-1]
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if stmt.Body == nil {
+		return nil
+	}
 
-		// Optional type annotation: (..., y: string)
-		if p.peekTokenIs(token.COLON) {
-			p.nextToken() // consume COLON
-			ident.Type = p.parseTypeAnnotation()
-		}
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
 
-		identifiers = append(identifiers, ident)
+	// No line break is allowed between throw and its expression
+	if p.peekToken.Pos.Line != p.curToken.Pos.Line {
+		p.errorAt(p.curToken, "illegal newline after throw")
+		return nil
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
 		return nil
 	}
 
-	return identifiers
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
 }
 
-func (p *Parser) parseIfExpression() ast.Expression {
-	expression := &ast.IfExpression{Token: p.curToken}
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
+	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
+	stmt.Block = p.parseBlockStatement()
 
-	p.nextToken()
-	expression.Condition = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.parseCatchClause(stmt) {
+			return nil
+		}
+	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
 	}
 
-	if !p.expectPeek(token.LBRACE) {
+	if stmt.CatchBody == nil && stmt.Finally == nil {
+		p.errorAt(p.peekToken, "missing catch or finally after try")
 		return nil
 	}
 
-	expression.Consequence = p.parseBlockStatement()
+	return stmt
+}
 
-	if p.peekTokenIs(token.ELSE) {
-		p.nextToken()
+// parseCatchClause parses catch (e) { ... } with curToken on 'catch'. The
+// parameter is scoped to the clause.
+func (p *Parser) parseCatchClause(stmt *ast.TryStatement) bool {
+	p.openScope(false)
+	defer p.closeScope()
 
-		if p.peekTokenIs(token.IF) {
-			p.nextToken()
-			expression.Alternative = p.parseIfExpression()
-		} else {
-			if !p.expectPeek(token.LBRACE) {
-				return nil
-			}
-			expression.Alternative = p.parseBlockStatement()
+	// Optional binding: catch (e: unknown) { ... } or catch { ... }
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return false
 		}
+		stmt.CatchParam = p.parseParameter()
+		if !p.expectPeek(token.RPAREN) {
+			return false
+		}
+		p.declare(stmt.CatchParam.Value, nil, false)
 	}
 
-	return expression
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+	stmt.CatchBody = p.parseBlockStatement()
+	return true
 }
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	block := &ast.BlockStatement{Token: p.curToken}
-	block.Statements = []ast.Statement{}
+// parseNewExpression parses new Callee(args). The callee is a member
+// expression such as http.Agent; the argument list is optional.
+func (p *Parser) parseNewExpression() ast.Expression {
+	exp := &ast.NewExpression{Token: p.curToken}
 
 	p.nextToken()
+	start := p.curToken.Pos
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken)
+		return nil
+	}
+	callee := prefix()
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
+	for callee != nil && (p.peekTokenIs(token.DOT) || p.peekTokenIs(token.LBRACKET)) {
+		p.finishNode(callee, start)
+		p.nextToken()
+		callee = p.infixParseFns[p.curToken.Type](callee)
+	}
+	if callee == nil {
+		return nil
+	}
+	p.finishNode(callee, start)
+	exp.Constructor = callee
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if exp.TypeArgs = p.parseTypeArguments(); exp.TypeArgs == nil {
+			return nil
 		}
+	}
+
+	if p.peekTokenIs(token.LPAREN) {
 		p.nextToken()
+		exp.Arguments = p.parseCallArguments()
+	} else {
+		exp.Arguments = []ast.Expression{}
 	}
 
-	return block
+	return exp
 }
 
 func (p *Parser) parseCallArguments() []ast.Expression {
@@ -484,12 +1918,12 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseListElement())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -518,6 +1952,22 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
+	// The type arguments of a generic call bind like the call: a + f<T>(x)
+	if p.peekTokenIs(token.LT) {
+		saved := p.save()
+		p.nextToken()
+		args := p.callTypeArguments()
+		p.restore(saved)
+		if args != nil {
+			return CALL
+		}
+	}
+	// A line break before ++ or -- ends the expression, so that
+	// a \n ++b is two statements rather than a++ b
+	if (p.peekTokenIs(token.INCREMENT) || p.peekTokenIs(token.DECREMENT)) &&
+		p.peekToken.Pos.Line != p.curToken.End.Line {
+		return LOWEST
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -540,11 +1990,30 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
+	if p.startsAssignmentPattern() {
+		return p.parsePattern(true)
+	}
+
 	hash := &ast.HashLiteral{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
+
+		// { ...defaults, name: "x" } copies the properties of defaults
+		if p.curTokenIs(token.ELLIPSIS) {
+			spread := p.parseSpreadElement()
+			if spread == nil {
+				return nil
+			}
+			hash.Pairs[spread] = nil
+			hash.Keys = append(hash.Keys, spread)
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
 		key := p.parseExpression(LOWEST)
 
 		if !p.expectPeek(token.COLON) {
@@ -555,6 +2024,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -586,7 +2056,7 @@ func (p *Parser) parseImportStatement() ast.Statement {
 		return nil
 	}
 
-	stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Alias = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
 
 	// Expect 'from'
 	if !p.expectPeek(token.FROM) {
@@ -598,7 +2068,7 @@ func (p *Parser) parseImportStatement() ast.Statement {
 		return nil
 	}
 
-	stmt.Source = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Source = &ast.StringLiteral{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
 
 	// Optional Semicolon
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -621,78 +2091,20 @@ func (p *Parser) parseDeclareStatement() ast.Statement {
 	return nil
 }
 
-func (p *Parser) parseTypeAnnotation() string {
-	// If the next token is [, it might be a Tuple (or Array start if we supported [T] syntax, which we do now for tuples)
-	if p.peekTokenIs(token.LBRACKET) {
-		p.nextToken() // consume [
-		return p.parseTupleType()
-	}
-
-	p.nextToken() // consume first type identifier
-
-	typeName := p.curToken.Literal
-
-	for p.peekTokenIs(token.DOT) {
-		p.nextToken() // consume DOT
-		p.nextToken() // consume next part of type
-		typeName += "." + p.curToken.Literal
-	}
-
-	// Handle Array Types: number[] or string[][]
-	for p.peekTokenIs(token.LBRACKET) {
-		p.nextToken() // consume [
-		if !p.peekTokenIs(token.RBRACKET) {
-			break
-		}
-		p.nextToken() // consume ]
-		typeName += "[]"
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	// The lexer describes malformed input longer than one character
+	if tok.Type == token.ILLEGAL && len(tok.Literal) > 1 {
+		p.errorAt(tok, "%s", tok.Literal)
+		return
 	}
-
-	return typeName
+	p.errorAt(tok, "no prefix parse function for %s found", tok.Type)
 }
 
-func (p *Parser) parseTupleType() string {
-	// curToken is [
-	var types []string
-
-	if p.peekTokenIs(token.RBRACKET) {
-		p.nextToken()
-		return "[]"
-	}
-
-	for {
-		// Parse the inner type
-		// parseTypeAnnotation expects to be called *before* the type token
-		// Since we are at [ or , (previous token), and peek is the start of type.
-		// e.g. [ string
-		// cur=[, peek=string.
-		// parseTypeAnnotation calls nextToken -> cur=string. Correct.
-
-		typeStr := p.parseTypeAnnotation()
-		types = append(types, typeStr)
-
-		if p.peekTokenIs(token.RBRACKET) {
-			break
-		}
-
-		if !p.expectPeek(token.COMMA) {
-			return ""
-		}
-	}
-
-	if !p.expectPeek(token.RBRACKET) {
-		return ""
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	if p.startsAssignmentPattern() {
+		return p.parsePattern(true)
 	}
 
-	return "[" + strings.Join(types, ", ") + "]"
-}
-
-func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
-}
-
-func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
@@ -709,12 +2121,12 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -724,6 +2136,26 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseListElement parses an element of an array literal or an argument of
+// a call, which may be spread: f(...args), [...a, ...b].
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	return p.parseSpreadElement()
+}
+
+// parseSpreadElement parses ...value with curToken on '...'.
+func (p *Parser) parseSpreadElement() ast.Expression {
+	spread := &ast.SpreadElement{Token: p.curToken}
+	p.nextToken()
+	if spread.Argument = p.parseExpression(LOWEST); spread.Argument == nil {
+		return nil
+	}
+	p.finishNode(spread, spread.Token.Pos)
+	return spread
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 
@@ -734,5 +2166,10 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	if key, ok := exp.Index.(*ast.StringLiteral); ok {
+		if inlined := p.inlineEnumMember(left, key.Token); inlined != nil {
+			return inlined
+		}
+	}
 	return exp
 }
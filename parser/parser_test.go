@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"ts-engine/lexer"
+	"ts-engine/token"
+)
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		src string
+		pos token.Position
+	}{
+		{"let = 5;", token.Position{Line: 1, Column: 5, Offset: 4}},
+		{"let x = 1;\nlet y = (2;", token.Position{Line: 2, Column: 11, Offset: 21}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.src), false)
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Fatalf("expected a parse error for %q", tt.src)
+		}
+		if errs[0].Pos != tt.pos {
+			t.Errorf("%q: got error at %+v (%s), want %+v", tt.src, errs[0].Pos, errs[0].Message, tt.pos)
+		}
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	src := "let x = 1;\nfoo(x, 2);"
+	p := New(lexer.New(src), false)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	tests := []struct {
+		pos, end token.Position
+	}{
+		{token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 11, Offset: 10}},
+		{token.Position{Line: 2, Column: 1, Offset: 11}, token.Position{Line: 2, Column: 11, Offset: 21}},
+	}
+	for i, tt := range tests {
+		stmt := program.Statements[i]
+		if stmt.Pos() != tt.pos || stmt.End() != tt.end {
+			t.Errorf("statement %d: got span %+v-%+v, want %+v-%+v", i, stmt.Pos(), stmt.End(), tt.pos, tt.end)
+		}
+	}
+}
+
+func TestOperatorSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"-2 ** 2;", "unary operator used immediately before exponentiation expression"},
+		{"typeof x ** 2;", "unary operator used immediately before exponentiation expression"},
+		{"a ?? b || c;", "'||' and '&&' operations cannot be mixed with '??' without parentheses"},
+		{"a && b ?? c;", "'||' and '&&' operations cannot be mixed with '??' without parentheses"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.src), false)
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("expected a parse error for %q", tt.src)
+			continue
+		}
+		if !strings.Contains(errs[0].Message, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.src, errs[0].Message, tt.want)
+		}
+	}
+
+	for _, src := range []string{"(-2) ** 2;", "a ?? (b || c);", "(a && b) ?? c;"} {
+		p := New(lexer.New(src), false)
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("%q: unexpected errors %v", src, p.Errors())
+		}
+	}
+}
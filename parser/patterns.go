@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// parsePattern parses a destructuring pattern with curToken on '{' or '['.
+// In an assignment (assign is true) its targets may be any assignable
+// expression; otherwise they are variables being declared.
+func (p *Parser) parsePattern(assign bool) ast.Expression {
+	start := p.curToken.Pos
+
+	var pattern ast.Expression
+	if p.curTokenIs(token.LBRACE) {
+		pattern = p.parseObjectPattern(assign)
+	} else {
+		pattern = p.parseArrayPattern(assign)
+	}
+	if pattern == nil {
+		return nil
+	}
+
+	p.finishNode(pattern, start)
+	return pattern
+}
+
+// isPattern reports whether exp is a destructuring pattern.
+func isPattern(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.ObjectPattern, *ast.ArrayPattern:
+		return true
+	}
+	return false
+}
+
+// startsAssignmentPattern reports whether the array or object literal at
+// curToken is the left-hand side of an assignment, [a, b] = [b, a].
+func (p *Parser) startsAssignmentPattern() bool {
+	return p.tokenAt(p.closingParen()+1).Type == token.ASSIGN
+}
+
+// parseObjectPattern parses { a, b: c, d = 1, [key]: e, ...rest }.
+func (p *Parser) parseObjectPattern(assign bool) ast.Expression {
+	pattern := &ast.ObjectPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if pattern.Rest = p.parsePatternRest(token.RBRACE, assign); pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		prop := p.parsePatternProperty(assign)
+		if prop == nil {
+			return nil
+		}
+		pattern.Properties = append(pattern.Properties, prop)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
+// parsePatternProperty parses a property of an object pattern with
+// curToken on its key.
+func (p *Parser) parsePatternProperty(assign bool) *ast.PatternProperty {
+	start := p.curToken
+	prop := &ast.PatternProperty{}
+
+	switch {
+	case p.curTokenIs(token.LBRACKET):
+		p.nextToken()
+		prop.Key, prop.Computed = p.parseExpression(LOWEST), true
+		if prop.Key == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+	case p.curTokenIs(token.STRING):
+		prop.Key = p.parseStringLiteral()
+	case p.curTokenIs(token.NUMBER):
+		prop.Key = p.parseNumberLiteral()
+	case p.curTokenIs(token.IDENT), token.IsKeyword(p.curToken.Literal):
+		prop.Key = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+	default:
+		p.errorAt(p.curToken, "expected property name in destructuring pattern, got %s instead", p.curToken.Type)
+		return nil
+	}
+	if prop.Key == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		if prop.Target = p.parsePatternTarget(assign); prop.Target == nil {
+			return nil
+		}
+	} else {
+		// The shorthand { a } binds the property to a variable of its name
+		if start.Type != token.IDENT {
+			p.peekError(token.COLON)
+			return nil
+		}
+		prop.Target = &ast.Identifier{Span: tokenSpan(start), Token: start, Value: start.Literal}
+	}
+
+	var ok bool
+	if prop.Default, ok = p.parsePatternDefault(); !ok {
+		return nil
+	}
+
+	p.finishNode(prop, start.Pos)
+	return prop
+}
+
+// parseArrayPattern parses [a, , [b], c = 1, ...rest]. Holes skip an
+// element.
+func (p *Parser) parseArrayPattern(assign bool) ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			pattern.Elements = append(pattern.Elements, nil)
+			continue
+		}
+
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if pattern.Rest = p.parsePatternRest(token.RBRACKET, assign); pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		start := p.curToken.Pos
+		el := &ast.PatternElement{}
+		if el.Target = p.parsePatternTarget(assign); el.Target == nil {
+			return nil
+		}
+		var ok bool
+		if el.Default, ok = p.parsePatternDefault(); !ok {
+			return nil
+		}
+		p.finishNode(el, start)
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+// parsePatternRest parses ...target with curToken on '...'. It must be the
+// last element of the pattern, closed by end.
+func (p *Parser) parsePatternRest(end token.TokenType, assign bool) ast.Expression {
+	p.nextToken()
+	rest := p.parsePatternTarget(assign)
+	if rest == nil {
+		return nil
+	}
+	if !p.peekTokenIs(end) {
+		p.errorAt(p.peekToken, "A rest element must be last in a destructuring pattern.")
+		return nil
+	}
+	return rest
+}
+
+// parsePatternTarget parses what a pattern element is unpacked into: a
+// nested pattern, a variable or, in an assignment, any assignable
+// expression.
+func (p *Parser) parsePatternTarget(assign bool) ast.Expression {
+	switch {
+	case p.curTokenIs(token.LBRACE), p.curTokenIs(token.LBRACKET):
+		return p.parsePattern(assign)
+
+	case assign:
+		tok := p.curToken
+		target := p.parseExpression(ASSIGN)
+		if target != nil && !isAssignable(target) {
+			p.errorAt(tok, "invalid destructuring assignment target")
+			return nil
+		}
+		return target
+
+	case p.curTokenIs(token.IDENT):
+		return &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	p.errorAt(p.curToken, "expected variable name in destructuring pattern, got %s instead", p.curToken.Type)
+	return nil
+}
+
+// parsePatternDefault parses the '= value' after a pattern element, if
+// there is one. It reports false if the value is malformed.
+func (p *Parser) parsePatternDefault() (ast.Expression, bool) {
+	if !p.peekTokenIs(token.ASSIGN) {
+		return nil, true
+	}
+	p.nextToken()
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	return value, value != nil
+}
+
+// parseDestructuringDeclaration parses the rest of let { a, b }: T = value
+// with peekToken on the pattern.
+func (p *Parser) parseDestructuringDeclaration(stmt *ast.LetStatement) *ast.LetStatement {
+	p.nextToken()
+	stmt.Pattern = p.parsePattern(false)
+	if stmt.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		setPatternType(stmt.Pattern, p.parseTypeAnnotation())
+	}
+
+	if !p.peekTokenIs(token.ASSIGN) {
+		p.errorAt(p.peekToken, "A destructuring declaration must have an initializer.")
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	return stmt
+}
+
+func setPatternType(pattern ast.Expression, t ast.TypeNode) {
+	switch pattern := pattern.(type) {
+	case *ast.ObjectPattern:
+		pattern.Type = t
+	case *ast.ArrayPattern:
+		pattern.Type = t
+	}
+}
+
+// parsePatternParameter parses a destructured parameter, ({ x, y }: Point),
+// which may have a default value, ({ x, y }: Point = origin).
+// The function takes it as a single parameter named after the pattern, and
+// a declaration added to the start of its body unpacks it.
+func (p *Parser) parsePatternParameter() *ast.Identifier {
+	start := p.curToken
+	pattern := p.parsePattern(false)
+	if pattern == nil {
+		return nil
+	}
+
+	param := &ast.Identifier{Token: start, Value: pattern.String()}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		param.Type = p.parseTypeAnnotation()
+	}
+	var ok bool
+	if param.Default, ok = p.parsePatternDefault(); !ok {
+		return nil
+	}
+	p.finishNode(param, start.Pos)
+
+	decl := &ast.LetStatement{
+		Token:   token.Token{Type: token.LET, Literal: "let", Pos: start.Pos, End: start.End},
+		Pattern: pattern,
+		Value:   &ast.Identifier{Span: param.Span, Token: start, Value: param.Value},
+	}
+	decl.SetSpan(param.Pos(), param.End())
+	p.patternParams = append(p.patternParams, decl)
+
+	return param
+}
+
+// unpackParameters puts the declarations unpacking the destructured
+// parameters of the function just parsed at the start of its body.
+func (p *Parser) unpackParameters(body *ast.BlockStatement, decls []ast.Statement) {
+	if len(decls) > 0 && body != nil {
+		body.Statements = append(decls, body.Statements...)
+	}
+}
@@ -0,0 +1,621 @@
+package parser
+
+import (
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// parseTypeAnnotation parses the type that follows the current token,
+// usually a ':', leaving the last token of the type as the current token.
+func (p *Parser) parseTypeAnnotation() ast.TypeNode {
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseReturnType parses the return type annotation of a function, which
+// unlike other annotations may be a type predicate: x is Fish.
+func (p *Parser) parseReturnType() ast.TypeNode {
+	next := p.tokenAt(p.pos + 2)
+	if !p.peekTokenIs(token.IDENT) || next.Type != token.IDENT || next.Literal != "is" {
+		return p.parseTypeAnnotation()
+	}
+
+	p.nextToken()
+	predicate := &ast.TypePredicate{Token: p.curToken, ParamName: p.curToken.Literal}
+	p.nextToken()
+	predicate.Type = p.parseTypeAnnotation()
+	if predicate.Type == nil {
+		return nil
+	}
+	p.finishNode(predicate, predicate.Token.Pos)
+
+	return predicate
+}
+
+// parseType parses a type starting at the current token. From loosest to
+// tightest binding, types are unions (A | B), intersections (A & B),
+// array and indexed access types (T[], T["key"]) and the rest.
+func (p *Parser) parseType() ast.TypeNode {
+	start := p.curToken
+
+	// A union may start with '|', to line up members written one per line
+	if p.curTokenIs(token.PIPE) {
+		p.nextToken()
+	}
+
+	first := p.parseIntersectionType()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+
+	union := &ast.UnionType{Token: start, Types: []ast.TypeNode{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		t := p.parseIntersectionType()
+		if t == nil {
+			return nil
+		}
+		union.Types = append(union.Types, t)
+	}
+	p.finishNode(union, start.Pos)
+
+	return union
+}
+
+func (p *Parser) parseIntersectionType() ast.TypeNode {
+	start := p.curToken
+
+	first := p.parsePostfixType()
+	if first == nil || !p.peekTokenIs(token.AMPERSAND) {
+		return first
+	}
+
+	intersection := &ast.IntersectionType{Token: start, Types: []ast.TypeNode{first}}
+	for p.peekTokenIs(token.AMPERSAND) {
+		p.nextToken()
+		p.nextToken()
+		t := p.parsePostfixType()
+		if t == nil {
+			return nil
+		}
+		intersection.Types = append(intersection.Types, t)
+	}
+	p.finishNode(intersection, start.Pos)
+
+	return intersection
+}
+
+// parsePostfixType parses array types, number[][], and indexed access
+// types, Person["name"].
+func (p *Parser) parsePostfixType() ast.TypeNode {
+	t := p.parsePrimaryType()
+
+	for t != nil && p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		bracket := p.curToken
+
+		if p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			array := &ast.ArrayType{Token: bracket, Elem: t}
+			p.finishNode(array, t.Pos())
+			t = array
+			continue
+		}
+
+		index := p.parseTypeAnnotation()
+		if index == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		access := &ast.IndexedAccessType{Token: bracket, Object: t, Index: index}
+		p.finishNode(access, t.Pos())
+		t = access
+	}
+
+	return t
+}
+
+func (p *Parser) parsePrimaryType() ast.TypeNode {
+	switch p.curToken.Type {
+	case token.TYPEOF:
+		return p.parseTypeofType()
+
+	case token.NULL, token.VOID:
+		named := &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+		p.finishNode(named, named.Token.Pos)
+		return named
+
+	case token.IDENT, token.THIS:
+		switch p.curToken.Literal {
+		case "keyof":
+			return p.parseKeyofType()
+		case "readonly":
+			// readonly string[] only differs from string[] to the checker
+			if p.peekTokenIs(token.IDENT) || p.peekTokenIs(token.LBRACKET) {
+				p.nextToken()
+				return p.parsePostfixType()
+			}
+		}
+		return p.parseNamedType()
+
+	case token.STRING, token.NUMBER, token.TRUE, token.FALSE, token.MINUS:
+		return p.parseLiteralType()
+
+	case token.LBRACKET:
+		return p.parseTupleType()
+
+	case token.LBRACE:
+		return p.parseObjectType()
+
+	case token.LT:
+		// A generic function type: <T>(x: T) => T
+		start := p.curToken
+		params := p.parseTypeParams()
+		if params == nil || !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		fn, ok := p.parseFunctionType().(*ast.FunctionType)
+		if !ok {
+			return nil
+		}
+		fn.Token, fn.TypeParams = start, params
+		p.finishNode(fn, start.Pos)
+		return fn
+
+	case token.LPAREN:
+		if next := p.tokenAt(p.closingParen() + 1); next.Type == token.ARROW {
+			return p.parseFunctionType()
+		}
+		// A parenthesized type: (string | number)[]
+		t := p.parseTypeAnnotation()
+		if t == nil || !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return t
+	}
+
+	p.errorAt(p.curToken, "expected a type, got %s instead", p.curToken.Type)
+	return nil
+}
+
+// parseNamedType parses a possibly dotted type name, with type arguments
+// if it has them: number, http.IncomingMessage, Promise<string>.
+func (p *Parser) parseNamedType() ast.TypeNode {
+	named := &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		named.Name += "." + p.curToken.Literal
+	}
+	p.finishNode(named, named.Token.Pos)
+
+	if !p.peekTokenIs(token.LT) {
+		return named
+	}
+
+	p.nextToken()
+	generic := &ast.GenericType{Token: p.curToken, Base: named}
+	if generic.Args = p.parseTypeArguments(); generic.Args == nil {
+		return nil
+	}
+	p.finishNode(generic, named.Token.Pos)
+
+	return generic
+}
+
+func (p *Parser) parseLiteralType() ast.TypeNode {
+	lit := &ast.LiteralType{Token: p.curToken}
+
+	var value ast.Expression
+	switch p.curToken.Type {
+	case token.STRING:
+		value = p.parseStringLiteral()
+	case token.TRUE, token.FALSE:
+		value = p.parseBoolean()
+	case token.NUMBER:
+		value = p.parseNumberLiteral()
+	case token.MINUS:
+		minus := p.curToken
+		if !p.expectPeek(token.NUMBER) {
+			return nil
+		}
+		right := p.parseNumberLiteral()
+		if right == nil {
+			return nil
+		}
+		right.(*ast.NumberLiteral).Span = tokenSpan(p.curToken)
+		value = &ast.PrefixExpression{Token: minus, Operator: "-", Right: right}
+	}
+	if value == nil {
+		return nil
+	}
+
+	p.finishNode(value, lit.Token.Pos)
+	lit.Value = value
+	p.finishNode(lit, lit.Token.Pos)
+
+	return lit
+}
+
+// parseTupleType parses [string, number], with curToken on '['.
+func (p *Parser) parseTupleType() ast.TypeNode {
+	tuple := &ast.TupleType{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		elem := p.parseTypeAnnotation()
+		if elem == nil {
+			return nil
+		}
+		tuple.Elems = append(tuple.Elems, elem)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	p.finishNode(tuple, tuple.Token.Pos)
+
+	return tuple
+}
+
+// parseFunctionType parses (x: number) => string, with curToken on '('.
+func (p *Parser) parseFunctionType() ast.TypeNode {
+	fn := &ast.FunctionType{Token: p.curToken}
+
+	fn.Params = p.parseFunctionTypeParams()
+	if fn.Params == nil || !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	fn.Return = p.parseTypeAnnotation()
+	if fn.Return == nil {
+		return nil
+	}
+	p.finishNode(fn, fn.Token.Pos)
+
+	return fn
+}
+
+// parseFunctionTypeParams parses the parameters of a function type or
+// method signature, leaving the ')' as the current token.
+func (p *Parser) parseFunctionTypeParams() []*ast.FunctionTypeParam {
+	params := []*ast.FunctionTypeParam{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		rest := p.peekTokenIs(token.ELLIPSIS)
+		if rest {
+			p.nextToken()
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		param := &ast.FunctionTypeParam{Name: p.curToken.Literal, Rest: rest}
+		if p.peekTokenIs(token.QUESTION) {
+			p.nextToken()
+			param.Optional = true
+		}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if param.Type = p.parseTypeAnnotation(); param.Type == nil {
+				return nil
+			}
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		if rest {
+			p.errorAt(p.peekToken, "A rest parameter must be last in a parameter list.")
+			return nil
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return params
+}
+
+// parseObjectType parses an object literal type, with curToken on '{'.
+// Members are separated by ';', ',' or a line break.
+func (p *Parser) parseObjectType() ast.TypeNode {
+	obj := &ast.ObjectType{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		if p.curTokenIs(token.LBRACKET) {
+			if obj.Index = p.parseIndexSignature(); obj.Index == nil {
+				return nil
+			}
+		} else {
+			member := p.parsePropertySignature()
+			if member == nil {
+				return nil
+			}
+			obj.Members = append(obj.Members, member)
+		}
+
+		switch {
+		case p.peekTokenIs(token.SEMICOLON), p.peekTokenIs(token.COMMA):
+			p.nextToken()
+		case p.peekTokenIs(token.RBRACE), p.peekToken.Pos.Line > p.curToken.Pos.Line:
+		default:
+			p.peekError(token.SEMICOLON)
+			return nil
+		}
+	}
+	p.nextToken()
+	p.finishNode(obj, obj.Token.Pos)
+
+	return obj
+}
+
+// parsePropertySignature parses a member of an object type: name: string,
+// age?: number, readonly id: number or area(): number.
+func (p *Parser) parsePropertySignature() *ast.PropertySignature {
+	start := p.curToken.Pos
+	member := &ast.PropertySignature{}
+
+	if p.curToken.Literal == "readonly" && !p.peekTokenIs(token.COLON) &&
+		!p.peekTokenIs(token.QUESTION) && !p.peekTokenIs(token.LPAREN) {
+		member.Readonly = true
+		p.nextToken()
+	}
+
+	switch {
+	case p.curTokenIs(token.IDENT), p.curTokenIs(token.STRING), p.curTokenIs(token.NUMBER), token.IsKeyword(p.curToken.Literal):
+		member.Token = p.curToken
+		member.Name = p.curToken.Literal
+	default:
+		p.errorAt(p.curToken, "expected a property name, got %s instead", p.curToken.Type)
+		return nil
+	}
+
+	if p.peekTokenIs(token.QUESTION) {
+		p.nextToken()
+		member.Optional = true
+	}
+
+	if p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.LT) {
+		p.nextToken()
+		fn := &ast.FunctionType{Token: p.curToken}
+		if p.curTokenIs(token.LT) {
+			if fn.TypeParams = p.parseTypeParams(); fn.TypeParams == nil || !p.expectPeek(token.LPAREN) {
+				return nil
+			}
+		}
+		if fn.Params = p.parseFunctionTypeParams(); fn.Params == nil {
+			return nil
+		}
+		fn.Return = &ast.NamedType{Token: p.curToken, Name: "any"}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if fn.Return = p.parseTypeAnnotation(); fn.Return == nil {
+				return nil
+			}
+		}
+		p.finishNode(fn, fn.Token.Pos)
+		member.Method = true
+		member.Type = fn
+	} else {
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		if member.Type = p.parseTypeAnnotation(); member.Type == nil {
+			return nil
+		}
+	}
+	p.finishNode(member, start)
+
+	return member
+}
+
+// parseIndexSignature parses [key: string]: T, with curToken on '['.
+func (p *Parser) parseIndexSignature() *ast.IndexSignature {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	index := &ast.IndexSignature{KeyName: p.curToken.Literal}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	if index.Key = p.parseTypeAnnotation(); index.Key == nil {
+		return nil
+	}
+	if !p.expectPeek(token.RBRACKET) || !p.expectPeek(token.COLON) {
+		return nil
+	}
+	if index.Value = p.parseTypeAnnotation(); index.Value == nil {
+		return nil
+	}
+
+	return index
+}
+
+// parseTypeofType parses typeof x or typeof x.y, with curToken on 'typeof'.
+func (p *Parser) parseTypeofType() ast.TypeNode {
+	t := &ast.TypeofType{Token: p.curToken}
+
+	p.nextToken()
+	if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.THIS) {
+		p.errorAt(p.curToken, "expected an identifier after typeof, got %s instead", p.curToken.Type)
+		return nil
+	}
+	t.Name = p.curToken.Literal
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		t.Name += "." + p.curToken.Literal
+	}
+	p.finishNode(t, t.Token.Pos)
+
+	return t
+}
+
+// parseKeyofType parses keyof T, with curToken on 'keyof'.
+func (p *Parser) parseKeyofType() ast.TypeNode {
+	t := &ast.KeyofType{Token: p.curToken}
+
+	p.nextToken()
+	if t.Type = p.parsePostfixType(); t.Type == nil {
+		return nil
+	}
+	p.finishNode(t, t.Token.Pos)
+
+	return t
+}
+
+// parseInterfaceDeclaration parses interface Name extends A, B { ... },
+// with curToken on 'interface'.
+func (p *Parser) parseInterfaceDeclaration() ast.Statement {
+	decl := &ast.InterfaceDeclaration{Token: p.curToken}
+
+	p.nextToken()
+	decl.Name = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if decl.TypeParams = p.parseTypeParams(); decl.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+		for {
+			base := p.parseTypeAnnotation()
+			if base == nil {
+				return nil
+			}
+			decl.Extends = append(decl.Extends, base)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	body, ok := p.parseObjectType().(*ast.ObjectType)
+	if !ok {
+		return nil
+	}
+	decl.Body = body
+
+	return decl
+}
+
+// parseTypeAliasDeclaration parses type Name<T> = Type; with curToken on
+// 'type'.
+func (p *Parser) parseTypeAliasDeclaration() ast.Statement {
+	decl := &ast.TypeAliasDeclaration{Token: p.curToken}
+
+	p.nextToken()
+	decl.Name = &ast.Identifier{Span: tokenSpan(p.curToken), Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.LT) {
+		p.nextToken()
+		if decl.TypeParams = p.parseTypeParams(); decl.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	if decl.Type = p.parseTypeAnnotation(); decl.Type == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return decl
+}
+
+// parseTypeParams parses the type parameters of a generic declaration,
+// <T, K extends keyof T = keyof T>, with curToken on '<'. It leaves the
+// '>' as the current token.
+func (p *Parser) parseTypeParams() []*ast.TypeParam {
+	params := []*ast.TypeParam{}
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		param := &ast.TypeParam{Token: p.curToken, Name: p.curToken.Literal}
+		if p.peekTokenIs(token.EXTENDS) {
+			p.nextToken()
+			if param.Constraint = p.parseTypeAnnotation(); param.Constraint == nil {
+				return nil
+			}
+		}
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			if param.Default = p.parseTypeAnnotation(); param.Default == nil {
+				return nil
+			}
+		}
+		p.finishNode(param, param.Token.Pos)
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+		// A trailing comma is allowed: <T,>
+		if p.peekTokenIs(token.GT) {
+			break
+		}
+	}
+
+	if !p.expectPeek(token.GT) {
+		return nil
+	}
+	return params
+}
+
+// parseTypeArguments parses <number, string> with curToken on '<',
+// leaving the '>' as the current token.
+func (p *Parser) parseTypeArguments() []ast.TypeNode {
+	args := []ast.TypeNode{}
+	for {
+		arg := p.parseTypeAnnotation()
+		if arg == nil {
+			return nil
+		}
+		args = append(args, arg)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.GT) {
+		return nil
+	}
+	return args
+}
+
+// callTypeArguments speculatively parses the '<' at curToken as the type
+// arguments of a call, f<number>(x), which must be followed by the call's
+// '('. If they are not, as in a < b, it backtracks and returns nil.
+func (p *Parser) callTypeArguments() []ast.TypeNode {
+	saved := p.save()
+	if args := p.parseTypeArguments(); args != nil && len(p.errors) == saved.errors && p.peekTokenIs(token.LPAREN) {
+		return args
+	}
+	p.restore(saved)
+	return nil
+}
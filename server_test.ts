@@ -4,24 +4,25 @@ import * as http from 'http';
 
 // 2. Create Server with Typed Handler
 const server: any = http.createServer(function (req: http.IncomingMessage, res: http.ServerResponse) {
-    console.log("Received request: " + req.method + " " + req.url);
+    console.log(`Received request: ${req.method} ${req.url}`);
 
     // 3. Routing Logic
-    if (req.url === '/favicon.ico') {
-        res.writeHead(204);  // No Content – tells browser "nothing here"
-        res.end();
-        return;
-    }
-    else if (req.url === '/') {
-        // 4. Response Headers and Body
-        res.writeHead(200, { 'Content-Type': 'text/html' });
-        res.end(`
+    switch (req.url) {
+        case '/favicon.ico':
+            res.writeHead(204);  // No Content – tells browser "nothing here"
+            res.end();
+            return;
+        case '/':
+            // 4. Response Headers and Body
+            res.writeHead(200, { 'Content-Type': 'text/html' });
+            res.end(`
   <h1>Welcome to TS Engine Server!</h1>
   <button onclick="window.location.href = '/home'">Go to /home</button>
 `);
-    }else if (req.url === '/home') {
-        res.writeHead(200, { 'Content-Type': 'text/html' });
-        res.end(`<!DOCTYPE html>
+            break;
+        case '/home':
+            res.writeHead(200, { 'Content-Type': 'text/html' });
+            res.end(`<!DOCTYPE html>
 <html lang="en">
 <head>
   <meta charset="UTF-8">
@@ -80,19 +81,21 @@ const server: any = http.createServer(function (req: http.IncomingMessage, res:
 </script>
 </body>
 </html>`);
-    } else if (req.url === '/json') {
-        res.writeHead(200, { 'Content-Type': 'application/json' });
-        res.end('{ "status": "ok", "engine": "ts-engine" }');
-    } else {
-        res.writeHead(404, { 'Content-Type': 'text/plain' });
-        res.end('Page Not Found');
+            break;
+        case '/json':
+            res.writeHead(200, { 'Content-Type': 'application/json' });
+            res.end('{ "status": "ok", "engine": "ts-engine" }');
+            break;
+        default:
+            res.writeHead(404, { 'Content-Type': 'text/plain' });
+            res.end('Page Not Found');
     }
 });
 
 // 5. Start Server
-// Note: This blocks the execution loop.
+// Note: The server keeps the event loop running until the process is stopped.
 console.log("Starting server on http://localhost:3000");
 server.listen(3000, function () {
-    // Callback support (executed before blocking)
+    // Runs on the event loop once the server is listening
     console.log("Server is listening!");
 });
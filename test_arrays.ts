@@ -5,3 +5,17 @@ console.log(person[1]); // Output: 30
 
 
 
+
+let scores: number[] = [72, 95, 58, 88];
+scores.push(64);
+console.log(scores.length); // Output: 5
+
+let passed: number[] = scores.filter((s: number): boolean => s >= 60);
+let curved: number[] = passed.map((s: number): number => s + 5);
+let total: number = curved.reduce((sum: number, s: number): number => sum + s, 0);
+console.log(curved, total); // Output: [77, 100, 93, 69] 339
+
+let ranked: number[] = scores.toSorted((a: number, b: number): number => b - a);
+console.log(ranked.join(" > "), scores.at(-1)); // Output: 95 > 88 > 72 > 64 > 58 64
+console.log(scores.find((s: number): boolean => s > 90), scores.includes(58)); // Output: 95 true
+console.log(Array.isArray(scores), Array.from("abc"), [[1, 2], [3]].flat()); // Output: true [a, b, c] [1, 2, 3]
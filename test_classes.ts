@@ -0,0 +1,41 @@
+// test_classes.ts - Constructors and the objects new gives
+
+class Empty {
+    constructor() {}
+}
+let e: Empty = new Empty();
+console.log(e instanceof Empty); // Output: true
+
+class Point {
+    constructor(public x: number, public y: number) {}
+
+    sum(): number {
+        return this.x + this.y;
+    }
+}
+let p: Point = new Point(1, 2);
+console.log(p.x, p.y, p.sum()); // Output: 1 2 3
+
+// The last statement of a constructor is not its result, only a return is
+class Box {
+    items: any;
+
+    constructor(items: any) {
+        this.items = items;
+    }
+
+    first(): any {
+        return this.items[0];
+    }
+}
+let box: Box = new Box([1, 2]);
+console.log(box instanceof Box, box.first()); // Output: true 1
+
+class Singleton {
+    static instance: any = { shared: true };
+
+    constructor() {
+        return Singleton.instance;
+    }
+}
+console.log(new Singleton() === Singleton.instance); // Output: true
@@ -0,0 +1,138 @@
+// test_coercion.js - Conformance table for type coercion, equality and comparison
+// Each row is [expression, result, expected]; rows that do not match are printed.
+
+let withValueOf = { valueOf: function () { return 42; } };
+let withToString = { toString: function () { return "7"; } };
+let both = { valueOf: function () { return 1; }, toString: function () { return "two"; } };
+let obj = {};
+let arr = [1, 2];
+let cyclic = [1];
+cyclic[1] = cyclic;
+
+let rows = [
+    // Abstract equality
+    ["null == undefined", null == undefined, true],
+    ["null == 0", null == 0, false],
+    ["undefined == 0", undefined == 0, false],
+    ["null == false", null == false, false],
+    ["1 == '1'", 1 == "1", true],
+    ["0 == ''", 0 == "", true],
+    ["0 == '0'", 0 == "0", true],
+    ["'' == '0'", "" == "0", false],
+    ["1 == true", 1 == true, true],
+    ["2 == true", 2 == true, false],
+    ["'1' == true", "1" == true, true],
+    ["'' == false", "" == false, true],
+    ["NaN == NaN", NaN == NaN, false],
+    ["[1] == 1", [1] == 1, true],
+    ["[1, 2] == '1,2'", [1, 2] == "1,2", true],
+    ["[] == ''", [] == "", true],
+    ["[] == false", [] == false, true],
+    ["[0] == false", [0] == false, true],
+    ["obj == '[object Object]'", obj == "[object Object]", true],
+    ["obj == obj", obj == obj, true],
+    ["{} == {}", {} == {}, false],
+    ["withValueOf == 42", withValueOf == 42, true],
+    ["' \\n 12 \\t' == 12", " \n 12 \t" == 12, true],
+    ["'0x1f' == 31", "0x1f" == 31, true],
+    ["'1e3' == 1000", "1e3" == 1000, true],
+    ["'abc' != 0", "abc" != 0, true],
+
+    // Strict equality
+    ["1 === 1", 1 === 1, true],
+    ["1 === '1'", 1 === "1", false],
+    ["'a' === 'a'", "a" === "a", true],
+    ["null === undefined", null === undefined, false],
+    ["undefined === undefined", undefined === undefined, true],
+    ["NaN === NaN", NaN === NaN, false],
+    ["0 === -0", 0 === -0, true],
+    ["arr === arr", arr === arr, true],
+    ["[1] === [1]", [1] === [1], false],
+
+    // Relational comparison
+    ["'a' < 'b'", "a" < "b", true],
+    ["'B' < 'a'", "B" < "a", true],
+    ["'10' < '9'", "10" < "9", true],
+    ["'10' < 9", "10" < 9, false],
+    ["'abc' < 'abcd'", "abc" < "abcd", true],
+    ["'b' >= 'b'", "b" >= "b", true],
+    ["null >= 0", null >= 0, true],
+    ["undefined >= 0", undefined >= 0, false],
+    ["NaN <= NaN", NaN <= NaN, false],
+    ["'x' < 1", "x" < 1, false],
+    ["'x' >= 1", "x" >= 1, false],
+    ["[2] > 1", [2] > 1, true],
+    ["true > false", true > false, true],
+    ["withValueOf > 41", withValueOf > 41, true],
+
+    // Addition
+    ["1 + '2'", 1 + "2", "12"],
+    ["'1' + 2 + 3", "1" + 2 + 3, "123"],
+    ["1 + 2 + '3'", 1 + 2 + "3", "33"],
+    ["1 + true", 1 + true, 2],
+    ["1 + null", 1 + null, 1],
+    ["'a' + null", "a" + null, "anull"],
+    ["'a' + undefined", "a" + undefined, "aundefined"],
+    ["[1, 2] + [3]", [1, 2] + [3], "1,23"],
+    ["[] + []", [] + [], ""],
+    ["[] + {}", [] + {}, "[object Object]"],
+    ["[null, undefined] + ''", [null, undefined] + "", ","],
+    ["withValueOf + 1", withValueOf + 1, 43],
+    ["withToString + 1", withToString + 1, "71"],
+    ["both + ''", both + "", "1"],
+    ["`${both}`", `${both}`, "two"],
+    ["0.1 + 0.2 + ''", 0.1 + 0.2 + "", "0.30000000000000004"],
+
+    // Other arithmetic converts to numbers
+    ["'6' * '7'", "6" * "7", 42],
+    ["'10' - 4", "10" - 4, 6],
+    ["'8' / true", "8" / true, 8],
+    ["null * 5", null * 5, 0],
+    ["withToString * 2", withToString * 2, 14],
+    ["'2' ** '3'", "2" ** "3", 8],
+    ["'12' >> 2", "12" >> 2, 3],
+    ["+'3.5'", +"3.5", 3.5],
+    ["+''", +"", 0],
+    ["+true", +true, 1],
+    ["+[]", +[], 0],
+    ["+['5']", +["5"], 5],
+    ["-'4'", -"4", -4],
+    ["~'5'", ~"5", -6],
+
+    // Truthiness
+    ["!0", !0, true],
+    ["!''", !"", true],
+    ["!NaN", !NaN, true],
+    ["!'0'", !"0", false],
+    ["![]", ![], false],
+    ["!{}", !{}, false],
+    ["0 || 'fallback'", 0 || "fallback", "fallback"],
+    ["'' || 'fallback'", "" || "fallback", "fallback"],
+    ["1 && 'second'", 1 && "second", "second"],
+    ["'' && 'second'", "" && "second", ""],
+
+    // An array that contains itself joins as an empty string there
+    ["cyclic.join()", cyclic.join(), "1,"],
+    ["'' + cyclic", "" + cyclic, "1,"],
+    ["cyclic == '1,'", cyclic == "1,", true],
+    ["[cyclic, 2].join('-')", [cyclic, 2].join("-"), "1,-2"]
+];
+
+let failed = 0;
+for (const [expression, result, expected] of rows) {
+    if (result !== expected) {
+        console.log("FAIL: " + expression + " gave " + result + ", expected " + expected);
+        failed++;
+    }
+}
+
+// NaN is not equal to itself, so these rows check for it separately
+let nans = [["+'abc'", +"abc"], ["+undefined", +undefined], ["'a' * 1", "a" * 1], ["+'1_000'", +"1_000"], ["+'0x'", +"0x"]];
+for (const [expression, result] of nans) {
+    if (!Number.isNaN(result)) {
+        console.log("FAIL: " + expression + " gave " + result + ", expected NaN");
+        failed++;
+    }
+}
+
+console.log(failed == 0 ? "All coercion checks passed" : failed + " coercion checks failed");
@@ -1,10 +1,28 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position is a location in the source text. Line and Column are 1-based
+// (Column counts characters, not bytes); Offset is the 0-based byte offset.
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character
+	End     Position // position just past the last character
 }
 
 const (
@@ -13,9 +31,18 @@ const (
 
 	// Identifiers + literals
 	IDENT  = "IDENT"  // add, foobar, x, y, ...
-	INT    = "INT"    // 1343456
+	NUMBER = "NUMBER" // 1343456, 0.5, 1e3, 0xff
 	STRING = "STRING" // "foobar"
 
+	// Template literals: `text` without substitutions, or split around
+	// them as `head${, }middle${ and }tail`
+	TEMPLATE        = "TEMPLATE"
+	TEMPLATE_HEAD   = "TEMPLATE_HEAD"
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE"
+	TEMPLATE_TAIL   = "TEMPLATE_TAIL"
+
+	PRIVATE_NAME = "PRIVATE_NAME" // #count
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -25,21 +52,61 @@ const (
 	SLASH    = "/"
 	MOD      = "%"
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+
+	// The lexer reads '>' on its own, so that Array<Array<T>> closes two
+	// type argument lists. The parser joins it with what follows into
+	// these operators in expressions.
+	GT_EQ                       = ">="
+	SHIFT_RIGHT                 = ">>"
+	UNSIGNED_SHIFT_RIGHT        = ">>>"
+	SHIFT_RIGHT_ASSIGN          = ">>="
+	UNSIGNED_SHIFT_RIGHT_ASSIGN = ">>>="
+
+	EXPONENT       = "**"
+	CARET          = "^"
+	TILDE          = "~"
+	SHIFT_LEFT     = "<<"
+	NULLISH        = "??"
+	OPTIONAL_CHAIN = "?."
 
 	EQ            = "=="
 	NOT_EQ        = "!="
 	EQ_STRICT     = "==="
 	NOT_EQ_STRICT = "!=="
+	ARROW         = "=>"
 	AND           = "&&"
 	OR            = "||"
+	AMPERSAND     = "&"
+	PIPE          = "|"
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	MOD_ASSIGN      = "%="
+	AND_ASSIGN      = "&&="
+	OR_ASSIGN       = "||="
+	NULLISH_ASSIGN  = "??="
+
+	EXPONENT_ASSIGN   = "**="
+	BIT_AND_ASSIGN    = "&="
+	BIT_OR_ASSIGN     = "|="
+	BIT_XOR_ASSIGN    = "^="
+	SHIFT_LEFT_ASSIGN = "<<="
+
+	INCREMENT = "++"
+	DECREMENT = "--"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	DOT       = "."
+	ELLIPSIS  = "..."
 	COLON     = ":"
+	QUESTION  = "?"
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -64,6 +131,32 @@ const (
 	IMPORT   = "IMPORT"
 	FROM     = "FROM"
 	AS       = "AS"
+	ASYNC    = "ASYNC"
+	THIS     = "THIS"
+	WHILE    = "WHILE"
+	DO       = "DO"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	SWITCH   = "SWITCH"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	NEW      = "NEW"
+	CLASS    = "CLASS"
+	EXTENDS  = "EXTENDS"
+	SUPER    = "SUPER"
+	ENUM     = "ENUM"
+
+	INSTANCEOF = "INSTANCEOF"
+	TYPEOF     = "TYPEOF"
+	NULL       = "NULL"
+	VOID       = "VOID"
+	DELETE     = "DELETE"
 )
 
 var keywords = map[string]TokenType{
@@ -82,6 +175,32 @@ var keywords = map[string]TokenType{
 	"import":   IMPORT,
 	"from":     FROM,
 	"as":       AS,
+	"async":    ASYNC,
+	"this":     THIS,
+	"while":    WHILE,
+	"do":       DO,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"switch":   SWITCH,
+	"case":     CASE,
+	"default":  DEFAULT,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"new":      NEW,
+	"class":    CLASS,
+	"extends":  EXTENDS,
+	"super":    SUPER,
+	"enum":     ENUM,
+
+	"instanceof": INSTANCEOF,
+	"typeof":     TYPEOF,
+	"null":       NULL,
+	"void":       VOID,
+	"delete":     DELETE,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -90,3 +209,10 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// IsKeyword reports whether ident is a reserved word. Keywords may still be
+// used as property names, as in promise.catch or { new: 1 }.
+func IsKeyword(ident string) bool {
+	_, ok := keywords[ident]
+	return ok
+}
@@ -21,14 +21,25 @@ declare module "http" {
 declare function require(moduleName: string): any;
 
 // Global fetch support
-declare function fetch(url: string): {
+declare function fetch(url: string): Promise<{
     status: number;
     ok: boolean;
     statusText: string;
-};
+    text(): Promise<string>;
+    json(): Promise<any>;
+}>;
 
 // Console support
 interface Console {
     log(...args: any[]): void;
 }
 declare var console: Console;
+
+// Timers
+declare function setTimeout(callback: (...args: any[]) => void, ms?: number, ...args: any[]): number;
+declare function setInterval(callback: (...args: any[]) => void, ms?: number, ...args: any[]): number;
+declare function setImmediate(callback: (...args: any[]) => void, ...args: any[]): number;
+declare function clearTimeout(id?: number): void;
+declare function clearInterval(id?: number): void;
+declare function clearImmediate(id?: number): void;
+declare function queueMicrotask(callback: () => void): void;
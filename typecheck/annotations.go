@@ -0,0 +1,329 @@
+package typecheck
+
+import (
+	"strings"
+	"ts-engine/ast"
+)
+
+// annotation resolves the type annotation of a variable or parameter.
+func (c *checker) annotation(ident *ast.Identifier) Type {
+	if t, ok := c.annotations[ident]; ok {
+		return t
+	}
+	t := c.resolveType(ident.Type)
+	c.annotations[ident] = t
+	return t
+}
+
+// resolveType converts a type annotation to a Type.
+func (c *checker) resolveType(node ast.TypeNode) Type {
+	switch node := node.(type) {
+	case nil:
+		return Any
+
+	case *ast.NamedType:
+		return c.resolveName(node, nil)
+
+	case *ast.GenericType:
+		args := make([]Type, len(node.Args))
+		for i, arg := range node.Args {
+			args[i] = c.resolveType(arg)
+		}
+		switch {
+		case node.Base.Name == "Array" && len(args) == 1:
+			return &Array{Elem: args[0]}
+		case node.Base.Name == "Promise" && len(args) == 1:
+			return &Promise{Value: args[0]}
+		}
+		return c.resolveName(node.Base, args)
+
+	case *ast.ArrayType:
+		return &Array{Elem: c.resolveType(node.Elem)}
+
+	case *ast.TupleType:
+		tuple := &Tuple{}
+		for _, el := range node.Elems {
+			tuple.Elems = append(tuple.Elems, c.resolveType(el))
+		}
+		return tuple
+
+	case *ast.FunctionType:
+		outer := c.scope
+		c.scope = newScope(outer)
+		defer func() { c.scope = outer }()
+		fn := &Function{TypeParams: c.typeParams(node.TypeParams)}
+		fn.Return = c.resolveType(node.Return)
+		for _, param := range node.Params {
+			if param.Rest {
+				fn.Rest = c.restElement(node, c.resolveType(param.Type))
+				break
+			}
+			fn.Params = append(fn.Params, Param{Name: param.Name, Type: c.resolveType(param.Type), Optional: param.Optional})
+		}
+		return fn
+
+	case *ast.ObjectType:
+		obj := &Object{Props: map[string]Type{}}
+		c.addMembers(obj, node)
+		return obj
+
+	case *ast.LiteralType:
+		switch value := node.Value.(type) {
+		case *ast.StringLiteral:
+			return &Literal{Value: value.Value, Base: String}
+		case *ast.Boolean:
+			return &Literal{Value: value.Value, Base: Boolean}
+		case *ast.NumberLiteral:
+			return &Literal{Value: value.Value, Base: Number}
+		case *ast.PrefixExpression:
+			return &Literal{Value: -value.Right.(*ast.NumberLiteral).Value, Base: Number}
+		}
+		return Any
+
+	case *ast.UnionType:
+		types := make([]Type, len(node.Types))
+		for i, member := range node.Types {
+			types[i] = c.resolveType(member)
+		}
+		return unionOf(types...)
+
+	case *ast.IntersectionType:
+		return c.intersection(node)
+
+	case *ast.TypePredicate:
+		c.resolveType(node.Type)
+		return Boolean
+
+	case *ast.IndexedAccessType:
+		return c.indexedAccess(c.resolveType(node.Object), c.resolveType(node.Index), node.Index)
+
+	case *ast.KeyofType:
+		return keyof(c.resolveType(node.Type))
+
+	case *ast.TypeofType:
+		path := strings.Split(node.Name, ".")
+		if path[0] == "this" {
+			return c.this
+		}
+		sym := c.scope.lookup(path[0])
+		if sym == nil {
+			c.errorAt(node, "Cannot find name '%s'.", path[0])
+			return Any
+		}
+		t := sym.typ
+		for _, name := range path[1:] {
+			t = c.property(t, name, node)
+		}
+		return t
+	}
+
+	return Any
+}
+
+// indexedAccess resolves T[K], the type of the properties of T named by
+// K: a string literal type or a union of them, such as keyof T. Numbers
+// index arrays and tuples. Other keys, such as a type parameter, give any.
+func (c *checker) indexedAccess(obj, key Type, node ast.Node) Type {
+	var types []Type
+	for _, k := range members(key) {
+		lit, isLiteral := k.(*Literal)
+		switch {
+		case isLiteral && lit.Base == String:
+			types = append(types, c.property(obj, lit.Value.(string), node))
+		case widen(k) == Number:
+			switch obj := obj.(type) {
+			case *Array:
+				types = append(types, obj.Elem)
+			case *Tuple:
+				types = append(types, unionOf(obj.Elems...))
+			default:
+				return Any
+			}
+		default:
+			return Any
+		}
+	}
+	return unionOf(types...)
+}
+
+// keyof resolves keyof T: the names of the properties of an object type as
+// a union of string literal types, or string if it has an index signature.
+// Keys of other types, such as a type parameter, are taken to be strings.
+func keyof(t Type) Type {
+	obj, ok := t.(*Object)
+	if !ok || obj.index() != nil {
+		return String
+	}
+	var names []Type
+	for _, name := range obj.properties() {
+		names = append(names, &Literal{Value: name, Base: String})
+	}
+	return unionOf(names...)
+}
+
+// intersection resolves A & B. An intersection of object types is an
+// object with the properties of all of them; of other types, the one they
+// have in common, or never if they have none.
+func (c *checker) intersection(node *ast.IntersectionType) Type {
+	types := make([]Type, len(node.Types))
+	objects := true
+	for i, member := range node.Types {
+		types[i] = c.resolveType(member)
+		if types[i] == Any {
+			return Any
+		}
+		if _, ok := types[i].(*Object); !ok {
+			objects = false
+		}
+	}
+
+	if objects {
+		obj := &Object{Name: node.String(), Props: map[string]Type{}}
+		for _, t := range types {
+			obj.inherit(t.(*Object))
+		}
+		return obj
+	}
+
+	result := types[0]
+	for _, t := range types[1:] {
+		switch {
+		case assignable(result, t):
+		case assignable(t, result):
+			result = t
+		default:
+			return Never
+		}
+	}
+	return result
+}
+
+// resolveName resolves a type name: a primitive, a class, an enum member
+// such as Color.Red, or a type from a module such as http.IncomingMessage,
+// which is not known statically. A
+// generic class or type is instantiated with the type arguments args.
+func (c *checker) resolveName(node *ast.NamedType, args []Type) Type {
+	if p, ok := primitives[node.Name]; ok {
+		return p
+	}
+	switch node.Name {
+	case "object", "Object", "Function":
+		return Any
+	case "Promise":
+		return &Promise{Value: Any}
+	case "Array":
+		return &Array{Elem: Any}
+	case "this":
+		return c.this
+	}
+	if enumName, member, ok := strings.Cut(node.Name, "."); ok {
+		// Color.Red, the type of a member of an enum
+		if nt := c.scope.lookupType(enumName); nt != nil {
+			if enum, ok := nt.typ.(*Enum); ok {
+				if m, ok := enum.member(member); ok {
+					return m
+				}
+				c.errorAt(node, "Namespace '%s' has no exported member '%s'.", enumName, member)
+			}
+		}
+		return Any
+	}
+
+	if nt := c.scope.lookupType(node.Name); nt != nil {
+		t := c.resolveNamed(nt)
+		if nt.resolving {
+			return c.deferTypeArgs(&nt.pending, node.Name, nt.params, t, args, node)
+		}
+		return c.applyTypeArgs(node.Name, nt.params, t, args, node)
+	}
+	if sym := c.scope.lookup(node.Name); sym != nil {
+		if cls, ok := sym.typ.(*Class); ok {
+			if cls.declaring {
+				return c.deferTypeArgs(&cls.pending, node.Name, cls.TypeParams, cls.Instance, args, node)
+			}
+			return c.applyTypeArgs(node.Name, cls.TypeParams, cls.Instance, args, node)
+		}
+	}
+	c.errorAt(node, "Cannot find name '%s'.", node.Name)
+	return Any
+}
+
+// addMembers adds the members of an object type or interface body to obj.
+func (c *checker) addMembers(obj *Object, body *ast.ObjectType) {
+	for _, member := range body.Members {
+		obj.Props[member.Name] = c.resolveType(member.Type)
+		if member.Optional {
+			setFlag(&obj.Optional, member.Name)
+		}
+		if member.Readonly {
+			setFlag(&obj.Readonly, member.Name)
+		}
+	}
+	if body.Index != nil {
+		c.resolveType(body.Index.Key)
+		obj.Index = c.resolveType(body.Index.Value)
+	}
+}
+
+// resolveNamed resolves an interface or type alias, in the scope it was
+// declared in. An interface's type exists before its members are resolved,
+// so that they can refer to it, as in interface Node { next?: Node }.
+func (c *checker) resolveNamed(nt *namedType) Type {
+	if nt.typ != nil {
+		return nt.typ
+	}
+	if nt.resolving {
+		// A type alias that refers to itself
+		return Any
+	}
+
+	outer := c.scope
+	c.scope = newScope(nt.scope)
+	defer func() { c.scope = outer }()
+
+	// A generic type is resolved with its type parameters unbound
+	switch decl := nt.decl.(type) {
+	case *ast.InterfaceDeclaration:
+		nt.params = c.typeParams(decl.TypeParams)
+	case *ast.TypeAliasDeclaration:
+		nt.params = c.typeParams(decl.TypeParams)
+	}
+	var args []Type
+	for _, param := range nt.params {
+		args = append(args, param)
+	}
+
+	switch decl := nt.decl.(type) {
+	case *ast.InterfaceDeclaration:
+		obj := &Object{Name: decl.Name.Value, Args: args, Props: map[string]Type{}}
+		nt.typ = obj
+		nt.resolving = true
+		c.addMembers(obj, decl.Body)
+		for _, base := range decl.Extends {
+			switch t := c.resolveType(base).(type) {
+			case *Object:
+				obj.inherit(t)
+			default:
+				if t != Any {
+					c.errorAt(base, "An interface can only extend an object type or intersection of object types with statically known members.")
+				}
+			}
+		}
+		nt.resolving = false
+		for _, fill := range nt.pending {
+			fill()
+		}
+
+	case *ast.TypeAliasDeclaration:
+		nt.resolving = true
+		t := c.resolveType(decl.Type)
+		nt.resolving = false
+		if _, literal := decl.Type.(*ast.ObjectType); literal {
+			t.(*Object).Name = decl.Name.Value
+			t.(*Object).Args = args
+		}
+		nt.typ = t
+	}
+
+	return nt.typ
+}
@@ -0,0 +1,159 @@
+package typecheck
+
+// arrayProperty is the type of the property name of an array whose
+// elements are of type elem: length, or one of the methods of
+// Array.prototype.
+func arrayProperty(elem Type, name string) (Type, bool) {
+	array := &Array{Elem: elem}
+	index := Param{Name: "index", Type: Number}
+	optional := func(name string, t Type) Param { return Param{Name: name, Type: t, Optional: true} }
+	// callback is the type of the function that forEach, map and the like
+	// call with each element, its index and the array.
+	callback := func(ret Type) *Function {
+		return &Function{
+			Params: []Param{{Name: "value", Type: elem}, index, {Name: "array", Type: array}},
+			Return: ret,
+		}
+	}
+	iterate := func(ret Type, result Type, typeParams ...*TypeParam) *Function {
+		return &Function{
+			TypeParams: typeParams,
+			Params:     []Param{{Name: "callbackfn", Type: callback(ret)}, optional("thisArg", Any)},
+			Return:     result,
+		}
+	}
+	compare := optional("compareFn", &Function{
+		Params: []Param{{Name: "a", Type: elem}, {Name: "b", Type: elem}},
+		Return: Number,
+	})
+	search := []Param{{Name: "searchElement", Type: elem}, optional("fromIndex", Number)}
+
+	switch name {
+	case "length":
+		return Number, true
+	case "push", "unshift":
+		return &Function{Rest: elem, Return: Number}, true
+	case "pop", "shift":
+		return &Function{Return: unionOf(elem, Undefined)}, true
+	case "at":
+		return &Function{Params: []Param{index}, Return: unionOf(elem, Undefined)}, true
+	case "splice", "toSpliced":
+		return &Function{
+			Params: []Param{{Name: "start", Type: Number}, optional("deleteCount", Number)},
+			Rest:   elem,
+			Return: array,
+		}, true
+	case "sort", "toSorted":
+		return &Function{Params: []Param{compare}, Return: array}, true
+	case "reverse", "toReversed":
+		return &Function{Return: array}, true
+	case "fill":
+		return &Function{
+			Params: []Param{{Name: "value", Type: elem}, optional("start", Number), optional("end", Number)},
+			Return: array,
+		}, true
+	case "slice":
+		return &Function{Params: []Param{optional("start", Number), optional("end", Number)}, Return: array}, true
+	case "concat":
+		return &Function{Rest: unionOf(elem, array), Return: array}, true
+	case "with":
+		return &Function{Params: []Param{index, {Name: "value", Type: elem}}, Return: array}, true
+	case "join":
+		return &Function{Params: []Param{optional("separator", String)}, Return: String}, true
+	case "toString":
+		return &Function{Return: String}, true
+	case "indexOf", "lastIndexOf":
+		return &Function{Params: search, Return: Number}, true
+	case "includes":
+		return &Function{Params: search, Return: Boolean}, true
+	case "flat":
+		return &Function{Params: []Param{optional("depth", Number)}, Return: &Array{Elem: flatElem(elem)}}, true
+	case "forEach":
+		return iterate(Void, Void), true
+	case "some", "every":
+		return iterate(Unknown, Boolean), true
+	case "filter":
+		return iterate(Unknown, array), true
+	case "find", "findLast":
+		return iterate(Unknown, unionOf(elem, Undefined)), true
+	case "findIndex", "findLastIndex":
+		return iterate(Unknown, Number), true
+	case "map":
+		u := &TypeParam{Name: "U"}
+		return iterate(u, &Array{Elem: u}, u), true
+	case "flatMap":
+		u := &TypeParam{Name: "U"}
+		return iterate(unionOf(u, &Array{Elem: u}), &Array{Elem: u}, u), true
+	case "reduce", "reduceRight":
+		// reduce<U = T>(callbackfn: (previousValue: U, currentValue: T,
+		// currentIndex: number, array: T[]) => U, initialValue?: U): U
+		u := &TypeParam{Name: "U", Default: elem}
+		reducer := &Function{
+			Params: []Param{
+				{Name: "previousValue", Type: u},
+				{Name: "currentValue", Type: elem},
+				{Name: "currentIndex", Type: Number},
+				{Name: "array", Type: array},
+			},
+			Return: u,
+		}
+		return &Function{
+			TypeParams: []*TypeParam{u},
+			Params:     []Param{{Name: "callbackfn", Type: reducer}, optional("initialValue", u)},
+			Return:     u,
+		}, true
+	}
+	return nil, false
+}
+
+// flatElem is the type of the elements of an array of elem flattened one
+// level: the elements of elem if it is an array, or elem itself.
+func flatElem(elem Type) Type {
+	var types []Type
+	for _, member := range members(elem) {
+		switch member := member.(type) {
+		case *Array:
+			types = append(types, member.Elem)
+		case *Tuple:
+			types = append(types, member.Elems...)
+		default:
+			types = append(types, member)
+		}
+	}
+	return unionOf(types...)
+}
+
+// arrayClass is the type of the Array global. new Array<number>(3) is a
+// number[], and new Array(3) an any[].
+func arrayClass() *Class {
+	anyArray := &Array{Elem: Any}
+	elem := &TypeParam{Name: "T", Default: Any}
+	t := &TypeParam{Name: "T"}
+	u := &TypeParam{Name: "U", Default: Any}
+	return &Class{
+		Name:       "Array",
+		TypeParams: []*TypeParam{elem},
+		Instance:   &Array{Elem: elem},
+		Statics: &Object{Props: map[string]Type{
+			"isArray": &Function{
+				Params: []Param{{Name: "arg", Type: Any}},
+				Return: Boolean,
+				Guard:  &Guard{Param: 0, Type: anyArray},
+			},
+			"from": &Function{
+				TypeParams: []*TypeParam{u},
+				Params: []Param{
+					{Name: "arrayLike", Type: Any},
+					{Name: "mapfn", Type: &Function{
+						Params: []Param{{Name: "v", Type: Any}, {Name: "k", Type: Number}},
+						Return: u,
+					}, Optional: true},
+				},
+				Return: &Array{Elem: u},
+			},
+			"of": &Function{TypeParams: []*TypeParam{t}, Rest: t, Return: &Array{Elem: t}},
+		}},
+		Construct: &Function{TypeParams: []*TypeParam{elem}, Rest: Any, Return: &Array{Elem: elem}},
+		Callable:  true,
+	}
+}
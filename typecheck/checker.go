@@ -0,0 +1,1829 @@
+// Package typecheck checks the types of a program before it is evaluated.
+// It infers the types of expressions from literals, annotations and the
+// builtins, and reports calls, returns and assignments that do not fit.
+package typecheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// Error is a type error, located at the offending source range.
+type Error struct {
+	Pos     token.Position
+	End     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+const typeMismatch = "Type '%s' is not assignable to type '%s'."
+
+// Check type checks a program and returns the errors found, in source
+// order.
+func Check(program *ast.Program) []*Error {
+	c := &checker{
+		source:      program.Source,
+		scope:       newScope(globals()),
+		this:        Any,
+		types:       map[ast.Expression]Type{},
+		annotations: map[*ast.Identifier]Type{},
+		signatures:  map[*ast.FunctionLiteral]*Function{},
+		classes:     map[*ast.ClassLiteral]*Class{},
+		exhaustive:  map[*ast.SwitchStatement]bool{},
+	}
+	c.hoistVars(program.Statements)
+	c.block(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Pos, c.errors[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.errors
+}
+
+type symbol struct {
+	typ      Type
+	constant bool
+
+	// narrows is the variable a symbol stands for where its type has been
+	// narrowed, as in the body of if (x !== null).
+	narrows *symbol
+
+	// A let, const or class is pending from the start of its block until
+	// its declaration, and may not be used then except from a function
+	// other than declaredIn, the one whose body declares it.
+	pending    bool
+	declaredIn *function
+}
+
+type scope struct {
+	vars  map[string]*symbol
+	types map[string]*namedType // interfaces and type aliases
+	paths map[string]Type       // narrowed properties, such as r.body
+	outer *scope
+}
+
+// namedType is an interface or type alias, resolved when first used, or
+// a type parameter.
+type namedType struct {
+	decl      ast.Statement
+	scope     *scope // where the names in the declaration are resolved
+	typ       Type
+	params    []*TypeParam // of a generic interface or type alias
+	resolving bool
+	pending   []func() // instances of it to fill in once resolved
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: map[string]*symbol{}, types: map[string]*namedType{}, paths: map[string]Type{}, outer: outer}
+}
+
+func (s *scope) declare(name string, t Type, constant bool) *symbol {
+	sym := &symbol{typ: t, constant: constant}
+	s.vars[name] = sym
+	return sym
+}
+
+func (s *scope) declareType(name string, decl ast.Statement) {
+	s.types[name] = &namedType{decl: decl, scope: s}
+}
+
+func (s *scope) lookupType(name string) *namedType {
+	for sc := s; sc != nil; sc = sc.outer {
+		if nt, ok := sc.types[name]; ok {
+			return nt
+		}
+	}
+	return nil
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for sc := s; sc != nil; sc = sc.outer {
+		if sym, ok := sc.vars[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// function is the context of the function whose body is being checked.
+type function struct {
+	declared  Type // declared return type (of the promise, if async), or nil
+	async     bool
+	returns   []Type
+	hasReturn bool // a return statement has a value
+
+	// constructor is set in the body of a class constructor, where the
+	// readonly fields of the class may be assigned.
+	constructor bool
+}
+
+type checker struct {
+	errors []*Error
+	source string // of the program, to quote in messages
+
+	scope  *scope
+	fn     *function
+	this   Type
+	class  *Class               // class whose members are being checked
+	static bool                 // checking a static member of class
+	ctor   *ast.FunctionLiteral // the constructor of class
+
+	// Results, so that each node is only checked once
+	types       map[ast.Expression]Type
+	annotations map[*ast.Identifier]Type
+	signatures  map[*ast.FunctionLiteral]*Function
+	classes     map[*ast.ClassLiteral]*Class
+
+	// exhaustive records the switches where a case or default matches
+	// every value the discriminant may have
+	exhaustive map[*ast.SwitchStatement]bool
+
+	// superCall is set once a super() call has been checked, so that a
+	// derived constructor without one can be reported
+	superCall bool
+
+	// skipsChain is set when a link of the optional chain being checked
+	// may be skipped, so that the chain may give undefined
+	skipsChain bool
+}
+
+func (c *checker) errorAt(node ast.Node, format string, a ...interface{}) {
+	c.errorSpan(node.Pos(), node.End(), format, a...)
+}
+
+// text is the source text of node, as written.
+func (c *checker) text(node ast.Node) string {
+	start, end := node.Pos().Offset, node.End().Offset
+	if !node.Pos().IsValid() || start >= end || end > len(c.source) {
+		return node.String()
+	}
+	return c.source[start:end]
+}
+
+func (c *checker) errorSpan(pos, end token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Pos: pos, End: end, Message: fmt.Sprintf(format, a...)})
+}
+
+// block checks a list of statements in the current scope, after declaring
+// the names they declare.
+func (c *checker) block(stmts []ast.Statement) {
+	c.hoist(stmts)
+	c.statements(stmts)
+}
+
+// statements checks a list of statements whose declarations have been
+// hoisted.
+func (c *checker) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+
+		// After if (x === null) return, x is not null
+		if stmt, ok := stmt.(*ast.ExpressionStatement); ok {
+			if ifExpr, ok := stmt.Expression.(*ast.IfExpression); ok && ifExpr.Alternative == nil && exits(ifExpr.Consequence) {
+				c.narrow(c.narrowing(ifExpr.Condition, false))
+			}
+		}
+	}
+}
+
+// exits reports whether a statement always ends in a return, throw, break
+// or continue, so that the statements after it are not reached.
+func exits(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	case *ast.BlockStatement:
+		return len(stmt.Statements) > 0 && exits(stmt.Statements[len(stmt.Statements)-1])
+	}
+	return false
+}
+
+// terminates reports whether a list of statements always ends in a
+// return or throw, so that the end of the function body it is in is not
+// reached. Loops without a condition, such as while (true), are taken not
+// to end.
+func (c *checker) terminates(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		if c.terminatesStatement(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) terminatesStatement(stmt ast.Node) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	case *ast.BlockStatement:
+		return c.terminates(stmt.Statements)
+	case *ast.ExpressionStatement:
+		return c.terminatesStatement(stmt.Expression)
+	case *ast.IfExpression:
+		return stmt.Alternative != nil && c.terminatesStatement(stmt.Consequence) && c.terminatesStatement(stmt.Alternative)
+	case *ast.LabeledStatement:
+		return c.terminatesStatement(stmt.Body)
+	case *ast.WhileStatement:
+		cond, ok := stmt.Condition.(*ast.Boolean)
+		return ok && cond.Value
+	case *ast.ForStatement:
+		return stmt.Condition == nil
+	case *ast.TryStatement:
+		if stmt.Finally != nil && c.terminates(stmt.Finally.Statements) {
+			return true
+		}
+		return c.terminates(stmt.Block.Statements) && (stmt.CatchBody == nil || c.terminates(stmt.CatchBody.Statements))
+	case *ast.SwitchStatement:
+		// Every clause returns, except for empty ones that fall through
+		if !c.exhaustive[stmt] || len(stmt.Cases) == 0 {
+			return false
+		}
+		for i, clause := range stmt.Cases {
+			if len(clause.Consequent) == 0 && i < len(stmt.Cases)-1 {
+				continue
+			}
+			if !c.terminates(clause.Consequent) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// nested checks a statement in a scope of its own.
+func (c *checker) nested(stmt ast.Statement) {
+	outer := c.scope
+	c.scope = newScope(outer)
+	if block, ok := stmt.(*ast.BlockStatement); ok {
+		c.block(block.Statements)
+	} else {
+		c.statement(stmt)
+	}
+	c.scope = outer
+}
+
+// hoist declares the types, classes, functions and variables of a block
+// before it is checked, so that they may be used further up. Types and
+// classes come first, so that annotations can name them.
+func (c *checker) hoist(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.InterfaceDeclaration:
+			c.scope.declareType(stmt.Name.Value, stmt)
+		case *ast.TypeAliasDeclaration:
+			c.scope.declareType(stmt.Name.Value, stmt)
+		case *ast.EnumDeclaration:
+			c.declareEnum(stmt)
+		}
+		if lit := declaredClass(stmt); lit != nil {
+			cls := newClass(lit.Name.Value)
+			c.classes[lit] = cls
+			c.pending(c.scope.declare(cls.Name, cls, false))
+		}
+	}
+	for _, stmt := range stmts {
+		if lit := declaredClass(stmt); lit != nil {
+			c.classTypeParams(c.classes[lit], lit)
+		}
+	}
+
+	c.redeclarations(stmts)
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.hoistVariable(stmt)
+		case *ast.DeclarationList:
+			for _, decl := range stmt.Declarations {
+				c.hoistVariable(decl)
+			}
+		case *ast.ExpressionStatement:
+			if lit, ok := stmt.Expression.(*ast.FunctionLiteral); ok && lit.Name != "" {
+				c.scope.declare(lit.Name, c.signature(lit), false)
+			}
+		}
+	}
+}
+
+// redeclarations reports the names that the let and const declarations of
+// a block declare more than once, at each declaration of them.
+func (c *checker) redeclarations(stmts []ast.Statement) {
+	declared := map[string][]*ast.Identifier{}
+	var names []string
+	for _, stmt := range stmts {
+		var decls []*ast.LetStatement
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			decls = []*ast.LetStatement{stmt}
+		case *ast.DeclarationList:
+			decls = stmt.Declarations
+		}
+		for _, decl := range decls {
+			if decl.Token.Type == token.VAR {
+				continue
+			}
+			idents := []*ast.Identifier{decl.Name}
+			if decl.Pattern != nil {
+				idents = ast.PatternNames(decl.Pattern)
+			}
+			for _, ident := range idents {
+				if declared[ident.Value] == nil {
+					names = append(names, ident.Value)
+				}
+				declared[ident.Value] = append(declared[ident.Value], ident)
+			}
+		}
+	}
+	for _, name := range names {
+		if idents := declared[name]; len(idents) > 1 {
+			for _, ident := range idents {
+				c.errorAt(ident, "Cannot redeclare block-scoped variable '%s'.", name)
+			}
+		}
+	}
+}
+
+// hoistVars declares the var variables of a function body or program in
+// its scope, since var is scoped to the function rather than the block.
+func (c *checker) hoistVars(stmts []ast.Statement) {
+	for _, name := range ast.VarNames(stmts) {
+		if _, ok := c.scope.vars[name.Value]; ok {
+			continue
+		}
+		var t Type = Any
+		if name.Type != nil {
+			t = c.annotation(name)
+		}
+		c.scope.declare(name.Value, t, false)
+	}
+}
+
+func (c *checker) pending(sym *symbol) {
+	sym.pending = true
+	sym.declaredIn = c.fn
+}
+
+// initialized reports whether the variable sym, used by node, has been
+// declared by the time it is used, and reports an error if not.
+func (c *checker) initialized(sym *symbol, node *ast.Identifier) bool {
+	if !sym.pending || sym.declaredIn != c.fn {
+		return true
+	}
+	if cls, ok := sym.typ.(*Class); ok && cls.Name == node.Value {
+		c.errorAt(node, "Class '%s' used before its declaration.", node.Value)
+	} else {
+		c.errorAt(node, "Block-scoped variable '%s' used before its declaration.", node.Value)
+	}
+	return false
+}
+
+func declaredClass(stmt ast.Statement) *ast.ClassLiteral {
+	if stmt, ok := stmt.(*ast.ExpressionStatement); ok {
+		if lit, ok := stmt.Expression.(*ast.ClassLiteral); ok && lit.Name != nil {
+			return lit
+		}
+	}
+	return nil
+}
+
+func newClass(name string) *Class {
+	instance := &Object{Name: name, Props: map[string]Type{}}
+	return &Class{
+		Name:      name,
+		Instance:  instance,
+		Statics:   &Object{Props: map[string]Type{}},
+		Construct: &Function{Return: instance},
+		declaring: true,
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt)
+
+	case *ast.DeclarationList:
+		for _, decl := range stmt.Declarations {
+			c.let(decl)
+		}
+
+	case *ast.ExpressionStatement:
+		if stmt.Expression != nil {
+			c.expr(stmt.Expression)
+		}
+
+	case *ast.ReturnStatement:
+		c.returnStatement(stmt)
+
+	case *ast.BlockStatement:
+		c.nested(stmt)
+
+	case *ast.WhileStatement:
+		c.expr(stmt.Condition)
+		c.narrowed(c.narrowing(stmt.Condition, true), func() { c.nested(stmt.Body) })
+
+	case *ast.DoWhileStatement:
+		c.nested(stmt.Body)
+		c.expr(stmt.Condition)
+
+	case *ast.ForStatement:
+		outer := c.scope
+		c.scope = newScope(outer)
+		if stmt.Init != nil {
+			c.statement(stmt.Init)
+		}
+		if stmt.Condition != nil {
+			c.expr(stmt.Condition)
+		}
+		if stmt.Update != nil {
+			c.expr(stmt.Update)
+		}
+		c.nested(stmt.Body)
+		c.scope = outer
+
+	case *ast.ForOfStatement:
+		elem := c.elementType(c.expr(stmt.Iterable), stmt.Iterable)
+		var variable ast.Expression = stmt.Variable
+		if stmt.Pattern != nil {
+			variable = stmt.Pattern
+		}
+		c.loopVariable(stmt.Declaration, variable, elem, stmt.Body)
+
+	case *ast.ForInStatement:
+		c.expr(stmt.Object)
+		c.loopVariable(stmt.Declaration, stmt.Variable, String, stmt.Body)
+
+	case *ast.SwitchStatement:
+		c.switchStatement(stmt)
+
+	case *ast.LabeledStatement:
+		c.statement(stmt.Body)
+
+	case *ast.ThrowStatement:
+		c.expr(stmt.Value)
+
+	case *ast.TryStatement:
+		c.nested(stmt.Block)
+		if stmt.CatchBody != nil {
+			outer := c.scope
+			c.scope = newScope(outer)
+			if stmt.CatchParam != nil {
+				c.scope.declare(stmt.CatchParam.Value, Any, false)
+			}
+			c.block(stmt.CatchBody.Statements)
+			c.scope = outer
+		}
+		if stmt.Finally != nil {
+			c.nested(stmt.Finally)
+		}
+
+	case *ast.ImportStatement:
+		c.scope.declare(stmt.Alias.Value, Any, true)
+
+	case *ast.InterfaceDeclaration:
+		c.resolveNamed(c.scope.types[stmt.Name.Value])
+
+	case *ast.TypeAliasDeclaration:
+		c.resolveNamed(c.scope.types[stmt.Name.Value])
+
+	case *ast.EnumDeclaration:
+		c.enumDeclaration(stmt)
+	}
+}
+
+// hoistVariable declares the variables of a let or const declaration,
+// which cannot be used until it runs, when their block is entered.
+func (c *checker) hoistVariable(stmt *ast.LetStatement) {
+	if stmt.Token.Type == token.VAR {
+		return
+	}
+	if stmt.Pattern != nil {
+		for _, name := range ast.PatternNames(stmt.Pattern) {
+			c.pending(c.scope.declare(name.Value, Any, stmt.Token.Type == token.CONST))
+		}
+		return
+	}
+	var t Type = Any
+	if stmt.Name.Type != nil {
+		t = c.annotation(stmt.Name)
+	}
+	c.pending(c.scope.declare(stmt.Name.Value, t, stmt.Token.Type == token.CONST))
+}
+
+func (c *checker) let(stmt *ast.LetStatement) {
+	if stmt.Pattern != nil {
+		c.destructuringDeclaration(stmt)
+		return
+	}
+
+	sym := c.declared(stmt.Token.Type, stmt.Name.Value)
+
+	var declared Type
+	if stmt.Name.Type != nil {
+		declared = c.annotation(stmt.Name)
+	}
+
+	if stmt.Value != nil {
+		t := c.expr(stmt.Value)
+		switch {
+		case declared != nil:
+			c.assign(stmt.Value, t, declared, typeMismatch)
+		case stmt.Token.Type == token.CONST:
+			declared = t
+		default:
+			declared = widen(t)
+		}
+	}
+
+	if declared != nil {
+		sym.typ = declared
+	}
+	sym.pending = false
+}
+
+// declared returns the symbol hoisted for a variable of a let, const or
+// var declaration, which for var may be in an outer block.
+func (c *checker) declared(kind token.TokenType, name string) *symbol {
+	sym, ok := c.scope.vars[name]
+	if !ok && kind == token.VAR {
+		sym = c.scope.lookup(name)
+		ok = sym != nil
+	}
+	if !ok {
+		sym = c.scope.declare(name, Any, kind == token.CONST)
+	}
+	return sym
+}
+
+// loopVariable checks the body of a for...of or for...in loop, with the
+// loop variable, or the variables of the pattern it unpacks each value
+// into, in scope.
+func (c *checker) loopVariable(decl token.Token, variable ast.Expression, t Type, body ast.Statement) {
+	outer := c.scope
+	c.scope = newScope(outer)
+	c.pattern(variable, t, func(target ast.Expression, t Type) {
+		if decl.Type == "" {
+			// An existing variable is assigned to
+			if dst := c.reference(target); !assignable(t, dst) {
+				c.errorAt(target, typeMismatch, t, dst)
+			}
+			return
+		}
+		c.scope.declare(target.(*ast.Identifier).Value, t, decl.Type == token.CONST)
+	})
+	c.nested(body)
+	c.scope = outer
+}
+
+// elementType is the type of the values a for...of loop iterates over.
+func (c *checker) elementType(t Type, node ast.Node) Type {
+	if _, ok := t.(*TypeParam); ok {
+		t = apparent(t)
+	}
+	switch t := t.(type) {
+	case *Array:
+		return t.Elem
+	case *Tuple:
+		return unionOf(t.Elems...)
+	}
+	if widen(t) == String || t == Any {
+		return widen(t)
+	}
+	c.errorAt(node, "Type '%s' is not an array type or a string type.", t)
+	return Any
+}
+
+func (c *checker) returnStatement(stmt *ast.ReturnStatement) {
+	if c.fn == nil {
+		return
+	}
+
+	var t Type = Void
+	if stmt.ReturnValue != nil {
+		t = c.expr(stmt.ReturnValue)
+		c.fn.hasReturn = true
+		// An async function may return a promise of its result
+		if p, ok := t.(*Promise); ok && c.fn.async {
+			t = p.Value
+		}
+	}
+	c.fn.returns = append(c.fn.returns, t)
+
+	if c.fn.declared != nil && stmt.ReturnValue != nil {
+		c.assign(stmt.ReturnValue, t, c.fn.declared, typeMismatch)
+	}
+}
+
+// assign checks that a value of type src, computed by node, may be stored
+// where dst is expected. Array and object literals are checked element by
+// element, so that errors point at the offending element. format reports
+// a mismatch of the whole value.
+func (c *checker) assign(node ast.Expression, src, dst Type, format string) {
+	switch node := node.(type) {
+	case *ast.ArrayLiteral:
+		switch dst := dst.(type) {
+		case *Tuple:
+			if hasSpread(node) {
+				if src := c.literalTuple(node); !assignable(src, dst) {
+					c.errorAt(node, format, src, dst)
+				}
+				return
+			}
+			if len(node.Elements) != len(dst.Elems) {
+				c.errorAt(node, format, c.literalTuple(node), dst)
+				return
+			}
+			for i, el := range node.Elements {
+				c.assign(el, c.types[el], dst.Elems[i], typeMismatch)
+			}
+			return
+		case *Array:
+			for _, el := range node.Elements {
+				c.assign(el, c.types[el], dst.Elem, typeMismatch)
+			}
+			return
+		}
+
+	case *ast.HashLiteral:
+		if dst, ok := dst.(*Object); ok {
+			c.assignObjectLiteral(node, src, dst)
+			return
+		}
+	}
+
+	// A literal is checked against the member of a union it fits
+	if union, ok := dst.(*Union); ok {
+		for _, member := range union.Types {
+			if c.fits(node, member) {
+				c.assign(node, src, member, format)
+				return
+			}
+		}
+	}
+
+	if !assignable(src, dst) {
+		c.errorAt(node, format, src, dst)
+	}
+}
+
+// fits reports whether assign would accept the value of node where dst is
+// expected. Unlike assignable, it sees the literal types of the elements
+// and properties of array and object literals, which their types widen.
+func (c *checker) fits(node ast.Expression, dst Type) bool {
+	switch node := node.(type) {
+	case *ast.ArrayLiteral:
+		switch dst := dst.(type) {
+		case *Tuple:
+			if hasSpread(node) {
+				return assignable(c.literalTuple(node), dst)
+			}
+			if len(node.Elements) != len(dst.Elems) {
+				return false
+			}
+			for i, el := range node.Elements {
+				if !c.fits(el, dst.Elems[i]) {
+					return false
+				}
+			}
+			return true
+		case *Array:
+			for _, el := range node.Elements {
+				if !c.fits(el, dst.Elem) {
+					return false
+				}
+			}
+			return true
+		}
+
+	case *ast.HashLiteral:
+		if dst, ok := dst.(*Object); ok {
+			given := map[string]bool{}
+			unknown := false
+			for _, key := range node.Keys {
+				if spread, ok := key.(*ast.SpreadElement); ok {
+					src := c.spreadProperties(spread)
+					if src == nil {
+						unknown = true
+						continue
+					}
+					for _, name := range src.properties() {
+						given[name] = true
+						have, _ := src.Lookup(name)
+						if want, ok := dst.Lookup(name); ok && !assignable(have, want) {
+							return false
+						}
+					}
+					continue
+				}
+				name, ok := propertyName(key)
+				if !ok {
+					continue
+				}
+				given[name] = true
+				want, ok := dst.Lookup(name)
+				if !ok || !c.fits(node.Pairs[key], want) {
+					return false
+				}
+			}
+			for _, name := range dst.properties() {
+				if !given[name] && !unknown && dst.required(name) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	if union, ok := dst.(*Union); ok {
+		for _, member := range union.Types {
+			if c.fits(node, member) {
+				return true
+			}
+		}
+		return false
+	}
+	return assignable(c.types[node], dst)
+}
+
+// assignObjectLiteral checks an object literal against an object type: it
+// must have every property of the type, and no others.
+func (c *checker) assignObjectLiteral(node *ast.HashLiteral, src Type, dst *Object) {
+	given := map[string]bool{}
+	unknown := false
+	for _, key := range node.Keys {
+		// Spread properties are checked, but may include ones dst does not
+		// have
+		if spread, ok := key.(*ast.SpreadElement); ok {
+			src := c.spreadProperties(spread)
+			if src == nil {
+				unknown = true
+				continue
+			}
+			for _, name := range src.properties() {
+				given[name] = true
+				have, _ := src.Lookup(name)
+				if want, ok := dst.Lookup(name); ok && !assignable(have, want) {
+					c.errorAt(spread, typeMismatch, have, want)
+				}
+			}
+			continue
+		}
+
+		name, ok := propertyName(key)
+		if !ok {
+			continue
+		}
+		given[name] = true
+
+		want, ok := dst.Lookup(name)
+		if !ok {
+			c.errorAt(key, "Object literal may only specify known properties, and '%s' does not exist in type '%s'.", name, dst)
+			continue
+		}
+		value := node.Pairs[key]
+		c.assign(value, c.types[value], want, typeMismatch)
+	}
+
+	for _, name := range dst.properties() {
+		if !given[name] && !unknown && dst.required(name) {
+			c.errorAt(node, "Property '%s' is missing in type '%s' but required in type '%s'.", name, src, dst)
+		}
+	}
+}
+
+// literalTuple is the type of an array literal's elements, as a tuple.
+func (c *checker) literalTuple(node *ast.ArrayLiteral) *Tuple {
+	tuple := &Tuple{}
+	for _, el := range node.Elements {
+		if spread, ok := el.(*ast.SpreadElement); ok {
+			if t, ok := c.types[spread.Argument].(*Tuple); ok {
+				tuple.Elems = append(tuple.Elems, t.Elems...)
+				continue
+			}
+		}
+		tuple.Elems = append(tuple.Elems, c.types[el])
+	}
+	return tuple
+}
+
+func propertyName(key ast.Expression) (string, bool) {
+	switch key := key.(type) {
+	case *ast.Identifier:
+		return key.Value, true
+	case *ast.StringLiteral:
+		return key.Value, true
+	case *ast.NumberLiteral:
+		return key.String(), true
+	}
+	return "", false
+}
+
+// expr infers the type of an expression, checking it and its parts.
+func (c *checker) expr(node ast.Expression) Type {
+	if node == nil {
+		return Any
+	}
+	t := c.infer(node)
+	if t == nil {
+		t = Any
+	}
+	c.types[node] = t
+	return t
+}
+
+func (c *checker) infer(node ast.Expression) Type {
+	switch node := node.(type) {
+	case *ast.NumberLiteral:
+		return &Literal{Value: node.Value, Base: Number}
+
+	case *ast.StringLiteral:
+		return &Literal{Value: node.Value, Base: String}
+
+	case *ast.Boolean:
+		return &Literal{Value: node.Value, Base: Boolean}
+
+	case *ast.NullLiteral:
+		return Null
+
+	case *ast.TemplateLiteral:
+		for _, e := range node.Expressions {
+			c.expr(e)
+		}
+		return String
+
+	case *ast.TaggedTemplateExpression:
+		tag := c.expr(node.Tag)
+		for _, e := range node.Template.Expressions {
+			c.expr(e)
+		}
+		if fn, ok := tag.(*Function); ok {
+			return fn.Return
+		}
+		return Any
+
+	case *ast.Identifier:
+		sym := c.scope.lookup(node.Value)
+		if sym == nil {
+			c.errorAt(node, "Cannot find name '%s'.", node.Value)
+			return Any
+		}
+		c.initialized(sym, node)
+		if enum, ok := sym.typ.(*EnumObject); ok && enum.Enum.Const {
+			c.errorAt(node, "'const' enums can only be used in property or index access expressions or the right hand side of an import declaration or export assignment or type query.")
+		}
+		return sym.typ
+
+	case *ast.InlinedEnumMember:
+		if nt := c.scope.lookupType(node.Enum); nt != nil {
+			if enum, ok := nt.typ.(*Enum); ok {
+				return c.enumMember(enum, node.Member, node)
+			}
+		}
+		return c.expr(node.Value)
+
+	case *ast.ThisExpression:
+		return c.this
+
+	case *ast.SuperExpression:
+		return c.superType()
+
+	case *ast.PrefixExpression:
+		right := c.expr(node.Right)
+		switch node.Operator {
+		case "!":
+			return Boolean
+		case "typeof":
+			return String
+		case "void":
+			return Undefined
+		case "delete":
+			c.deleteOperand(node.Right)
+			return Boolean
+		case "+":
+			return Number
+		case "~", "-":
+			if !isNumeric(right) {
+				c.errorAt(node.Right, "An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type.")
+			}
+			if lit, ok := node.Right.(*ast.NumberLiteral); ok && node.Operator == "-" {
+				return &Literal{Value: -lit.Value, Base: Number}
+			}
+			return Number
+		}
+		return Any
+
+	case *ast.AwaitExpression:
+		return awaited(c.expr(node.Argument))
+
+	case *ast.InfixExpression:
+		if node.Operator == "." {
+			return c.member(node)
+		}
+		left := c.expr(node.Left)
+		var right Type
+		switch node.Operator {
+		case "&&", "||":
+			// The right operand is only evaluated if the left one is truthy,
+			// or falsy for ||
+			c.narrowed(c.narrowing(node.Left, node.Operator == "&&"), func() { right = c.expr(node.Right) })
+		default:
+			right = c.expr(node.Right)
+		}
+		return c.binary(node.Operator, node.Left, node.Right, left, right)
+
+	case *ast.ConditionalExpression:
+		c.expr(node.Condition)
+		var consequence, alternative Type
+		c.narrowed(c.narrowing(node.Condition, true), func() { consequence = c.expr(node.Consequence) })
+		c.narrowed(c.narrowing(node.Condition, false), func() { alternative = c.expr(node.Alternative) })
+		return unionOf(consequence, alternative)
+
+	case *ast.SequenceExpression:
+		return c.exprs(node.Expressions)[len(node.Expressions)-1]
+
+	case *ast.OptionalChain:
+		return c.optionalChain(node)
+
+	case *ast.IfExpression:
+		c.expr(node.Condition)
+		c.narrowed(c.narrowing(node.Condition, true), func() { c.nested(node.Consequence) })
+		c.narrowed(c.narrowing(node.Condition, false), func() {
+			switch alt := node.Alternative.(type) {
+			case *ast.BlockStatement:
+				c.nested(alt)
+			case ast.Expression:
+				c.expr(alt)
+			}
+		})
+		return Void
+
+	case *ast.FunctionLiteral:
+		var this Type = Any
+		if node.Arrow {
+			this = nil
+		}
+		sig := c.function(node, this)
+		if node.Name != "" {
+			// A named function is bound where it is evaluated
+			c.scope.declare(node.Name, sig, false)
+		}
+		return sig
+
+	case *ast.ClassLiteral:
+		return c.classLiteral(node)
+
+	case *ast.HashLiteral:
+		obj := &Object{Props: map[string]Type{}}
+		known := true
+		for _, key := range node.Keys {
+			if spread, ok := key.(*ast.SpreadElement); ok {
+				known = c.spreadObject(obj, spread) && known
+				continue
+			}
+			name, ok := propertyName(key)
+			if !ok {
+				c.expr(key)
+			}
+			t := c.expr(node.Pairs[key])
+			if ok {
+				obj.Props[name] = widen(t)
+			}
+		}
+		if !known {
+			return Any
+		}
+		return obj
+
+	case *ast.SpreadElement:
+		// The type of each of the values it spreads
+		return c.elementType(c.expr(node.Argument), node.Argument)
+
+	case *ast.ArrayLiteral:
+		if len(node.Elements) == 0 {
+			return &Array{Elem: Any}
+		}
+		var elems []Type
+		for _, el := range node.Elements {
+			elems = append(elems, widen(c.expr(el)))
+		}
+		return &Array{Elem: unionOf(elems...)}
+
+	case *ast.IndexExpression:
+		return c.index(node)
+
+	case *ast.CallExpression:
+		return c.call(node)
+
+	case *ast.NewExpression:
+		callee := c.expr(node.Constructor)
+		switch callee := callee.(type) {
+		case *Class:
+			return c.arguments(callee.Construct, node.TypeArgs, node.Arguments, node).Return
+		case *Function:
+			c.arguments(callee, node.TypeArgs, node.Arguments, node)
+			return Any
+		}
+		c.exprs(node.Arguments)
+		if callee != Any {
+			c.errorAt(node.Constructor, "This expression is not constructable. Type '%s' has no construct signatures.", callee)
+		}
+		return Any
+
+	case *ast.AssignmentExpression:
+		return c.assignment(node)
+
+	case *ast.UpdateExpression:
+		t := c.reference(node.Target)
+		if !isNumeric(t) {
+			c.errorAt(node.Target, "An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type.")
+		}
+		return Number
+	}
+
+	return Any
+}
+
+func (c *checker) exprs(nodes []ast.Expression) []Type {
+	types := make([]Type, len(nodes))
+	for i, node := range nodes {
+		types[i] = c.expr(node)
+	}
+	return types
+}
+
+func isNumeric(t Type) bool {
+	t = apparent(t)
+	return t == Number || t == Any
+}
+
+// binary infers the type of a binary operation, checking its operands.
+// Literal operands count as their primitive types.
+func (c *checker) binary(op string, leftNode, rightNode ast.Node, left, right Type) Type {
+	switch op {
+	case "+":
+		l, r := apparent(left), apparent(right)
+		switch {
+		case l == String || r == String:
+			return String
+		case l == Any || r == Any:
+			return Any
+		case l == Number && r == Number:
+			return Number
+		}
+		c.errorSpan(leftNode.Pos(), rightNode.End(), "Operator '+' cannot be applied to types '%s' and '%s'.", left, right)
+		return Any
+
+	case "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", ">>>":
+		if !isNumeric(left) {
+			c.errorAt(leftNode, "The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type.")
+		}
+		if !isNumeric(right) {
+			c.errorAt(rightNode, "The right-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type.")
+		}
+		return Number
+
+	case "<", ">", "<=", ">=":
+		l, r := apparent(left), apparent(right)
+		if l != Any && r != Any && !(l == Number && r == Number) && !(l == String && r == String) {
+			c.errorSpan(leftNode.Pos(), rightNode.End(), "Operator '%s' cannot be applied to types '%s' and '%s'.", op, left, right)
+		}
+		return Boolean
+
+	case "==", "!=", "===", "!==":
+		if !overlaps(left, right) {
+			c.errorSpan(leftNode.Pos(), rightNode.End(), "This comparison appears to be unintentional because the types '%s' and '%s' have no overlap.", left, right)
+		}
+		return Boolean
+
+	case "instanceof":
+		return Boolean
+
+	case "in":
+		for _, member := range members(right) {
+			if isPrimitiveValue(apparent(member)) || isNullish(member) {
+				c.errorAt(rightNode, "The right-hand side of an 'in' expression must not be a primitive.")
+				break
+			}
+		}
+		return Boolean
+
+	case "&&":
+		return unionOf(falsy(left), right)
+
+	case "||":
+		return unionOf(truthy(left), right)
+
+	case "??":
+		if t := nonNullable(left); t != Never {
+			return unionOf(t, right)
+		}
+		return right
+	}
+
+	return Any
+}
+
+// member infers the type of obj.name.
+func (c *checker) member(node *ast.InfixExpression) Type {
+	obj := c.link(c.expr(node.Left), node.Left, node.Optional)
+	name := node.Right.(*ast.Identifier)
+	t := c.property(obj, name.Value, name)
+	c.checkAccess(obj, name)
+	if narrowed := c.scope.lookupPath(path(node)); narrowed != nil {
+		return narrowed
+	}
+	if o, ok := obj.(*Object); ok && !o.required(name.Value) {
+		// Reading an optional property may give undefined
+		if _, has := o.Lookup(name.Value); has {
+			return unionOf(t, Undefined)
+		}
+	}
+	return t
+}
+
+// checkAccess reports a use of a private or protected member name of a
+// value of type t outside of the classes it is accessible within.
+func (c *checker) checkAccess(t Type, name *ast.Identifier) {
+	obj, ok := t.(*Object)
+	if cls, isClass := t.(*Class); isClass {
+		obj, ok = cls.Statics, cls.Statics != nil
+	}
+	if !ok {
+		return
+	}
+	a, ok := obj.access(name.Value)
+	if !ok || a.allows(c.class) {
+		return
+	}
+	if a.Modifier == "private" {
+		c.errorAt(name, "Property '%s' is private and only accessible within class '%s'.", name.Value, a.Class.Name)
+	} else {
+		c.errorAt(name, "Property '%s' is protected and only accessible within class '%s' and its subclasses.", name.Value, a.Class.Name)
+	}
+}
+
+// nonNull reports a value of type t, computed by node, that may be null
+// or undefined where it is used as an object, and returns t without them.
+func (c *checker) nonNull(t Type, node ast.Node) Type {
+	if !nullable(t) {
+		return t
+	}
+	c.errorAt(node, "'%s' is possibly %s.", c.text(node), nullishNames(t))
+	if t = nonNullable(t); t == Never {
+		return Any
+	}
+	return t
+}
+
+// property is the type of a property of a value of type t. node locates
+// the error if there is no such property.
+func (c *checker) property(t Type, name string, node ast.Node) Type {
+	if p, ok := propertyType(t, name); ok {
+		return p
+	}
+	c.errorAt(node, "Property '%s' does not exist on type '%s'.", name, t)
+	return Any
+}
+
+// propertyType looks up a property of a value of type t. Strings only have
+// a length and numbers and booleans no properties at all, as the evaluator
+// has no methods for them. Values of types whose properties are not known
+// have every property, of type any. The properties of a union are those
+// all of its members have.
+func propertyType(t Type, name string) (Type, bool) {
+	switch t := t.(type) {
+	case *Object:
+		return t.Lookup(name)
+	case *Class:
+		switch name {
+		case "prototype":
+			return t.Instance, true
+		case "name":
+			return String, true
+		}
+		if t.Statics == nil {
+			return Any, true
+		}
+		return t.Statics.Lookup(name)
+	case *Array:
+		return arrayProperty(t.Elem, name)
+	case *Tuple:
+		return arrayProperty(unionOf(t.Elems...), name)
+	case *Promise:
+		return promiseProperty(t.Value, name)
+	case *TypeParam:
+		if t.Constraint == nil {
+			return nil, false
+		}
+		return propertyType(t.Constraint, name)
+	case *EnumObject:
+		if m, ok := t.Enum.member(name); ok {
+			return m, true
+		}
+		return nil, false
+	case *Union:
+		var types []Type
+		for _, member := range t.Types {
+			p, ok := propertyType(member, name)
+			if !ok {
+				return nil, false
+			}
+			types = append(types, p)
+		}
+		return unionOf(types...), true
+	case *Primitive, *Literal:
+		switch widen(t) {
+		case String:
+			if name == "length" {
+				return Number, true
+			}
+			return nil, false
+		case Number, Boolean:
+			return nil, false
+		}
+	}
+	return Any, true
+}
+
+// index infers the type of obj[index].
+func (c *checker) index(node *ast.IndexExpression) Type {
+	obj := c.link(c.expr(node.Left), node.Left, node.Optional)
+	index := c.expr(node.Index)
+
+	// Arrays, tuples and strings have named properties, such as
+	// xs["length"], and are otherwise indexed by number
+	if indexedByNumber(obj) {
+		if s, ok := node.Index.(*ast.StringLiteral); ok {
+			return c.property(obj, s.Value, node.Index)
+		}
+		if !assignable(index, Number) {
+			c.errorAt(node.Index, "Element implicitly has an 'any' type because index expression is not of type 'number'.")
+			return Any
+		}
+	}
+
+	switch t := obj.(type) {
+	case *Array:
+		return t.Elem
+	case *Tuple:
+		if n, ok := node.Index.(*ast.NumberLiteral); ok {
+			i := int(n.Value)
+			if float64(i) != n.Value || i < 0 || i >= len(t.Elems) {
+				c.errorAt(node.Index, "Tuple type '%s' of length '%d' has no element at index '%s'.", t, len(t.Elems), n)
+				return Any
+			}
+			return t.Elems[i]
+		}
+		return unionOf(t.Elems...)
+	case *Object, *Class:
+		if s, ok := node.Index.(*ast.StringLiteral); ok {
+			return c.property(t, s.Value, node.Index)
+		}
+	case *EnumObject:
+		// Color[0] is the name of the member whose value is 0
+		if s, ok := node.Index.(*ast.StringLiteral); ok {
+			return c.property(t, s.Value, node.Index)
+		}
+		return String
+	}
+	if widen(obj) == String {
+		return String
+	}
+	return Any
+}
+
+// indexedByNumber reports whether values of type t, arrays, tuples and
+// strings, are indexed by number.
+func indexedByNumber(t Type) bool {
+	switch t := widen(t).(type) {
+	case *Array, *Tuple:
+		return true
+	default:
+		return t == String
+	}
+}
+
+// superType is the type super.x looks x up on: the superclass instance in
+// methods, or the superclass itself in static methods.
+func (c *checker) superType() Type {
+	if c.class == nil || c.class.Super == nil {
+		return Any
+	}
+	if c.static {
+		return c.class.Super
+	}
+	return c.class.Super.Instance
+}
+
+func (c *checker) call(node *ast.CallExpression) Type {
+	if _, ok := node.Function.(*ast.SuperExpression); ok {
+		c.superCall = true
+		if c.class != nil && c.class.Super != nil {
+			c.arguments(c.class.Super.Construct, nil, node.Arguments, node)
+		} else {
+			c.exprs(node.Arguments)
+		}
+		return Void
+	}
+
+	callee := c.expr(node.Function)
+	if node.Optional {
+		callee = c.link(callee, node.Function, true)
+	} else if nullable(callee) {
+		c.errorAt(node.Function, "Cannot invoke an object which is possibly %s.", nullishNames(callee))
+		callee = nonNullable(callee)
+		if callee == Never {
+			callee = Any
+		}
+	}
+
+	switch callee := callee.(type) {
+	case *Function:
+		return c.arguments(callee, node.TypeArgs, node.Arguments, node).Return
+	case *Class:
+		if callee.Callable {
+			return c.arguments(callee.Construct, node.TypeArgs, node.Arguments, node).Return
+		}
+		c.exprs(node.Arguments)
+		c.errorAt(node.Function, "Value of type '%s' is not callable. Did you mean to include 'new'?", callee)
+		return Any
+	default:
+		c.exprs(node.Arguments)
+		if callee != Any {
+			c.errorAt(node.Function, "This expression is not callable. Type '%s' has no call signatures.", callee)
+		}
+		return Any
+	}
+}
+
+// arguments checks the arguments of a call against the parameters of fn,
+// after instantiating it with the type arguments of the call if it is
+// generic. It returns the function called.
+func (c *checker) arguments(fn *Function, typeArgs []ast.TypeNode, args []ast.Expression, node ast.Node) *Function {
+	list, open := c.spreadArguments(args, c.exprs(args))
+	if len(fn.TypeParams) > 0 || typeArgs != nil {
+		fn = c.instantiate(fn, typeArgs, argumentTypes(list), node)
+	}
+
+	required, max := fn.required(), len(fn.Params)
+	switch {
+	case open != nil && (fn.Rest == nil || len(list) < max):
+		c.errorAt(open[0].node, "A spread argument must either have a tuple type or be passed to a rest parameter.")
+	case len(list) < required || (fn.Rest == nil && len(list) > max):
+		var expected string
+		switch {
+		case fn.Rest != nil:
+			expected = fmt.Sprintf("at least %d", required)
+		case required == max:
+			expected = fmt.Sprint(required)
+		default:
+			expected = fmt.Sprintf("%d-%d", required, max)
+		}
+		c.errorAt(node, "Expected %s arguments, but got %d.", expected, len(list))
+	}
+
+	for i, arg := range append(list, open...) {
+		param := fn.Rest
+		if i < len(fn.Params) && i < len(list) {
+			param = fn.Params[i].Type
+			if fn.Params[i].Optional {
+				param = unionOf(param, Undefined)
+			}
+		}
+		if param == nil {
+			break
+		}
+		c.assign(arg.node, arg.typ, param, "Argument of type '%s' is not assignable to parameter of type '%s'.")
+	}
+	return fn
+}
+
+func (c *checker) assignment(node *ast.AssignmentExpression) Type {
+	// [a, b] = [b, a]
+	switch node.Left.(type) {
+	case *ast.ObjectPattern, *ast.ArrayPattern:
+		return c.patternAssignment(node)
+	}
+
+	target := c.reference(node.Left)
+	value := c.expr(node.Value)
+
+	var result Type
+	switch node.Operator {
+	case "=", "&&=", "||=", "??=":
+		c.assign(node.Value, value, target, typeMismatch)
+		result = value
+	default:
+		result = c.binary(strings.TrimSuffix(node.Operator, "="), node.Left, node.Value, target, value)
+		if !assignable(result, target) {
+			c.errorAt(node.Left, typeMismatch, result, target)
+		}
+	}
+
+	// Where a variable has been narrowed, it now has the type of its value
+	if ident, ok := node.Left.(*ast.Identifier); ok {
+		if sym := c.scope.lookup(ident.Value); sym != nil && sym.narrows != nil {
+			sym.typ = filter(target, func(member Type) bool { return assignable(result, member) })
+			if sym.typ == Never {
+				sym.typ = target
+			}
+		}
+	}
+	return result
+}
+
+// reference infers the type of an assignment target, checking that it is
+// not a constant.
+func (c *checker) reference(node ast.Expression) Type {
+	if member, ok := node.(*ast.InfixExpression); ok && member.Operator == "." {
+		c.forget(path(member))
+		t := c.expr(member)
+		name := member.Right.(*ast.Identifier)
+		switch obj := c.types[member.Left].(type) {
+		case *Object:
+			if obj.readonly(name.Value) && !c.initializing(obj, member) {
+				c.errorAt(name, "Cannot assign to '%s' because it is a read-only property.", name.Value)
+			}
+		case *Class:
+			if obj.Statics != nil && obj.Statics.readonly(name.Value) {
+				c.errorAt(name, "Cannot assign to '%s' because it is a read-only property.", name.Value)
+			}
+		case *EnumObject:
+			c.errorAt(name, "Cannot assign to '%s' because it is a read-only property.", name.Value)
+		}
+		return t
+	}
+
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		return c.expr(node)
+	}
+
+	c.forget(ident.Value)
+	sym := c.scope.lookup(ident.Value)
+	if sym == nil {
+		c.errorAt(ident, "Cannot find name '%s'.", ident.Value)
+		return Any
+	}
+	c.initialized(sym, ident)
+	if sym.constant {
+		c.errorAt(ident, "Cannot assign to '%s' because it is a constant.", ident.Value)
+	}
+	// A narrowed variable may be assigned anything its declaration allows
+	if sym.narrows != nil {
+		sym = sym.narrows
+	}
+	c.types[ident] = sym.typ
+	return sym.typ
+}
+
+// initializing reports whether member, a property of obj, is a field of
+// the class whose constructor is being checked, assigned through this.
+func (c *checker) initializing(obj *Object, member *ast.InfixExpression) bool {
+	if _, ok := member.Left.(*ast.ThisExpression); !ok || c.fn == nil || !c.fn.constructor {
+		return false
+	}
+	_, own := obj.Props[member.Right.(*ast.Identifier).Value]
+	return own
+}
+
+// signature is the type of a function as declared by its annotations. Its
+// return type is filled in when the body is checked, if not annotated.
+func (c *checker) signature(lit *ast.FunctionLiteral) *Function {
+	if sig, ok := c.signatures[lit]; ok {
+		return sig
+	}
+
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() { c.scope = outer }()
+
+	// Defaults are checked here, in a scope holding the parameters before
+	// them, since an unannotated parameter takes the type of its default
+	sig := &Function{TypeParams: c.typeParams(lit.TypeParams), Return: Any}
+	for i, param := range lit.Parameters {
+		var t Type = Any
+		if param.Type != nil {
+			t = c.annotation(param)
+		}
+		if param.Default != nil {
+			d := c.expr(param.Default)
+			if param.Type != nil {
+				c.assign(param.Default, d, t, typeMismatch)
+			} else {
+				t = widen(d)
+			}
+		}
+		if lit.Rest && i == len(lit.Parameters)-1 {
+			sig.Rest = c.restElement(param, t)
+			break
+		}
+		sig.Params = append(sig.Params, Param{Name: param.Value, Type: t, Optional: param.Optional || param.Default != nil})
+		c.scope.declare(param.Value, parameterType(param, t), false)
+	}
+	if lit.ReturnType != nil {
+		sig.Return = c.resolveType(lit.ReturnType)
+	}
+	if predicate, ok := lit.ReturnType.(*ast.TypePredicate); ok {
+		sig.Guard = &Guard{Param: -1, Type: c.resolveType(predicate.Type)}
+		for i, param := range lit.Parameters {
+			if param.Value == predicate.ParamName {
+				sig.Guard.Param = i
+			}
+		}
+		if sig.Guard.Param < 0 {
+			c.errorAt(predicate, "Cannot find parameter '%s'.", predicate.ParamName)
+			sig.Guard = nil
+		}
+	}
+
+	c.signatures[lit] = sig
+	return sig
+}
+
+// parameterType is the type of a parameter of type t within its function.
+// An optional parameter without a default may be missing, and so
+// undefined.
+func parameterType(param *ast.Identifier, t Type) Type {
+	if param.Optional && t != Any {
+		return unionOf(t, Undefined)
+	}
+	return t
+}
+
+// restElement is the type of each of the arguments a rest parameter of
+// type t collects. node locates the error if t is not an array type.
+func (c *checker) restElement(node ast.Node, t Type) Type {
+	switch t := t.(type) {
+	case *Array:
+		return t.Elem
+	case *Tuple:
+		return unionOf(t.Elems...)
+	}
+	if t != Any {
+		c.errorAt(node, "A rest parameter must be of an array type.")
+	}
+	return Any
+}
+
+// function checks the body of a function and returns its type. this is
+// the type of 'this' in the body, or nil for arrow functions, which keep
+// the enclosing one.
+func (c *checker) function(lit *ast.FunctionLiteral, this Type) *Function {
+	sig := c.signature(lit)
+
+	outerScope, outerFn, outerThis := c.scope, c.fn, c.this
+	c.scope = newScope(outerScope)
+	if this != nil {
+		c.this = this
+	}
+	c.declareTypeParams(sig.TypeParams)
+	if !lit.Arrow {
+		c.scope.declare("arguments", &Array{Elem: Any}, false)
+	}
+	for i, param := range lit.Parameters {
+		if i == len(sig.Params) {
+			// The rest parameter holds an array of the remaining arguments
+			var t Type = &Array{Elem: sig.Rest}
+			if param.Type != nil {
+				t = c.annotation(param)
+			}
+			c.scope.declare(param.Value, t, false)
+			break
+		}
+		c.scope.declare(param.Value, parameterType(param, sig.Params[i].Type), false)
+	}
+
+	fn := &function{async: lit.Async, constructor: lit == c.ctor}
+	if lit.ReturnType != nil {
+		fn.declared = sig.Return
+		if lit.Async {
+			if p, ok := sig.Return.(*Promise); ok {
+				fn.declared = p.Value
+			} else if sig.Return != Any {
+				c.errorAt(lit.ReturnType, "The return type of an async function or method must be the global Promise<T> type.")
+				fn.declared = Any
+			}
+		}
+	}
+	c.fn = fn
+
+	c.hoistVars(lit.Body.Statements)
+	c.block(lit.Body.Statements)
+
+	switch {
+	case lit.ReturnType == nil:
+		var ret Type = Void
+		if len(fn.returns) > 0 {
+			ret = widen(unionOf(fn.returns...))
+		}
+		if lit.Async {
+			ret = &Promise{Value: ret}
+		}
+		sig.Return = ret
+	case !fn.hasReturn && fn.declared != Void && fn.declared != Any && fn.declared != Unknown && fn.declared != Never:
+		c.errorAt(lit.ReturnType, "A function whose declared type is neither 'undefined', 'void', nor 'any' must return a value.")
+	case fn.hasReturn && fn.declared != Any && fn.declared != Unknown && !includes(fn.declared, Void) && !includes(fn.declared, Undefined) && !c.terminates(lit.Body.Statements):
+		c.errorAt(lit.ReturnType, "Function lacks ending return statement and return type does not include 'undefined'.")
+	}
+
+	c.scope, c.fn, c.this = outerScope, outerFn, outerThis
+	return sig
+}
+
+// classLiteral checks a class declaration or expression. The members are
+// declared from their annotations first, so that methods can use each
+// other, then initializers and bodies are checked with 'this' bound to the
+// instance, or to the class for static members.
+func (c *checker) classLiteral(lit *ast.ClassLiteral) Type {
+	cls, ok := c.classes[lit]
+	if !ok {
+		name := ""
+		if lit.Name != nil {
+			name = lit.Name.Value
+		}
+		cls = newClass(name)
+		c.classes[lit] = cls
+	}
+	if sym, ok := c.scope.vars[cls.Name]; ok && sym.typ == cls {
+		sym.pending = false
+	}
+	c.classTypeParams(cls, lit)
+	instance := cls.Instance.(*Object)
+
+	if lit.SuperClass != nil {
+		switch super := c.expr(lit.SuperClass).(type) {
+		case *Class:
+			cls.Super = c.superClass(super, cls, lit)
+			super = cls.Super
+			if base, ok := super.Instance.(*Object); ok {
+				instance.Base = base
+			}
+			cls.Statics.Base = super.Statics
+			// The implicit constructor passes its arguments on
+			cls.Construct.Params = super.Construct.Params
+			cls.Construct.Rest = super.Construct.Rest
+		default:
+			if super != Any {
+				c.errorAt(lit.SuperClass, "Type '%s' is not a constructor function type.", super)
+			}
+		}
+	}
+
+	outerScope, outerClass, outerStatic, outerCtor := c.scope, c.class, c.static, c.ctor
+	c.scope = newScope(outerScope)
+	if lit.Name != nil {
+		c.scope.declare(lit.Name.Value, cls, false)
+	}
+	c.declareTypeParams(cls.TypeParams)
+	c.class = cls
+
+	for _, member := range lit.Members {
+		home := instance
+		if member.Static {
+			home = cls.Statics
+		}
+
+		declareModifiers(home, member.Name, member.Modifiers, cls)
+		switch member.Kind {
+		case ast.ClassField:
+			home.Props[member.Name] = Any
+			if member.Type != nil {
+				home.Props[member.Name] = c.resolveType(member.Type)
+			}
+		case ast.ClassMethod:
+			home.Props[member.Name] = c.signature(member.Value)
+		case ast.ClassGetter:
+			home.Props[member.Name] = c.signature(member.Value).Return
+		case ast.ClassSetter:
+			if _, ok := home.Props[member.Name]; !ok {
+				home.Props[member.Name] = c.signature(member.Value).Params[0].Type
+			}
+		case ast.ClassConstructor:
+			sig := c.signature(member.Value)
+			cls.Construct = &Function{Params: sig.Params, Rest: sig.Rest, Return: instance}
+			for _, name := range member.ParameterProperties {
+				for _, param := range sig.Params {
+					if param.Name == name {
+						instance.Props[name] = param.Type
+						declareModifiers(instance, name, member.ParameterModifiers[name], cls)
+					}
+				}
+			}
+		}
+	}
+
+	cls.Construct.TypeParams = cls.TypeParams
+	cls.declaring = false
+	for _, fill := range cls.pending {
+		fill()
+	}
+
+	for _, member := range lit.Members {
+		home, this := instance, Type(instance)
+		if member.Static {
+			home, this = cls.Statics, cls
+		}
+		c.static = member.Static
+
+		if member.Kind == ast.ClassConstructor {
+			outerSuperCall := c.superCall
+			c.ctor, c.superCall = member.Value, false
+			c.function(member.Value, this)
+			if lit.SuperClass != nil && !c.superCall {
+				c.errorSpan(member.Pos(), member.End(), "Constructors for derived classes must contain a 'super' call.")
+			}
+			c.superCall = outerSuperCall
+			continue
+		}
+		if member.Kind != ast.ClassField {
+			sig := c.function(member.Value, this)
+			if member.Kind == ast.ClassGetter && member.Value.ReturnType == nil {
+				home.Props[member.Name] = sig.Return
+			}
+			continue
+		}
+
+		if member.Init == nil {
+			continue
+		}
+		outerThis := c.this
+		c.this = this
+		t := c.expr(member.Init)
+		c.this = outerThis
+
+		if member.Type != nil {
+			c.assign(member.Init, t, home.Props[member.Name], typeMismatch)
+		} else {
+			home.Props[member.Name] = t
+		}
+	}
+
+	c.scope, c.class, c.static, c.ctor = outerScope, outerClass, outerStatic, outerCtor
+	return cls
+}
+
+// declareModifiers records the modifiers of member name of home, declared
+// in class cls.
+func declareModifiers(home *Object, name string, m ast.Modifiers, cls *Class) {
+	if m.Readonly {
+		setFlag(&home.Readonly, name)
+	}
+	if m.Access != "" {
+		if home.Access == nil {
+			home.Access = map[string]Access{}
+		}
+		home.Access[name] = Access{Modifier: m.Access, Class: cls}
+	}
+}
+
+// superClass is the superclass a class extends, instantiated with the
+// type arguments of extends Base<T>, which may refer to the type
+// parameters of the class.
+func (c *checker) superClass(super, cls *Class, lit *ast.ClassLiteral) *Class {
+	if len(super.TypeParams) == 0 && lit.SuperArgs == nil {
+		return super
+	}
+
+	outer := c.scope
+	c.scope = newScope(outer)
+	c.declareTypeParams(cls.TypeParams)
+	args := make([]Type, len(lit.SuperArgs))
+	for i, arg := range lit.SuperArgs {
+		args[i] = c.resolveType(arg)
+	}
+	c.scope = outer
+
+	if len(super.TypeParams) == 0 {
+		c.errorAt(lit.SuperClass, "Type '%s' is not generic.", super.Name)
+		return super
+	}
+	bindings := c.bindTypeArgs(super.Name, super.TypeParams, args, lit.SuperClass)
+	if bindings == nil {
+		bindings = map[*TypeParam]Type{}
+		for _, param := range super.TypeParams {
+			bindings[param] = Any
+		}
+	}
+	construct := *super.Construct
+	construct.TypeParams = nil
+	return &Class{
+		Name:      super.Name,
+		Super:     super.Super,
+		Instance:  substitute(super.Instance, bindings),
+		Statics:   super.Statics,
+		Construct: substitute(&construct, bindings).(*Function),
+		Callable:  super.Callable,
+		Generic:   super,
+	}
+}
@@ -0,0 +1,404 @@
+package typecheck_test
+
+import (
+	"strings"
+	"testing"
+
+	"ts-engine/lexer"
+	"ts-engine/parser"
+	"ts-engine/typecheck"
+)
+
+// check parses and type checks a .ts program, returning the messages of
+// the type errors found.
+func check(t *testing.T, src string) []string {
+	t.Helper()
+	p := parser.New(lexer.New(src), true)
+	program := p.ParseProgram()
+	for _, err := range p.Errors() {
+		t.Fatalf("parse error: %s\n%s", err, src)
+	}
+	var messages []string
+	for _, err := range typecheck.Check(program) {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
+func TestAccepted(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"annotated let", `let x: number = 1; x = 2;`},
+		{"string concatenation", `let s: string = "a"; let t: string = s + 1;`},
+		{"function call", `function add(a: number, b: number): number { return a + b; } let n: number = add(1, 2);`},
+		{"string length", `let s: string = "abc"; let n: number = s.length;`},
+		{"promise methods", `
+			async function f(): Promise<void> {
+				const p: Promise<number> = Promise.resolve(1);
+				const s: Promise<string> = p.then((n: number) => "#" + n);
+				const all: number[] = await Promise.all([p, 2]);
+				const first: number = await Promise.race([p, Promise.resolve(3)]);
+				const made: Promise<number> = new Promise<number>((resolve) => resolve(1));
+				const failed: Promise<number> = Promise.reject(new Error("no"));
+				const either: Promise<number | string> = p.catch((e: any) => "x").finally(() => {});
+			}`},
+		{"keyof", `type K = keyof { a: number; b: string }; let k: K = "b";`},
+		{"indexed access by keyof", `interface P { x: number; y: string } let v: P[keyof P] = "s";`},
+		{"generic indexed access", `
+			function get<T, K extends keyof T>(o: T, key: K): T[K] { return o[key]; }
+			let n: number = get({ a: 1 }, "a");`},
+		{"Number and String statics", "let ok: boolean = Number.isInteger(Number.EPSILON); let r: string = String.raw`a${1}`;"},
+		{"several declarators", `for (let i: number = 0, j: number = 3; i < j; i++, j--) { let d: number = j - i; }`},
+		{"union narrowing", `
+			function f(v: string | number): number {
+				if (typeof v === "string") { return v.length; }
+				return v;
+			}`},
+		{"null check", `
+			function f(v: string | null): string {
+				if (v === null) { return ""; }
+				return v;
+			}`},
+		{"type guard", `
+			interface Fish { swim: number }
+			interface Bird { fly: number }
+			function isFish(p: Fish | Bird): p is Fish { return "swim" in p; }
+			function move(p: Fish | Bird): number {
+				if (isFish(p)) { return p.swim; }
+				return p.fly;
+			}`},
+		{"nested tuple", `let t: [[number, string], boolean] = [[1, "a"], true];`},
+		{"function type", `let f: (x: number) => string = (x: number): string => "" + x;`},
+		{"interface", `
+			interface User { name: string; age?: number }
+			let u: User = { name: "Ann" };`},
+		{"interface extends", `
+			interface Named { name: string }
+			interface User extends Named { readonly id: number }
+			let u: User = { name: "Ann", id: 1 };
+			let s: string = u.name;`},
+		{"declared later", `let p: Point = { x: 1 }; type Point = { x: number };`},
+		{"identity", `function id<T>(x: T): T { return x; } const s: string = id("a"); const n: number = id<number>(1);`},
+		{"generic interface", `interface Box<T> { value: T } const b: Box<string> = { value: "x" }; const v: string = b.value;`},
+		{"generic class", `
+			class Stack<T> {
+				items: T[] = [];
+				push(x: T): void {}
+			}
+			const s: Stack<number> = new Stack<number>();
+			s.push(1);`},
+		{"readonly fields assigned in the constructor", `
+			class P {
+				readonly x: number;
+				readonly z: number = 0;
+				constructor(public readonly y: number) { this.x = y; this.z = y; }
+			}
+			const n: number = new P(1).y;`},
+		{"private and protected members within their classes", `
+			class A {
+				private x = 1;
+				protected y = 2;
+				private static count = 0;
+				sum(other: A): number { A.count++; return this.x + other.x + this.y; }
+			}
+			class B extends A {
+				constructor(protected z: number) { super(); }
+				twice(): number { return this.y * 2 + this.z; }
+			}`},
+		{"string index", `const s: string = "abc"; const c: string = s[1];`},
+		{"named array property by index", `const xs: number[] = [1]; const n: number = xs["length"];`},
+		{"typeof narrows a property", `
+			function f(r: { body: string | number }): number {
+				if (typeof r.body === "string") return r.body.length;
+				return r.body;
+			}`},
+		{"null check narrows a property", `
+			function f(o: { a: string | null }): number {
+				if (o.a !== null) { return o.a.length; }
+				return 0;
+			}`},
+		{"early return narrows a property", `
+			function f(o: { a: { b: string | null } }): number {
+				if (o.a.b === null) return 0;
+				return o.a.b.length;
+			}`},
+		{"truthiness narrows an optional property", `
+			interface R { user?: { name: string } }
+			function f(r: R): string { return r.user ? r.user.name : ""; }`},
+		{"same name in nested blocks", `let a: number = 1; { let a: string = "x"; } function f(a: number): void { let b: number = a; }`},
+		{"comparison is not a type argument", `let a: number = 1; let b: number = 2; let c: boolean = a < b;`},
+		{"enum member", `enum Color { Red, Green } let c: Color = Color.Green;`},
+		{"string enum", `enum Dir { Up = "UP" } let s: string = Dir.Up;`},
+		{"const enum", `const enum E { A = 1 } let n: number = E.A;`},
+		{"shadowed const enum", `const enum E { A = 1 } function f(E: { A: string }): string { return E.A; }`},
+		{"object pattern", `const { a, b: bee }: { a: number; b: string } = { a: 1, b: "x" }; const s: string = bee;`},
+		{"array pattern", `const [n, s]: [number, string] = [1, "x"]; const t: string = s;`},
+		{"array default", `const [x = 1] = [2]; let y: number = x;`},
+		{"string default", `let [y = "d"] = ["q"]; let s: string = y;`},
+		{"object default", `const { a = 1 } = { a: 2 };`},
+		{"parameter default", `function f([p = 0]: number[]): number { return p; }`},
+		{"string as object type", `let o: { length: number } = "abc";`},
+		{"array as object type", `let o: { length: number } = [1, 2];`},
+		{"tuple as object type", `let t: [number, string] = [1, "a"]; let o: { length: number } = t;`},
+		{"length constraint", `
+			function longest<T extends { length: number }>(a: T, b: T): T {
+				return a.length >= b.length ? a : b;
+			}
+			const s: string = longest("a", "bb");
+			const arr: number[] = longest([1], [2, 3]);`},
+		{"explicit type argument", `
+			function lo<T extends { length: number }>(x: T): number { return x.length; }
+			const n: number = lo<string>("abc");`},
+		{"literal inference", `
+			function c2<T extends string>(a: T, b: T): T { return b; }
+			const xy: "x" | "y" = c2("x", "y");`},
+		{"rest parameter", `function sum(...nums: number[]): number { return nums.length; } sum(1, 2, 3);`},
+		{"tuple spread", `function f(a: number, b: string): void {} const args: [number, string] = [1, "a"]; f(...args);`},
+		{"optional parameter", `function f(x: number, y?: string): void {} f(1);`},
+		{"default gives the type", `function f(x = 1): number { return x; } f();`},
+		{"undefined in a union", `let v: number | undefined = undefined; if (v !== undefined) { let w: number = v; }`},
+		{"conditional", `let n: number = 1; let s: string = n > 0 ? "pos" : "neg";`},
+		{"optional chaining", `let u: { a: { b: number } } | null = null; let v: number | undefined = u?.a.b;`},
+		{"block shadowing", `let x: number = 1; { let x: string = "a"; }`},
+		{"hoisted function", `let r: number = f(); function f(): number { return 1; }`},
+		{"array methods", `
+			const nums: number[] = [3, 1, 2];
+			const strs: string[] = nums.map((x: number): string => "#" + x);
+			const sum: number = nums.reduce((s: number, x: number): number => s + x, 0);
+			const last: number | undefined = nums.pop();`},
+		{"generic Array constructor", `
+			const a: number[] = new Array<number>(3);
+			const b: string[] = new Array(3);
+			const u: unknown = a;
+			if (u instanceof Array) { let n: number = u.length; }`},
+		{"switch exhaustive", `
+			type Shape = "circle" | "square";
+			function sides(s: Shape): number {
+				switch (s) {
+					case "circle": return 0;
+					case "square": return 4;
+				}
+			}`},
+		{"switch on an enum", `
+			enum Op { Add, Sub }
+			function apply(op: Op, a: number, b: number): number {
+				switch (op) {
+					case Op.Add: return a + b;
+					case Op.Sub: return a - b;
+				}
+			}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := check(t, tt.src); len(errs) != 0 {
+				t.Errorf("unexpected errors: %q", errs)
+			}
+		})
+	}
+}
+
+func TestRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // a substring of the first error
+	}{
+		{"wrong annotation", `let x: number = "a";`,
+			`is not assignable to type 'number'.`},
+		{"wrong argument", `function f(a: number): void {} f("x");`,
+			`is not assignable to parameter of type 'number'.`},
+		{"arity", `function f(a: number): void {} f();`,
+			`Expected 1 arguments, but got 0.`},
+		{"wrong return", `function f(): number { return "x"; }`,
+			`is not assignable to type 'number'.`},
+		{"unknown name", `let x: number = y;`,
+			`Cannot find name 'y'.`},
+		{"const assignment", `const x: number = 1; x = 2;`,
+			`Cannot assign to 'x' because it is a constant.`},
+		{"nested tuple", `let t: [[number, string], boolean] = [[1, 2], true];`,
+			`is not assignable to type 'string'.`},
+		{"missing property", `
+			interface User { name: string; id: number }
+			let u: User = { name: "Ann" };`,
+			`Property 'id' is missing`},
+		{"readonly property", `
+			interface User { readonly id: number }
+			let u: User = { id: 1 };
+			u.id = 2;`,
+			`Cannot assign to 'id' because it is a read-only property.`},
+		{"readonly parameter property", `
+			class P { constructor(public readonly y: number) {} }
+			const p: P = new P(1);
+			p.y = 2;`,
+			`Cannot assign to 'y' because it is a read-only property.`},
+		{"readonly field", `
+			class P { readonly y = 1; }
+			const p: P = new P();
+			p.y = 2;`,
+			`Cannot assign to 'y' because it is a read-only property.`},
+		{"readonly field outside the constructor", `
+			class P { readonly y = 1; move(): void { this.y = 2; } }`,
+			`Cannot assign to 'y' because it is a read-only property.`},
+		{"readonly static", `
+			class P { static readonly max = 1; }
+			P.max = 2;`,
+			`Cannot assign to 'max' because it is a read-only property.`},
+		{"private member", `class A { private x = 1 } new A().x;`,
+			`Property 'x' is private and only accessible within class 'A'.`},
+		{"private member in a subclass", `
+			class A { private x = 1 }
+			class B extends A { get(): number { return this.x; } }`,
+			`Property 'x' is private and only accessible within class 'A'.`},
+		{"private parameter property", `
+			class A { constructor(private x: number) {} }
+			new A(1).x;`,
+			`Property 'x' is private and only accessible within class 'A'.`},
+		{"protected member", `
+			class A { protected x = 1 }
+			class B extends A {}
+			new B().x;`,
+			`Property 'x' is protected and only accessible within class 'A' and its subclasses.`},
+		{"string index of an array", `
+			const xs: number[] = [1, 2];
+			for (const k in xs) { xs[k]; }`,
+			`Element implicitly has an 'any' type because index expression is not of type 'number'.`},
+		{"string index of a tuple", `const t: [number] = [1]; const k: string = "0"; t[k];`,
+			`Element implicitly has an 'any' type because index expression is not of type 'number'.`},
+		{"derived constructor without super", `
+			class A { x: number = 1; }
+			class D extends A { y: number; constructor() { this.y = 2; } }`,
+			`Constructors for derived classes must contain a 'super' call.`},
+		{"optional property read", `
+			interface R { user?: { name: string } }
+			function f(r: R): string { return r.user.name; }`,
+			`'r.user' is possibly 'undefined'.`},
+		{"assignment ends a property narrowing", `
+			function f(o: { a: string | null }): number {
+				if (o.a !== null) { o.a = null; return o.a.length; }
+				return 0;
+			}`,
+			`'o.a' is possibly 'null'.`},
+		{"shadowing ends a property narrowing", `
+			function f(o: { a: string | null }, p: { a: string | null }): number {
+				if (o.a !== null) { const o: { a: string | null } = p; return o.a.length; }
+				return 0;
+			}`,
+			`'o.a' is possibly 'null'.`},
+		{"redeclared let", `let a: number = 1; let a: number = 2;`,
+			`Cannot redeclare block-scoped variable 'a'.`},
+		{"redeclared const in a function", `function f(): void { const a: number = 1; let b: number = 2, a: number = 3; }`,
+			`Cannot redeclare block-scoped variable 'a'.`},
+		{"possibly null", `function f(v: string | null): number { return v.length; }`,
+			`'v' is possibly 'null'.`},
+		{"literal union", `let code: 200 | 404 = 500;`,
+			`Type '500' is not assignable to type '200 | 404'.`},
+		{"wrong type argument", `function id<T>(x: T): T { return x; } id<string>(1);`,
+			`Argument of type '1' is not assignable to parameter of type 'string'.`},
+		{"generic property type", `interface Box<T> { value: T } const b: Box<number> = { value: "x" };`,
+			`Type '"x"' is not assignable to type 'number'.`},
+		{"generic method argument", `
+			class Stack<T> { push(x: T): void {} }
+			new Stack<number>().push("x");`,
+			`is not assignable to parameter of type 'number'.`},
+		{"enum from a number literal", `enum Dir { Up = "UP" } let d: Dir = "UP";`,
+			`Type '"UP"' is not assignable to type 'Dir'.`},
+		{"tuple element out of range", `const [a, b]: [number] = [1];`,
+			`Tuple type '[number]' of length '1' has no element at index '1'.`},
+		{"default of another type", `const [y = 3] = ["q"];`,
+			`Type '3' is not assignable to type 'string'.`},
+		{"default of annotated element", `const [x = "s"]: [number] = [1];`,
+			`Type '"s"' is not assignable to type 'number'.`},
+		{"default outside literal union", `const [k = "c"]: ["a" | "b"] = ["a"];`,
+			`Type '"c"' is not assignable to type '"a" | "b"'.`},
+		{"missing property in a pattern", `const { z } = { a: 1 };`,
+			`Property 'z' does not exist on type`},
+		{"number as object type", `let o: { length: number } = 5;`,
+			`Type '5' is not assignable to type '{ length: number; }'.`},
+		{"string missing property", `let o: { size: number } = "abc";`,
+			`Type '"abc"' is not assignable to type '{ size: number; }'.`},
+		{"null as object type", `let o: { x: number } = null;`,
+			`Type 'null' is not assignable to type '{ x: number; }'.`},
+		{"unsatisfied constraint", `
+			function lo<T extends { length: number }>(x: T): number { return x.length; }
+			lo(10);`,
+			`Argument of type '10' is not assignable to parameter of type '{ length: number; }'.`},
+		{"mixed literal inference", `
+			function c2<T extends string>(a: T, b: T): T { return b; }
+			c2("x", 1);`,
+			`Argument of type '1' is not assignable to parameter of type 'string'.`},
+		{"rest of the wrong type", `function sum(...nums: number[]): void {} sum(1, "a");`,
+			`Argument of type '"a"' is not assignable to parameter of type 'number'.`},
+		{"possibly null member", `function f(o: { a: string | null }): number { return o.a.length; }`,
+			`'o.a' is possibly 'null'.`},
+		{"possibly null element", `function f(a: (string | null)[]): number { return a[0].length; }`,
+			`'a[0]' is possibly 'null'.`},
+		{"optional is possibly undefined", `function f(y?: string): number { return y.length; }`,
+			`'y' is possibly`},
+		{"default of the wrong type", `function f(x: number = "a"): void {}`,
+			`Type '"a"' is not assignable to type 'number'.`},
+		{"undefined is not null", `let v: string | null = undefined;`,
+			`Type 'undefined' is not assignable to type 'string | null'.`},
+		{"arithmetic on a string", `let n: number = "a" * 2;`,
+			`The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type.`},
+		{"use before declaration", `let y: number = x; let x: number = 1;`,
+			`Block-scoped variable 'x' used before its declaration.`},
+		{"push of wrong type", `const nums: number[] = [1]; nums.push("x");`,
+			`Argument of type '"x"' is not assignable to parameter of type 'number'.`},
+		{"unknown array method", `const nums: number[] = [1]; nums.nope();`,
+			`Property 'nope' does not exist on type 'number[]'.`},
+		{"pop may be undefined", `const nums: number[] = [1]; const n: number = nums.pop();`,
+			`Type 'number | undefined' is not assignable to type 'number'.`},
+		{"Array type argument", `const s: string[] = new Array<number>(3);`,
+			`Type 'number[]' is not assignable to type 'string[]'.`},
+		{"switch not exhaustive", `
+			type Shape = "circle" | "square";
+			function sides(s: Shape): number {
+				switch (s) {
+					case "circle": return 0;
+				}
+			}`,
+			`Function lacks ending return statement`},
+		{"case of another type", `let n: number = 1; switch (n) { case "a": break; }`,
+			`Type '"a"' is not comparable to type 'number'.`},
+		{"Promise.resolve of another type", `let p: Promise<string> = Promise.resolve(1);`,
+			`Type 'Promise<number>' is not assignable to type 'Promise<string>'.`},
+		{"then callback of another type", `let p: Promise<number> = Promise.resolve(1); p.then((n: string) => n);`,
+			`Argument of type '(n: string) => string' is not assignable to parameter`},
+		{"Promise.all element type", `
+			async function f(nums: Promise<number>[]): Promise<void> {
+				const xs: string[] = await Promise.all(nums);
+			}`,
+			`Type 'number[]' is not assignable to type 'string[]'.`},
+		{"key not in keyof", `type K = keyof { a: number; b: string }; let k: K = "c";`,
+			`Type '"c"' is not assignable to type '"a" | "b"'.`},
+		{"indexed access by a union", `interface P { x: number; y: string; z: boolean } let v: P["x" | "y"] = true;`,
+			`Type 'true' is not assignable to type 'number | string'.`},
+		{"indexed access by a missing key", `interface P { x: number } let v: P["y"] = 1;`,
+			`Property 'y' does not exist on type 'P'.`},
+		{"property of a number", `let n: number = 1; let l: number = n.length;`,
+			`Property 'length' does not exist on type 'number'.`},
+		{"string method", `let s: string = "a"; let u: string = s.toUpperCase();`,
+			`Property 'toUpperCase' does not exist on type 'string'.`},
+		{"property of a boolean", `let b: boolean = true; let x: any = b.x;`,
+			`Property 'x' does not exist on type 'boolean'.`},
+		{"String is not callable", `let s: any = String(1);`,
+			`This expression is not callable.`},
+		{"unknown Number static", `let n: any = Number.nope;`,
+			`Property 'nope' does not exist on type 'NumberConstructor'.`},
+		{"second declarator", `let a: number = 1, b: string = 2;`,
+			`Type '2' is not assignable to type 'string'.`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := check(t, tt.src)
+			if len(errs) == 0 {
+				t.Fatalf("expected an error containing %q, got none", tt.want)
+			}
+			if !strings.Contains(errs[0], tt.want) {
+				t.Errorf("expected an error containing %q, got %q", tt.want, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package typecheck
+
+import (
+	"ts-engine/ast"
+)
+
+// declareEnum declares an enum both as a type and as the value of its
+// object, before the block it is in is checked.
+func (c *checker) declareEnum(decl *ast.EnumDeclaration) {
+	enum := &Enum{Name: decl.Name.Value, Const: decl.Const}
+	for _, m := range decl.Members {
+		member := &EnumMember{Enum: enum, Name: m.Name}
+		switch value := m.Value.(type) {
+		case *ast.NumberLiteral:
+			member.Value = value.Value
+		case *ast.StringLiteral:
+			member.Value = value.Value
+		}
+		enum.Members = append(enum.Members, member)
+	}
+	c.scope.types[enum.Name] = &namedType{typ: enum}
+	c.scope.declare(enum.Name, &EnumObject{Enum: enum}, true)
+}
+
+// enumDeclaration checks the computed initializers of an enum's members,
+// which may refer to the members before them by name.
+func (c *checker) enumDeclaration(decl *ast.EnumDeclaration) {
+	nt := c.scope.types[decl.Name.Value]
+	enum, ok := nt.typ.(*Enum)
+	if !ok {
+		return
+	}
+
+	outer := c.scope
+	c.scope = newScope(outer)
+	for i, m := range decl.Members {
+		if m.Computed {
+			if t := c.expr(m.Value); !isNumeric(t) {
+				c.errorAt(m.Value, "Type '%s' is not assignable to type 'number' as required for computed enum member values.", t)
+			}
+		}
+		c.scope.declare(m.Name, enum.Members[i], true)
+	}
+	c.scope = outer
+}
+
+// enumMember is the type of Enum.name, where the enum is declared.
+func (c *checker) enumMember(enum *Enum, name string, node ast.Node) Type {
+	if m, ok := enum.member(name); ok {
+		return m
+	}
+	c.errorAt(node, "Property '%s' does not exist on type 'typeof %s'.", name, enum.Name)
+	return Any
+}
@@ -0,0 +1,519 @@
+package typecheck
+
+import (
+	"fmt"
+	"ts-engine/ast"
+)
+
+// typeParams declares the type parameters of a generic declaration in the
+// current scope, then resolves their constraints and defaults, which may
+// refer to each other as in <T, K extends keyof T>.
+func (c *checker) typeParams(nodes []*ast.TypeParam) []*TypeParam {
+	if len(nodes) == 0 {
+		return nil
+	}
+	params := make([]*TypeParam, len(nodes))
+	for i, node := range nodes {
+		params[i] = &TypeParam{Name: node.Name}
+	}
+	c.declareTypeParams(params)
+	for i, node := range nodes {
+		if node.Constraint != nil {
+			params[i].Constraint = c.resolveType(node.Constraint)
+		}
+		if node.Default != nil {
+			params[i].Default = c.resolveType(node.Default)
+		}
+	}
+	return params
+}
+
+// declareTypeParams makes type parameters known by name in the current
+// scope, as in the body of the function or class they belong to.
+func (c *checker) declareTypeParams(params []*TypeParam) {
+	for _, param := range params {
+		c.scope.types[param.Name] = &namedType{typ: param}
+	}
+}
+
+// classTypeParams resolves the type parameters of a generic class, once,
+// in a scope of their own.
+func (c *checker) classTypeParams(cls *Class, lit *ast.ClassLiteral) {
+	if len(lit.TypeParams) == 0 || cls.TypeParams != nil {
+		return
+	}
+	outer := c.scope
+	c.scope = newScope(outer)
+	cls.TypeParams = c.typeParams(lit.TypeParams)
+	c.scope = outer
+
+	instance := cls.Instance.(*Object)
+	for _, param := range cls.TypeParams {
+		instance.Args = append(instance.Args, param)
+	}
+	cls.Construct.TypeParams = cls.TypeParams
+}
+
+// bindTypeArgs binds the type parameters of the generic type name to the
+// type arguments it is given, falling back to their defaults. It reports
+// a wrong number of arguments or an argument that does not satisfy its
+// constraint at node, and returns nil if the arguments do not fit.
+func (c *checker) bindTypeArgs(name string, params []*TypeParam, args []Type, node ast.Node) map[*TypeParam]Type {
+	required := 0
+	for _, param := range params {
+		if param.Default == nil {
+			required++
+		}
+	}
+	if len(args) < required || len(args) > len(params) {
+		generic := name + typeList(params)
+		if required == len(params) {
+			c.errorAt(node, "Generic type '%s' requires %d type argument(s).", generic, len(params))
+		} else {
+			c.errorAt(node, "Generic type '%s' requires between %d and %d type arguments.", generic, required, len(params))
+		}
+		return nil
+	}
+
+	bindings := map[*TypeParam]Type{}
+	for i, param := range params {
+		if i < len(args) {
+			bindings[param] = args[i]
+		} else {
+			bindings[param] = substitute(param.Default, bindings)
+		}
+	}
+	if !c.satisfies(params, bindings, node) {
+		return nil
+	}
+	return bindings
+}
+
+// satisfies checks that the types bound to params satisfy their
+// constraints, reporting those that do not at node.
+func (c *checker) satisfies(params []*TypeParam, bindings map[*TypeParam]Type, node ast.Node) bool {
+	ok := true
+	for _, param := range params {
+		if param.Constraint == nil {
+			continue
+		}
+		if constraint := substitute(param.Constraint, bindings); !assignable(bindings[param], constraint) {
+			c.errorAt(node, "Type '%s' does not satisfy the constraint '%s'.", bindings[param], constraint)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// applyTypeArgs instantiates the generic type name, whose type parameters
+// are params, with args: Box<number> for Box<T>. Within its own
+// declaration, Box<T> is the generic type itself.
+func (c *checker) applyTypeArgs(name string, params []*TypeParam, generic Type, args []Type, node ast.Node) Type {
+	if len(params) == 0 {
+		if len(args) > 0 {
+			c.errorAt(node, "Type '%s' is not generic.", name)
+		}
+		return generic
+	}
+
+	if ownParams(params, args) {
+		return generic
+	}
+
+	bindings := c.bindTypeArgs(name, params, args, node)
+	if bindings == nil {
+		return Any
+	}
+	return substitute(generic, bindings)
+}
+
+// ownParams reports whether args are the type parameters params
+// themselves, as in Box<T> in the declaration of Box<T>.
+func ownParams(params []*TypeParam, args []Type) bool {
+	if len(args) != len(params) {
+		return false
+	}
+	for i := range args {
+		if args[i] != params[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// deferTypeArgs is applyTypeArgs for a generic type whose members are not
+// all known yet, as Box<string> in the body of Box<T>, or in annotations
+// resolved before the class Box<T> is checked. The instance is filled in
+// by one of pending once they are.
+func (c *checker) deferTypeArgs(pending *[]func(), name string, params []*TypeParam, generic Type, args []Type, node ast.Node) Type {
+	obj, ok := generic.(*Object)
+	if !ok || len(params) == 0 || ownParams(params, args) {
+		return c.applyTypeArgs(name, params, generic, args, node)
+	}
+	instance := &Object{Name: obj.Name, Args: args, Props: map[string]Type{}}
+	*pending = append(*pending, func() {
+		if t, ok := c.applyTypeArgs(name, params, generic, args, node).(*Object); ok {
+			*instance = *t
+		}
+	})
+	return instance
+}
+
+// instantiate returns the generic function fn with its type parameters
+// replaced by the type arguments of a call: the explicit ones in
+// f<number>(x), or else those inferred from the types of its arguments.
+func (c *checker) instantiate(fn *Function, explicit []ast.TypeNode, args []Type, node ast.Node) *Function {
+	params := fn.TypeParams
+	bindings := map[*TypeParam]Type{}
+
+	if explicit != nil {
+		required := 0
+		for _, param := range params {
+			if param.Default == nil {
+				required++
+			}
+		}
+		if len(explicit) < required || len(explicit) > len(params) {
+			expected := fmt.Sprint(len(params))
+			if required != len(params) {
+				expected = fmt.Sprintf("%d-%d", required, len(params))
+			}
+			c.errorAt(node, "Expected %s type arguments, but got %d.", expected, len(explicit))
+			return erase(fn)
+		}
+		for i, param := range params {
+			if i < len(explicit) {
+				bindings[param] = c.resolveType(explicit[i])
+			} else {
+				bindings[param] = substitute(param.Default, bindings)
+			}
+		}
+		if !c.satisfies(params, bindings, node) {
+			return erase(fn)
+		}
+	} else {
+		inferred := map[*TypeParam]Type{}
+		for _, param := range params {
+			inferred[param] = nil
+		}
+		for i, arg := range args {
+			param := fn.Rest
+			if i < len(fn.Params) {
+				param = fn.Params[i].Type
+			}
+			if param == nil {
+				break
+			}
+			inferTypeArgs(param, arg, inferred)
+		}
+
+		for _, param := range params {
+			t := inferred[param]
+			switch {
+			case t == nil && param.Default != nil:
+				t = substitute(param.Default, bindings)
+			case t == nil:
+				t = Unknown
+			case !keepsLiterals(param.Constraint):
+				t = widen(t)
+			}
+			// An argument that does not satisfy the constraint is reported
+			// as not assignable to the parameter
+			if param.Constraint != nil && !assignable(t, substitute(param.Constraint, bindings)) {
+				t = substitute(param.Constraint, bindings)
+			}
+			bindings[param] = t
+		}
+	}
+
+	generic := *fn
+	generic.TypeParams = nil
+	return substitute(&generic, bindings).(*Function)
+}
+
+// keepsLiterals reports whether the types inferred for a type parameter
+// with this constraint keep their literal types, as in
+// <K extends string>, rather than being widened.
+func keepsLiterals(constraint Type) bool {
+	for _, member := range members(constraint) {
+		switch member.(type) {
+		case *Primitive, *Literal:
+			if !isNullish(member) && member != Void {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inferTypeArgs infers the type parameters in param from the type arg of
+// the value given for it. The first type inferred for each wins, except
+// that literals are joined: c("x", "y") infers "x" | "y" for
+// c<T extends string>(a: T, b: T).
+func inferTypeArgs(param, arg Type, inferred map[*TypeParam]Type) {
+	switch param := param.(type) {
+	case *TypeParam:
+		t, ok := inferred[param]
+		switch {
+		case !ok:
+		case t == nil:
+			inferred[param] = arg
+		case onlyLiterals(t) && onlyLiterals(arg):
+			inferred[param] = unionOf(t, arg)
+		}
+
+	case *Array:
+		switch arg := arg.(type) {
+		case *Array:
+			inferTypeArgs(param.Elem, arg.Elem, inferred)
+		case *Tuple:
+			inferTypeArgs(param.Elem, unionOf(arg.Elems...), inferred)
+		}
+
+	case *Tuple:
+		if arg, ok := arg.(*Tuple); ok && len(arg.Elems) == len(param.Elems) {
+			for i := range param.Elems {
+				inferTypeArgs(param.Elems[i], arg.Elems[i], inferred)
+			}
+		}
+
+	case *Promise:
+		if arg, ok := arg.(*Promise); ok {
+			inferTypeArgs(param.Value, arg.Value, inferred)
+		}
+
+	case *Function:
+		if arg, ok := arg.(*Function); ok {
+			for i := range param.Params {
+				if i < len(arg.Params) {
+					inferTypeArgs(param.Params[i].Type, arg.Params[i].Type, inferred)
+				}
+			}
+			inferTypeArgs(param.Return, arg.Return, inferred)
+		}
+
+	case *Object:
+		if arg, ok := arg.(*Object); ok {
+			for _, name := range param.properties() {
+				p, _ := param.Lookup(name)
+				if a, ok := arg.Lookup(name); ok {
+					inferTypeArgs(p, a, inferred)
+				}
+			}
+		}
+
+	case *Union:
+		// T | null given string | null infers string for T
+		var fixed []Type
+		var open *TypeParam
+		for _, member := range param.Types {
+			if tp, ok := member.(*TypeParam); ok {
+				open = tp
+			} else {
+				fixed = append(fixed, member)
+			}
+		}
+		if open == nil {
+			return
+		}
+		// T | T[] given number[] infers number for T
+		if arg, ok := arg.(*Array); ok {
+			for _, f := range fixed {
+				if f, ok := f.(*Array); ok && f.Elem == open {
+					inferTypeArgs(open, arg.Elem, inferred)
+					return
+				}
+			}
+		}
+		// T | Promise<T> given Promise<number> | string infers
+		// number | string for T
+		for _, f := range fixed {
+			if f, ok := f.(*Promise); ok && f.Value == open {
+				inferTypeArgs(open, awaited(arg), inferred)
+				return
+			}
+		}
+		rest := filter(arg, func(member Type) bool {
+			for _, f := range fixed {
+				if assignable(member, f) {
+					return false
+				}
+			}
+			return true
+		})
+		if rest != Never {
+			inferTypeArgs(open, rest, inferred)
+		}
+	}
+}
+
+// onlyLiterals reports whether t is a literal type or a union of them.
+func onlyLiterals(t Type) bool {
+	for _, member := range members(t) {
+		if _, ok := member.(*Literal); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// erase is a generic function with its type parameters standing for any
+// type, for comparing it with other function types.
+func erase(fn *Function) *Function {
+	if len(fn.TypeParams) == 0 {
+		return fn
+	}
+	bindings := map[*TypeParam]Type{}
+	for _, param := range fn.TypeParams {
+		bindings[param] = Any
+	}
+	erased := *fn
+	erased.TypeParams = nil
+	return substitute(&erased, bindings).(*Function)
+}
+
+// apparent is the type whose operations a value of type t supports: the
+// constraint of a type parameter, and the primitive type of a literal or
+// enum.
+func apparent(t Type) Type {
+	if tp, ok := t.(*TypeParam); ok {
+		if tp.Constraint == nil {
+			return Unknown
+		}
+		t = tp.Constraint
+	}
+	if enum, ok := widen(t).(*Enum); ok {
+		return enum.base()
+	}
+	return widen(t)
+}
+
+// substitution replaces type parameters with the types bound to them.
+// Objects are copied once each, so that recursive types terminate.
+type substitution struct {
+	bindings map[*TypeParam]Type
+	copies   map[*Object]*Object
+	mentions map[Type]bool
+}
+
+// substitute replaces the type parameters in t with the types bound to
+// them. Parts of t that do not mention them are kept as they are.
+func substitute(t Type, bindings map[*TypeParam]Type) Type {
+	s := &substitution{bindings: bindings, copies: map[*Object]*Object{}, mentions: map[Type]bool{}}
+	return s.apply(t)
+}
+
+func (s *substitution) apply(t Type) Type {
+	if t == nil || !s.mention(t, map[Type]bool{}) {
+		return t
+	}
+
+	switch t := t.(type) {
+	case *TypeParam:
+		return s.bindings[t]
+
+	case *Array:
+		return &Array{Elem: s.apply(t.Elem)}
+
+	case *Tuple:
+		tuple := &Tuple{}
+		for _, el := range t.Elems {
+			tuple.Elems = append(tuple.Elems, s.apply(el))
+		}
+		return tuple
+
+	case *Union:
+		types := make([]Type, len(t.Types))
+		for i, member := range t.Types {
+			types[i] = s.apply(member)
+		}
+		return unionOf(types...)
+
+	case *Promise:
+		return &Promise{Value: s.apply(t.Value)}
+
+	case *Function:
+		fn := &Function{TypeParams: t.TypeParams, Rest: s.apply(t.Rest), Return: s.apply(t.Return)}
+		for _, param := range t.Params {
+			param.Type = s.apply(param.Type)
+			fn.Params = append(fn.Params, param)
+		}
+		if t.Guard != nil {
+			fn.Guard = &Guard{Param: t.Guard.Param, Type: s.apply(t.Guard.Type)}
+		}
+		return fn
+
+	case *Object:
+		if obj, ok := s.copies[t]; ok {
+			return obj
+		}
+		obj := &Object{Name: t.Name, Props: map[string]Type{}, Optional: t.Optional, Readonly: t.Readonly, Access: t.Access}
+		s.copies[t] = obj
+		for _, arg := range t.Args {
+			obj.Args = append(obj.Args, s.apply(arg))
+		}
+		for name, p := range t.Props {
+			obj.Props[name] = s.apply(p)
+		}
+		obj.Index = s.apply(t.Index)
+		if t.Base != nil {
+			obj.Base = s.apply(t.Base).(*Object)
+		}
+		return obj
+	}
+	return t
+}
+
+// mention reports whether t mentions any of the bound type parameters.
+// seen holds the objects being looked at further up.
+func (s *substitution) mention(t Type, seen map[Type]bool) bool {
+	if m, ok := s.mentions[t]; ok {
+		return m
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	m := false
+	visit := func(types ...Type) {
+		for _, t := range types {
+			if t != nil && !m {
+				m = s.mention(t, seen)
+			}
+		}
+	}
+	switch t := t.(type) {
+	case *TypeParam:
+		_, m = s.bindings[t]
+	case *Array:
+		visit(t.Elem)
+	case *Tuple:
+		visit(t.Elems...)
+	case *Union:
+		visit(t.Types...)
+	case *Promise:
+		visit(t.Value)
+	case *Function:
+		for _, param := range t.Params {
+			visit(param.Type)
+		}
+		visit(t.Rest, t.Return)
+		if t.Guard != nil {
+			visit(t.Guard.Type)
+		}
+	case *Object:
+		visit(t.Args...)
+		for _, p := range t.Props {
+			visit(p)
+		}
+		visit(t.Index)
+		if t.Base != nil {
+			visit(t.Base)
+		}
+	}
+	if m {
+		s.mentions[t] = true
+	}
+	return m
+}
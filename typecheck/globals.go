@@ -0,0 +1,96 @@
+package typecheck
+
+// globals returns the scope holding the types of the evaluator's builtins.
+func globals() *scope {
+	s := newScope(nil)
+
+	timer := &Function{
+		Params: []Param{{Name: "callback", Type: Any}, {Name: "ms", Type: Number, Optional: true}},
+		Rest:   Any,
+		Return: Number,
+	}
+	clear := &Function{Params: []Param{{Name: "id", Type: Any, Optional: true}}, Return: Void}
+
+	s.declare("console", &Object{Name: "Console", Props: map[string]Type{
+		"log": &Function{Rest: Any, Return: Void},
+	}}, true)
+	s.declare("fetch", &Function{Params: []Param{{Name: "url", Type: String}}, Return: &Promise{Value: Any}}, true)
+	s.declare("require", &Function{Params: []Param{{Name: "moduleName", Type: String}}, Return: Any}, true)
+	s.declare("setTimeout", timer, true)
+	s.declare("setInterval", timer, true)
+	s.declare("setImmediate", &Function{Params: []Param{{Name: "callback", Type: Any}}, Rest: Any, Return: Number}, true)
+	s.declare("clearTimeout", clear, true)
+	s.declare("clearInterval", clear, true)
+	s.declare("clearImmediate", clear, true)
+	s.declare("queueMicrotask", &Function{Params: []Param{{Name: "callback", Type: Any}}, Return: Void}, true)
+	s.declare("undefined", Undefined, true)
+	s.declare("NaN", Number, true)
+	s.declare("Infinity", Number, true)
+	numberPredicate := &Function{Params: []Param{{Name: "number", Type: Any}}, Return: Boolean}
+	s.declare("Number", &Object{Name: "NumberConstructor", Props: map[string]Type{
+		"NaN":               Number,
+		"POSITIVE_INFINITY": Number,
+		"NEGATIVE_INFINITY": Number,
+		"MAX_SAFE_INTEGER":  Number,
+		"MIN_SAFE_INTEGER":  Number,
+		"MAX_VALUE":         Number,
+		"MIN_VALUE":         Number,
+		"EPSILON":           Number,
+		"isNaN":             numberPredicate,
+		"isFinite":          numberPredicate,
+		"isInteger":         numberPredicate,
+		"isSafeInteger":     numberPredicate,
+	}}, true)
+	s.declare("String", &Object{Name: "StringConstructor", Props: map[string]Type{
+		"raw": &Function{Params: []Param{{Name: "template", Type: Any}}, Rest: Any, Return: String},
+	}}, true)
+	s.declare("JSON", &Object{Name: "JSON", Props: map[string]Type{
+		"stringify": &Function{
+			Params: []Param{
+				{Name: "value", Type: Any},
+				{Name: "replacer", Type: Any, Optional: true},
+				{Name: "space", Type: Any, Optional: true},
+			},
+			Return: String,
+		},
+	}}, true)
+
+	s.declare("Promise", promiseClass(), true)
+
+	s.declare("Array", arrayClass(), true)
+
+	errorClass := newErrorClass("Error", nil)
+	s.declare("Error", errorClass, true)
+	for _, name := range []string{"TypeError", "RangeError", "SyntaxError", "ReferenceError"} {
+		s.declare(name, newErrorClass(name, errorClass), true)
+	}
+	aggregate := newErrorClass("AggregateError", errorClass)
+	aggregate.Instance.(*Object).Props["errors"] = &Array{Elem: Any}
+	aggregate.Construct.Params = []Param{
+		{Name: "errors", Type: Any},
+		{Name: "message", Type: String, Optional: true},
+	}
+	s.declare("AggregateError", aggregate, true)
+
+	return s
+}
+
+func newErrorClass(name string, super *Class) *Class {
+	instance := &Object{Name: name, Props: map[string]Type{}}
+	if super == nil {
+		instance.Props["name"] = String
+		instance.Props["message"] = String
+		instance.Props["stack"] = String
+	} else {
+		instance.Base = super.Instance.(*Object)
+	}
+
+	return &Class{
+		Name:      name,
+		Super:     super,
+		Instance:  instance,
+		Statics:   &Object{Props: map[string]Type{}},
+		Construct: &Function{Params: []Param{{Name: "message", Type: Any, Optional: true}}, Return: instance},
+		Callable:  true,
+	}
+}
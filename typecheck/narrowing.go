@@ -0,0 +1,429 @@
+package typecheck
+
+import (
+	"strings"
+	"ts-engine/ast"
+)
+
+// narrowing maps references to the narrower types they are known to have
+// where a condition holds, as x is a string in if (typeof x === "string").
+// References are variables, or properties of them such as r.body, keyed by
+// their path.
+type narrowing map[string]Type
+
+// path is the key a reference is narrowed by: the name of a variable, or
+// a dotted path such as r.body or this.x, or "" if expr is not one.
+func path(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Value
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.InfixExpression:
+		if expr.Operator != "." {
+			break
+		}
+		if left := path(expr.Left); left != "" {
+			return left + "." + expr.Right.(*ast.Identifier).Value
+		}
+	}
+	return ""
+}
+
+// lookupPath is the type the property at path p has been narrowed to, or
+// nil if it has not been, or the variable it starts from is shadowed by
+// one declared since.
+func (s *scope) lookupPath(p string) Type {
+	root, _, _ := strings.Cut(p, ".")
+	for sc := s; sc != nil; sc = sc.outer {
+		if t, ok := sc.paths[p]; ok {
+			return t
+		}
+		if sym, ok := sc.vars[root]; ok && sym.narrows == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// forget drops the narrowings of the reference at path p and of the
+// properties under it, once it is assigned to.
+func (c *checker) forget(p string) {
+	for sc := c.scope; sc != nil; sc = sc.outer {
+		for narrowed := range sc.paths {
+			if narrowed == p || strings.HasPrefix(narrowed, p+".") {
+				delete(sc.paths, narrowed)
+			}
+		}
+	}
+}
+
+// narrowed runs check with the variables in n narrowed to their types.
+func (c *checker) narrowed(n narrowing, check func()) {
+	outer := c.scope
+	c.scope = newScope(outer)
+	c.narrow(n)
+	check()
+	c.scope = outer
+}
+
+// narrow narrows references in the current scope, for the rest of it.
+func (c *checker) narrow(n narrowing) {
+	for name, t := range n {
+		if strings.Contains(name, ".") {
+			c.scope.paths[name] = t
+			continue
+		}
+		sym := c.scope.lookup(name)
+		if sym == nil {
+			continue
+		}
+		declared := sym
+		if sym.narrows != nil {
+			declared = sym.narrows
+		}
+		c.scope.vars[name] = &symbol{typ: t, constant: sym.constant, narrows: declared}
+	}
+}
+
+// narrowing works out the types references have when cond is truthy, or
+// falsy if assume is false. The condition has been checked already.
+func (c *checker) narrowing(cond ast.Expression, assume bool) narrowing {
+	switch cond := cond.(type) {
+	case *ast.Identifier:
+		return c.truthiness(cond, assume)
+
+	case *ast.PrefixExpression:
+		if cond.Operator == "!" {
+			return c.narrowing(cond.Right, !assume)
+		}
+
+	case *ast.InfixExpression:
+		switch cond.Operator {
+		case ".":
+			return c.truthiness(cond, assume)
+
+		case "&&", "||":
+			// a && b holds if both a and b do, and fails if either fails
+			if (cond.Operator == "&&") == assume {
+				left := c.narrowing(cond.Left, assume)
+				var right narrowing
+				c.narrowed(left, func() { right = c.narrowing(cond.Right, assume) })
+				for name, t := range right {
+					left[name] = t
+				}
+				return left
+			}
+			return either(c.narrowing(cond.Left, assume), c.narrowing(cond.Right, assume))
+
+		case "===", "!==", "==", "!=":
+			equal := cond.Operator == "===" || cond.Operator == "=="
+			loose := cond.Operator == "==" || cond.Operator == "!="
+			return c.equality(cond.Left, cond.Right, equal == assume, loose)
+
+		case "instanceof":
+			name, t := c.current(cond.Left)
+			if cls, ok := c.types[cond.Right].(*Class); ok && t != nil {
+				return narrowing{name: narrowTo(t, anyInstance(cls), assume)}
+			}
+
+		case "in":
+			key, ok := cond.Left.(*ast.StringLiteral)
+			name, t := c.current(cond.Right)
+			if !ok || t == nil {
+				return nil
+			}
+			return narrowing{name: filter(t, func(member Type) bool {
+				obj, ok := member.(*Object)
+				if !ok {
+					return member == Any || member == Unknown
+				}
+				if assume {
+					_, has := obj.Lookup(key.Value)
+					return has
+				}
+				return !obj.required(key.Value)
+			})}
+		}
+
+	case *ast.CallExpression:
+		fn, ok := c.types[cond.Function].(*Function)
+		if !ok || fn.Guard == nil || fn.Guard.Param >= len(cond.Arguments) {
+			return nil
+		}
+		name, t := c.current(cond.Arguments[fn.Guard.Param])
+		if t == nil {
+			return nil
+		}
+		return narrowing{name: narrowTo(t, fn.Guard.Type, assume)}
+	}
+
+	return nil
+}
+
+// truthiness narrows a reference used as a condition to its truthy or
+// falsy members.
+func (c *checker) truthiness(ref ast.Expression, assume bool) narrowing {
+	name, t := c.current(ref)
+	if t == nil {
+		return nil
+	}
+	if assume {
+		return narrowing{name: truthy(t)}
+	}
+	return narrowing{name: falsy(t)}
+}
+
+// equality narrows on a comparison of left and right, which holds if
+// equal is true, against typeof x, null, undefined or a literal. A loose
+// comparison with null or undefined matches both.
+func (c *checker) equality(left, right ast.Expression, equal, loose bool) narrowing {
+	switch left.(type) {
+	case *ast.Identifier, *ast.PrefixExpression, *ast.InfixExpression:
+	default:
+		// null === x
+		left, right = right, left
+	}
+
+	// typeof x === "string"
+	if prefix, ok := left.(*ast.PrefixExpression); ok && prefix.Operator == "typeof" {
+		name, t := c.current(prefix.Right)
+		typeName, isString := right.(*ast.StringLiteral)
+		if !isString || t == nil {
+			return nil
+		}
+		return narrowing{name: narrowTypeof(t, typeName.Value, equal)}
+	}
+
+	// shape.kind === "circle", for a union of objects told apart by kind
+	if member, ok := left.(*ast.InfixExpression); ok && member.Operator == "." {
+		obj, t := c.current(member.Left)
+		value := c.types[right]
+		if _, isLiteral := value.(*Literal); isLiteral && t != nil {
+			if _, isUnion := t.(*Union); isUnion {
+				key := member.Right.(*ast.Identifier).Value
+				return narrowing{obj: filter(t, func(t Type) bool {
+					p, ok := propertyType(t, key)
+					if !ok {
+						return false
+					}
+					if equal {
+						return overlaps(p, value)
+					}
+					lit, isLiteral := p.(*Literal)
+					return !isLiteral || !assignable(value, lit)
+				})}
+			}
+		}
+	}
+
+	name, t := c.current(left)
+	if t == nil {
+		return nil
+	}
+
+	switch value := c.types[right].(type) {
+	case *EnumMember:
+		if t == Any || t == Unknown {
+			return nil
+		}
+		return narrowing{name: filter(enumMembers(t), func(member Type) bool {
+			if equal {
+				return assignable(value, member)
+			}
+			return member != value
+		})}
+	case *Literal:
+		if !equal {
+			return narrowing{name: filter(t, func(member Type) bool {
+				lit, ok := member.(*Literal)
+				return !ok || lit.Value != value.Value
+			})}
+		}
+		if t == Any || t == Unknown {
+			return nil
+		}
+		return narrowing{name: filter(t, func(member Type) bool {
+			return assignable(value, member)
+		})}
+	}
+
+	if empty := c.types[right]; empty == Null || empty == Undefined {
+		matches := func(member Type) bool { return member == empty || loose && isNullish(member) }
+		if equal {
+			return narrowing{name: filter(t, func(member Type) bool { return matches(member) || member == Any || member == Unknown })}
+		}
+		return narrowing{name: filter(t, func(member Type) bool { return !matches(member) })}
+	}
+	return nil
+}
+
+// current is the path of a reference and the type it has here, or nil if
+// ref is not a reference to a declared variable or a property of one.
+func (c *checker) current(ref ast.Expression) (string, Type) {
+	name := path(ref)
+	switch ref := ref.(type) {
+	case *ast.Identifier:
+		if sym := c.scope.lookup(ref.Value); sym != nil {
+			return name, sym.typ
+		}
+	case *ast.InfixExpression:
+		if t, ok := c.types[ref]; ok && name != "" {
+			return name, t
+		}
+	}
+	return "", nil
+}
+
+// enumMembers is t with its enums replaced by the union of their members,
+// so that the members can be narrowed one by one.
+func enumMembers(t Type) Type {
+	var expanded []Type
+	for _, member := range members(t) {
+		if enum, ok := member.(*Enum); ok {
+			for _, m := range enum.Members {
+				expanded = append(expanded, m)
+			}
+			continue
+		}
+		expanded = append(expanded, member)
+	}
+	return unionOf(expanded...)
+}
+
+// either is the narrowing where one of a and b holds: only the variables
+// both narrow are narrowed, to the union of their types.
+func either(a, b narrowing) narrowing {
+	n := narrowing{}
+	for name, t := range a {
+		if other, ok := b[name]; ok {
+			n[name] = unionOf(t, other)
+		}
+	}
+	return n
+}
+
+// anyInstance is the type of the instances of cls, with its type
+// parameters standing for any: x instanceof Array makes x an any[].
+func anyInstance(cls *Class) Type {
+	bindings := map[*TypeParam]Type{}
+	for _, param := range cls.TypeParams {
+		bindings[param] = Any
+	}
+	return substitute(cls.Instance, bindings)
+}
+
+// narrowTo narrows t to the members of type target, or to target if none
+// of them is, as instanceof and type guards do. If is is false, it leaves
+// those members out instead.
+func narrowTo(t, target Type, is bool) Type {
+	if !is {
+		if _, ok := t.(*Union); !ok {
+			return t
+		}
+		return filter(t, func(member Type) bool { return !assignable(member, target) })
+	}
+	if t == Any || t == Unknown {
+		return target
+	}
+	if narrowed := filter(t, func(member Type) bool { return assignable(member, target) }); narrowed != Never {
+		return narrowed
+	}
+	return target
+}
+
+// narrowTypeof narrows t to the members whose typeof is name, or to the
+// others if is is false.
+func narrowTypeof(t Type, name string, is bool) Type {
+	if t == Any || apparent(t) == Unknown {
+		if !is {
+			return t
+		}
+		switch name {
+		case "string":
+			return String
+		case "number":
+			return Number
+		case "boolean":
+			return Boolean
+		}
+		return t
+	}
+	return filter(t, func(member Type) bool {
+		return (typeofName(member) == name) == is
+	})
+}
+
+// typeofName is what the typeof operator gives for values of type t.
+func typeofName(t Type) string {
+	switch t := apparent(t).(type) {
+	case *Function, *Class:
+		return "function"
+	case *Primitive:
+		switch t {
+		case Null:
+			return "object"
+		case Void:
+			return "undefined"
+		}
+		return t.Name
+	}
+	return "object"
+}
+
+// truthy is t without the members whose values are all falsy.
+func truthy(t Type) Type {
+	return filter(t, func(member Type) bool {
+		if lit, ok := member.(*Literal); ok {
+			return lit.Value != false && lit.Value != "" && lit.Value != 0.0
+		}
+		return !isNullish(member) && member != Void
+	})
+}
+
+// falsy is the members of t that may have falsy values.
+func falsy(t Type) Type {
+	return filter(t, func(member Type) bool {
+		switch member := member.(type) {
+		case *Literal:
+			return member.Value == false || member.Value == "" || member.Value == 0.0
+		case *Primitive:
+			return true
+		}
+		return false
+	})
+}
+
+func isNullish(t Type) bool {
+	return t == Null || t == Undefined
+}
+
+// nullable reports whether t includes null or undefined.
+func nullable(t Type) bool {
+	for _, member := range members(t) {
+		if isNullish(member) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNullable is t without null and undefined.
+func nonNullable(t Type) Type {
+	return filter(t, func(member Type) bool { return !isNullish(member) })
+}
+
+// nullishNames names the empty values of t for errors: 'null', 'undefined'
+// or both.
+func nullishNames(t Type) string {
+	var names []string
+	for _, empty := range []Type{Null, Undefined} {
+		for _, member := range members(t) {
+			if member == empty {
+				names = append(names, "'"+empty.String()+"'")
+				break
+			}
+		}
+	}
+	return strings.Join(names, " or ")
+}
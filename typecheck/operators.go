@@ -0,0 +1,78 @@
+package typecheck
+
+import "ts-engine/ast"
+
+// link is the type of the object of a member access, index or call, of
+// type t and computed by node. An optional link, a?.b, skips null and
+// undefined, which make the chain give undefined rather than an error.
+func (c *checker) link(t Type, node ast.Node, optional bool) Type {
+	if !optional {
+		return c.nonNull(t, node)
+	}
+	if !nullable(t) {
+		return t
+	}
+	c.skipsChain = true
+	if t = nonNullable(t); t == Never {
+		return Any
+	}
+	return t
+}
+
+// optionalChain infers the type of a?.b.c: that of a.b.c, or undefined if
+// a may be null or undefined.
+func (c *checker) optionalChain(node *ast.OptionalChain) Type {
+	outer := c.skipsChain
+	defer func() { c.skipsChain = outer }()
+
+	c.skipsChain = false
+	t := c.expr(node.Expression)
+	if c.skipsChain {
+		return unionOf(t, Undefined)
+	}
+	return t
+}
+
+// deleteOperand checks the operand of delete, which must be a property
+// that may be missing: an optional property or one of an index signature.
+func (c *checker) deleteOperand(operand ast.Expression) {
+	if chain, ok := operand.(*ast.OptionalChain); ok {
+		operand = chain.Expression
+	}
+
+	switch operand := operand.(type) {
+	case *ast.IndexExpression:
+		return
+	case *ast.InfixExpression:
+		if operand.Operator != "." {
+			break
+		}
+		obj, ok := nonNullable(c.types[operand.Left]).(*Object)
+		if !ok {
+			return
+		}
+		name := operand.Right.(*ast.Identifier).Value
+		for ; obj != nil; obj = obj.Base {
+			t, ok := obj.Props[name]
+			if !ok {
+				continue
+			}
+			if !obj.Optional[name] && t != Any && !includes(t, Undefined) {
+				c.errorAt(operand, "The operand of a 'delete' operator must be optional.")
+			}
+			return
+		}
+		return
+	}
+	c.errorAt(operand, "The operand of a 'delete' operator must be a property reference.")
+}
+
+// includes reports whether t is or has the member want.
+func includes(t, want Type) bool {
+	for _, member := range members(t) {
+		if member == want {
+			return true
+		}
+	}
+	return false
+}
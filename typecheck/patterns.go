@@ -0,0 +1,189 @@
+package typecheck
+
+import (
+	"ts-engine/ast"
+	"ts-engine/token"
+)
+
+// binding is called with each target of a destructuring pattern and the
+// type of the value unpacked into it.
+type binding func(target ast.Expression, t Type)
+
+// pattern checks a destructuring pattern unpacking a value of type t,
+// which may be nested patterns themselves.
+func (c *checker) pattern(pattern ast.Expression, t Type, bind binding) {
+	switch pattern := pattern.(type) {
+	case *ast.ObjectPattern:
+		c.objectPattern(pattern, t, bind)
+	case *ast.ArrayPattern:
+		c.arrayPattern(pattern, t, bind)
+	default:
+		bind(pattern, t)
+	}
+}
+
+func (c *checker) objectPattern(pattern *ast.ObjectPattern, t Type, bind binding) {
+	if nullable(t) {
+		c.errorAt(pattern, "Object is possibly %s.", nullishNames(t))
+		if t = nonNullable(t); t == Never {
+			t = Any
+		}
+	}
+
+	used := map[string]bool{}
+	for _, prop := range pattern.Properties {
+		var p Type = Any
+		if prop.Computed {
+			c.expr(prop.Key)
+			if s, ok := prop.Key.(*ast.StringLiteral); ok {
+				p = c.property(t, s.Value, prop.Key)
+			}
+		} else if name, ok := propertyName(prop.Key); ok {
+			used[name] = true
+			p = c.property(t, name, prop.Key)
+		}
+		c.element(prop.Target, prop.Default, p, bind)
+	}
+
+	if pattern.Rest == nil {
+		return
+	}
+
+	// The rest has the properties not already unpacked
+	var rest Type = Any
+	if obj, ok := t.(*Object); ok {
+		remaining := &Object{Props: map[string]Type{}, Index: obj.index()}
+		for _, name := range obj.properties() {
+			if used[name] {
+				continue
+			}
+			remaining.Props[name], _ = obj.Lookup(name)
+			if !obj.required(name) {
+				setFlag(&remaining.Optional, name)
+			}
+		}
+		rest = remaining
+	}
+	c.pattern(pattern.Rest, rest, bind)
+}
+
+func (c *checker) arrayPattern(pattern *ast.ArrayPattern, t Type, bind binding) {
+	if _, ok := t.(*TypeParam); ok {
+		t = apparent(t)
+	}
+
+	elem := func(i int) Type { return Any }
+	rest := func() Type { return &Array{Elem: Any} }
+	switch t := t.(type) {
+	case *Array:
+		elem = func(int) Type { return t.Elem }
+		rest = func() Type { return t }
+	case *Tuple:
+		elem = func(i int) Type {
+			if i >= len(t.Elems) {
+				c.errorAt(pattern.Elements[i], "Tuple type '%s' of length '%d' has no element at index '%d'.", t, len(t.Elems), i)
+				return Any
+			}
+			return t.Elems[i]
+		}
+		rest = func() Type {
+			if len(pattern.Elements) >= len(t.Elems) {
+				return &Tuple{}
+			}
+			return &Tuple{Elems: t.Elems[len(pattern.Elements):]}
+		}
+	default:
+		if widen(t) == String {
+			elem = func(int) Type { return String }
+			rest = func() Type { return &Array{Elem: String} }
+		} else if t != Any {
+			c.errorAt(pattern, "Type '%s' is not an array type.", widen(t))
+		}
+	}
+
+	for i, el := range pattern.Elements {
+		if el != nil {
+			c.element(el.Target, el.Default, elem(i), bind)
+		}
+	}
+	if pattern.Rest != nil {
+		c.pattern(pattern.Rest, rest(), bind)
+	}
+}
+
+// element checks one target of a pattern, which gets a value of type t or
+// its default. An element of an array literal has the literal type of its
+// value, so the default only needs to be of the same primitive type, as in
+// const [x = 1] = [2], and the target may be either.
+func (c *checker) element(target, def ast.Expression, t Type, bind binding) {
+	if def != nil {
+		d := c.expr(def)
+		if lit, ok := t.(*Literal); ok {
+			c.assign(def, d, lit.Base, typeMismatch)
+			t = unionOf(t, d)
+		} else if t != Any {
+			c.assign(def, d, t, typeMismatch)
+		} else if _, ok := target.(*ast.Identifier); ok {
+			t = d
+		}
+	}
+	c.pattern(target, t, bind)
+}
+
+// unpacked is the type of the value a pattern unpacks. An array literal
+// is taken as a tuple, so that each target gets the type of its own
+// element.
+func (c *checker) unpacked(value ast.Expression, t Type) Type {
+	if lit, ok := value.(*ast.ArrayLiteral); ok {
+		return c.literalTuple(lit)
+	}
+	return t
+}
+
+// destructuringDeclaration checks let { a, b }: T = value, declaring the
+// variables of the pattern.
+func (c *checker) destructuringDeclaration(stmt *ast.LetStatement) {
+	constant := stmt.Token.Type == token.CONST
+
+	t := c.expr(stmt.Value)
+	if annotated := patternType(stmt.Pattern); annotated != nil {
+		declared := c.resolveType(annotated)
+		c.assign(stmt.Value, t, declared, typeMismatch)
+		t = declared
+	} else {
+		t = c.unpacked(stmt.Value, t)
+	}
+
+	c.pattern(stmt.Pattern, t, func(target ast.Expression, t Type) {
+		name := target.(*ast.Identifier).Value
+		if !constant {
+			t = widen(t)
+		}
+		sym := c.declared(stmt.Token.Type, name)
+		sym.typ = t
+		sym.pending = false
+	})
+}
+
+// patternAssignment checks [a, b] = value, where each target is assigned
+// its part of the value.
+func (c *checker) patternAssignment(node *ast.AssignmentExpression) Type {
+	value := c.expr(node.Value)
+	c.pattern(node.Left, c.unpacked(node.Value, value), func(target ast.Expression, t Type) {
+		if dst := c.reference(target); !assignable(t, dst) {
+			c.errorAt(target, typeMismatch, t, dst)
+		}
+	})
+	return value
+}
+
+// patternType is the annotation of a whole pattern, or nil.
+func patternType(pattern ast.Expression) ast.TypeNode {
+	switch pattern := pattern.(type) {
+	case *ast.ObjectPattern:
+		return pattern.Type
+	case *ast.ArrayPattern:
+		return pattern.Type
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package typecheck
+
+// promiseProperty is the type of the property name of a promise of value:
+// one of the methods of Promise.prototype.
+func promiseProperty(value Type, name string) (Type, bool) {
+	optional := func(name string, t Type) Param { return Param{Name: name, Type: t, Optional: true} }
+	// handler is the type of a callback given the settled value, which
+	// may return a value or a promise of one.
+	handler := func(param Param, r *TypeParam) *Function {
+		return &Function{Params: []Param{param}, Return: unionOf(r, &Promise{Value: r})}
+	}
+	reason := Param{Name: "reason", Type: Any}
+
+	switch name {
+	case "then":
+		// then<R1 = T, R2 = never>(onfulfilled?: (value: T) => R1 | Promise<R1>,
+		// onrejected?: (reason: any) => R2 | Promise<R2>): Promise<R1 | R2>
+		r1 := &TypeParam{Name: "TResult1", Default: value}
+		r2 := &TypeParam{Name: "TResult2", Default: Never}
+		return &Function{
+			TypeParams: []*TypeParam{r1, r2},
+			Params: []Param{
+				optional("onfulfilled", handler(Param{Name: "value", Type: value}, r1)),
+				optional("onrejected", handler(reason, r2)),
+			},
+			Return: &Promise{Value: unionOf(r1, r2)},
+		}, true
+	case "catch":
+		r := &TypeParam{Name: "TResult", Default: Never}
+		return &Function{
+			TypeParams: []*TypeParam{r},
+			Params:     []Param{optional("onrejected", handler(reason, r))},
+			Return:     &Promise{Value: unionOf(value, r)},
+		}, true
+	case "finally":
+		return &Function{
+			Params: []Param{optional("onfinally", &Function{Return: Any})},
+			Return: &Promise{Value: value},
+		}, true
+	}
+	return nil, false
+}
+
+// awaited is the type of the value await gives for a value of type t:
+// promises give their values, and other values themselves.
+func awaited(t Type) Type {
+	types := make([]Type, 0, len(members(t)))
+	for _, member := range members(t) {
+		if p, ok := member.(*Promise); ok {
+			member = p.Value
+		}
+		types = append(types, member)
+	}
+	return unionOf(types...)
+}
+
+// promiseClass is the type of the Promise global. new Promise<number>(...)
+// is a Promise<number>; the statics combine the values or promises of
+// values they are given into a promise of the values' type.
+func promiseClass() *Class {
+	value := &TypeParam{Name: "T", Default: Any}
+	executor := &Function{
+		Params: []Param{
+			{Name: "resolve", Type: &Function{
+				Params: []Param{{Name: "value", Type: unionOf(value, &Promise{Value: value}), Optional: true}},
+				Return: Void,
+			}},
+			{Name: "reject", Type: &Function{Params: []Param{{Name: "reason", Type: Any, Optional: true}}, Return: Void}},
+		},
+		Return: Any,
+	}
+
+	// combine is the type of Promise.all and the like: a function of
+	// (T | Promise<T>)[] returning a promise of result(T).
+	combine := func(result func(t Type) Type) *Function {
+		t := &TypeParam{Name: "T"}
+		return &Function{
+			TypeParams: []*TypeParam{t},
+			Params:     []Param{{Name: "values", Type: &Array{Elem: unionOf(t, &Promise{Value: t})}}},
+			Return:     &Promise{Value: result(t)},
+		}
+	}
+	settled := func(t Type) Type {
+		return &Object{Props: map[string]Type{"status": String, "value": t, "reason": Any},
+			Optional: map[string]bool{"value": true, "reason": true}}
+	}
+
+	resolved := &TypeParam{Name: "T", Default: Void}
+	rejected := &TypeParam{Name: "T", Default: Never}
+	return &Class{
+		Name:       "Promise",
+		TypeParams: []*TypeParam{value},
+		Instance:   &Promise{Value: value},
+		Statics: &Object{Props: map[string]Type{
+			"resolve": &Function{
+				TypeParams: []*TypeParam{resolved},
+				Params:     []Param{{Name: "value", Type: unionOf(resolved, &Promise{Value: resolved}), Optional: true}},
+				Return:     &Promise{Value: resolved},
+			},
+			"reject": &Function{
+				TypeParams: []*TypeParam{rejected},
+				Params:     []Param{{Name: "reason", Type: Any, Optional: true}},
+				Return:     &Promise{Value: rejected},
+			},
+			"all":        combine(func(t Type) Type { return &Array{Elem: t} }),
+			"allSettled": combine(func(t Type) Type { return &Array{Elem: settled(t)} }),
+			"race":       combine(func(t Type) Type { return t }),
+			"any":        combine(func(t Type) Type { return t }),
+		}},
+		Construct: &Function{
+			TypeParams: []*TypeParam{value},
+			Params:     []Param{{Name: "executor", Type: executor}},
+			Return:     &Promise{Value: value},
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package typecheck
+
+import "ts-engine/ast"
+
+// argument is one argument of a call once spread tuples are expanded: the
+// node giving it and its type.
+type argument struct {
+	node ast.Expression
+	typ  Type
+}
+
+// spreadArguments expands the arguments of a call whose types are types.
+// Spreading a tuple gives one argument per element. Spreading an array
+// gives an unknown number of them, so it ends the list and is returned
+// separately, with the arguments after it.
+func (c *checker) spreadArguments(args []ast.Expression, types []Type) (list, open []argument) {
+	for i, arg := range args {
+		spread, ok := arg.(*ast.SpreadElement)
+		if !ok {
+			if open != nil {
+				open = append(open, argument{arg, types[i]})
+			} else {
+				list = append(list, argument{arg, types[i]})
+			}
+			continue
+		}
+		if tuple, ok := c.types[spread.Argument].(*Tuple); ok && open == nil {
+			for _, elem := range tuple.Elems {
+				list = append(list, argument{spread, elem})
+			}
+			continue
+		}
+		open = append(open, argument{spread, types[i]})
+	}
+	return list, open
+}
+
+// argumentTypes returns the types of a list of arguments.
+func argumentTypes(args []argument) []Type {
+	types := make([]Type, len(args))
+	for i, arg := range args {
+		types[i] = arg.typ
+	}
+	return types
+}
+
+// spreadObject adds the properties of the value of ...value in an object
+// literal to obj. It reports false if the value's properties are not
+// known, which makes the whole literal any.
+func (c *checker) spreadObject(obj *Object, spread *ast.SpreadElement) bool {
+	t := c.expr(spread.Argument)
+	if _, ok := t.(*TypeParam); ok {
+		t = apparent(t)
+	}
+	src, ok := nonNullable(t).(*Object)
+	if !ok {
+		return t != Any
+	}
+	for _, name := range src.properties() {
+		obj.Props[name], _ = src.Lookup(name)
+		if !src.required(name) {
+			setFlag(&obj.Optional, name)
+		} else if obj.Optional != nil {
+			delete(obj.Optional, name)
+		}
+	}
+	return true
+}
+
+// spreadProperties returns the properties the value of ...value in an
+// object literal gives, once checked, or nil if they are not known.
+func (c *checker) spreadProperties(spread *ast.SpreadElement) *Object {
+	src, _ := nonNullable(c.types[spread.Argument]).(*Object)
+	return src
+}
+
+// hasSpread reports whether an array literal has spread elements.
+func hasSpread(node *ast.ArrayLiteral) bool {
+	for _, el := range node.Elements {
+		if _, ok := el.(*ast.SpreadElement); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package typecheck
+
+import (
+	"maps"
+	"ts-engine/ast"
+)
+
+// switchStatement checks a switch. Each clause is checked with the
+// discriminant narrowed to the cases that lead to it, counting those that
+// fall through, and the default clause with it narrowed to the values no
+// case matches. Once every member of a union or enum has a case, that is
+// never, so const unreachable: never = x in default reports a missed case.
+func (c *checker) switchStatement(stmt *ast.SwitchStatement) {
+	t := c.expr(stmt.Discriminant)
+
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() { c.scope = outer }()
+
+	var stmts []ast.Statement
+	var tests []ast.Expression
+	for _, clause := range stmt.Cases {
+		stmts = append(stmts, clause.Consequent...)
+		if clause.Test == nil {
+			continue
+		}
+		tests = append(tests, clause.Test)
+		if caseType := c.expr(clause.Test); !overlaps(caseType, t) {
+			c.errorAt(clause.Test, "Type '%s' is not comparable to type '%s'.", caseType, t)
+		}
+	}
+	c.hoist(stmts)
+
+	unmatched := unmatchedCases(stmt.Discriminant, tests)
+	exhaustive := c.unmatched(t, tests) == Never
+	var reaching []ast.Expression // conditions under which the clause runs
+	for _, clause := range stmt.Cases {
+		if clause.Test != nil {
+			reaching = append(reaching, &ast.InfixExpression{Left: stmt.Discriminant, Operator: "===", Right: clause.Test})
+		} else {
+			reaching = append(reaching, unmatched)
+			exhaustive = true
+		}
+
+		c.clause(clause.Consequent, c.narrowing(anyOf(reaching), true))
+
+		if len(clause.Consequent) > 0 && exits(clause.Consequent[len(clause.Consequent)-1]) {
+			reaching = nil
+		}
+	}
+
+	// A switch on shape.kind covers every shape if none is left over
+	for _, left := range c.narrowing(unmatched, true) {
+		exhaustive = exhaustive || left == Never
+	}
+	c.exhaustive[stmt] = exhaustive
+}
+
+// clause checks the statements of a case clause with the variables in n
+// narrowed. The clauses share a scope, so that declarations carry over to
+// the clauses after this one, but narrowings do not.
+func (c *checker) clause(stmts []ast.Statement, n narrowing) {
+	before := maps.Clone(c.scope.vars)
+	c.narrow(n)
+	c.statements(stmts)
+	for name, sym := range c.scope.vars {
+		if sym.narrows == nil || before[name] == sym {
+			continue
+		}
+		if prev, ok := before[name]; ok {
+			c.scope.vars[name] = prev
+		} else {
+			delete(c.scope.vars, name)
+		}
+	}
+}
+
+// anyOf joins conditions with ||. A nil condition, which always holds,
+// makes the whole of it nil.
+func anyOf(conds []ast.Expression) ast.Expression {
+	var joined ast.Expression
+	for _, cond := range conds {
+		switch {
+		case cond == nil:
+			return nil
+		case joined == nil:
+			joined = cond
+		default:
+			joined = &ast.InfixExpression{Left: joined, Operator: "||", Right: cond}
+		}
+	}
+	return joined
+}
+
+// unmatchedCases is the condition under which no case of a switch on
+// discriminant matches: discriminant !== a && discriminant !== b, or nil if
+// there are no cases.
+func unmatchedCases(discriminant ast.Expression, tests []ast.Expression) ast.Expression {
+	var cond ast.Expression
+	for _, test := range tests {
+		differs := &ast.InfixExpression{Left: discriminant, Operator: "!==", Right: test}
+		if cond == nil {
+			cond = differs
+		} else {
+			cond = &ast.InfixExpression{Left: cond, Operator: "&&", Right: differs}
+		}
+	}
+	return cond
+}
+
+// unmatched is the part of t, the type of the discriminant of a switch,
+// that none of its cases match. Only literals and enum members are matched
+// off, so it is never only if t is a union of them with a case for each.
+func (c *checker) unmatched(t Type, tests []ast.Expression) Type {
+	return filter(enumMembers(t), func(member Type) bool {
+		for _, test := range tests {
+			switch value := c.types[test].(type) {
+			case *Literal:
+				if lit, ok := member.(*Literal); ok && lit.Value == value.Value {
+					return false
+				}
+			case *EnumMember:
+				if member == value {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
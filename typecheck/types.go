@@ -0,0 +1,744 @@
+package typecheck
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is a static type, as inferred for an expression or written in an
+// annotation.
+type Type interface {
+	String() string
+}
+
+// Primitive is one of the built-in types named by a keyword.
+type Primitive struct {
+	Name string
+}
+
+func (p *Primitive) String() string { return p.Name }
+
+var (
+	Any       = &Primitive{Name: "any"}
+	Unknown   = &Primitive{Name: "unknown"}
+	Never     = &Primitive{Name: "never"}
+	Void      = &Primitive{Name: "void"}
+	Null      = &Primitive{Name: "null"}
+	Undefined = &Primitive{Name: "undefined"}
+	Number    = &Primitive{Name: "number"}
+	String    = &Primitive{Name: "string"}
+	Boolean   = &Primitive{Name: "boolean"}
+)
+
+var primitives = map[string]*Primitive{
+	"any":       Any,
+	"unknown":   Unknown,
+	"never":     Never,
+	"void":      Void,
+	"null":      Null,
+	"undefined": Undefined,
+	"number":    Number,
+	"string":    String,
+	"boolean":   Boolean,
+}
+
+// Literal is the type of a single string, number or boolean value, such
+// as "left" or 404.
+type Literal struct {
+	Value interface{} // a string, float64 or bool
+	Base  *Primitive
+}
+
+func (l *Literal) String() string {
+	switch v := l.Value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatBool(l.Value.(bool))
+}
+
+// Union is the type of a value of any of Types, which are never unions
+// themselves. Unions are made by unionOf.
+type Union struct {
+	Types []Type
+}
+
+func (u *Union) String() string {
+	types := make([]string, len(u.Types))
+	for i, t := range u.Types {
+		types[i] = t.String()
+		if _, ok := t.(*Function); ok {
+			types[i] = "(" + types[i] + ")"
+		}
+	}
+	return strings.Join(types, " | ")
+}
+
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string {
+	switch a.Elem.(type) {
+	case *Function, *Union:
+		return "(" + a.Elem.String() + ")[]"
+	}
+	return a.Elem.String() + "[]"
+}
+
+type Tuple struct {
+	Elems []Type
+}
+
+func (t *Tuple) String() string {
+	elems := make([]string, len(t.Elems))
+	for i, el := range t.Elems {
+		elems[i] = el.String()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+type Param struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Function is the type of a function or method. Rest is the element type
+// of a trailing ...rest parameter, if there is one.
+type Function struct {
+	TypeParams []*TypeParam // of a generic function
+	Params     []Param
+	Rest       Type
+	Return     Type
+
+	// Guard is set for type guards, functions declared to return x is T.
+	Guard *Guard
+}
+
+// Guard tells that a type guard returning true means that its parameter
+// Param is of type Type.
+type Guard struct {
+	Param int
+	Type  Type
+}
+
+func (f *Function) String() string {
+	params := make([]string, 0, len(f.Params)+1)
+	for _, p := range f.Params {
+		name := p.Name
+		if p.Optional {
+			name += "?"
+		}
+		params = append(params, name+": "+p.Type.String())
+	}
+	if f.Rest != nil {
+		params = append(params, "...args: "+(&Array{Elem: f.Rest}).String())
+	}
+	ret := f.Return.String()
+	if f.Guard != nil {
+		ret = f.Params[f.Guard.Param].Name + " is " + f.Guard.Type.String()
+	}
+	return typeList(f.TypeParams) + "(" + strings.Join(params, ", ") + ") => " + ret
+}
+
+// TypeParam is a type parameter of a generic function, class, interface
+// or type alias, which stands for the type argument it is given.
+type TypeParam struct {
+	Name       string
+	Constraint Type // nil if unconstrained
+	Default    Type // nil if it has no default
+}
+
+func (tp *TypeParam) String() string { return tp.Name }
+
+// typeList renders types as <A, B>, or nothing if there are none.
+func typeList[T Type](types []T) string {
+	if len(types) == 0 {
+		return ""
+	}
+	list := make([]string, len(types))
+	for i, t := range types {
+		list[i] = t.String()
+	}
+	return "<" + strings.Join(list, ", ") + ">"
+}
+
+// required is the number of arguments a call must pass. A parameter with
+// a default still has to be passed if a required one follows it.
+func (f *Function) required() int {
+	n := 0
+	for i, p := range f.Params {
+		if !p.Optional {
+			n = i + 1
+		}
+	}
+	return n
+}
+
+// Object is the type of an object: an object literal, or an instance of a
+// class named Name whose inherited members are found on Base. Index is the
+// type of properties that are not listed, if it has an index signature.
+// Args are the type arguments of a generic class or interface, as in
+// Box<number>.
+type Object struct {
+	Name     string
+	Args     []Type
+	Props    map[string]Type
+	Optional map[string]bool
+	Readonly map[string]bool
+	Access   map[string]Access // of private and protected class members
+	Index    Type
+	Base     *Object
+}
+
+// Access is the accessibility of a private or protected class member:
+// where it may be used from.
+type Access struct {
+	Modifier string // "private" or "protected"
+	Class    *Class // the class declaring the member
+}
+
+// allows reports whether a member with access a may be used in the body
+// of class cls, which is nil outside of classes.
+func (a Access) allows(cls *Class) bool {
+	for k := cls; k != nil; k = k.Super {
+		if k == a.Class || k.Generic == a.Class {
+			return true
+		}
+		if a.Modifier == "private" {
+			return false
+		}
+	}
+	return false
+}
+
+func (o *Object) String() string {
+	if o.Name != "" {
+		return o.Name + typeList(o.Args)
+	}
+
+	keys := make([]string, 0, len(o.Props))
+	for key := range o.Props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 && o.Index == nil {
+		return "{}"
+	}
+
+	var out strings.Builder
+	out.WriteString("{ ")
+	for _, key := range keys {
+		name := key
+		if o.Optional[key] {
+			name += "?"
+		}
+		out.WriteString(name + ": " + o.Props[key].String() + "; ")
+	}
+	if o.Index != nil {
+		out.WriteString("[key: string]: " + o.Index.String() + "; ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// Lookup finds a property of o or of the objects it inherits from, or the
+// index signature's type for properties that are not listed.
+func (o *Object) Lookup(name string) (Type, bool) {
+	for obj := o; obj != nil; obj = obj.Base {
+		if t, ok := obj.Props[name]; ok {
+			return t, true
+		}
+	}
+	if index := o.index(); index != nil {
+		return index, true
+	}
+	return nil, false
+}
+
+// index is the type of o's index signature, or nil if it has none.
+func (o *Object) index() Type {
+	for obj := o; obj != nil; obj = obj.Base {
+		if obj.Index != nil {
+			return obj.Index
+		}
+	}
+	return nil
+}
+
+// properties returns the names of all the properties of o, including the
+// inherited ones.
+func (o *Object) properties() []string {
+	seen := map[string]bool{}
+	var names []string
+	for obj := o; obj != nil; obj = obj.Base {
+		for name := range obj.Props {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// required reports whether objects of type o must have property name.
+func (o *Object) required(name string) bool {
+	for obj := o; obj != nil; obj = obj.Base {
+		if _, ok := obj.Props[name]; ok {
+			return !obj.Optional[name]
+		}
+	}
+	return false
+}
+
+// readonly reports whether property name of o may not be assigned to.
+func (o *Object) readonly(name string) bool {
+	for obj := o; obj != nil; obj = obj.Base {
+		if _, ok := obj.Props[name]; ok {
+			return obj.Readonly[name]
+		}
+	}
+	return false
+}
+
+// access is the accessibility of property name of o, if it is a private
+// or protected class member.
+func (o *Object) access(name string) (Access, bool) {
+	for obj := o; obj != nil; obj = obj.Base {
+		if _, ok := obj.Props[name]; ok {
+			a, ok := obj.Access[name]
+			return a, ok
+		}
+	}
+	return Access{}, false
+}
+
+// inherit copies the properties of base to o, as an interface extending
+// base does.
+func (o *Object) inherit(base *Object) {
+	for _, name := range base.properties() {
+		if _, ok := o.Props[name]; ok {
+			continue
+		}
+		o.Props[name], _ = base.Lookup(name)
+		if !base.required(name) {
+			setFlag(&o.Optional, name)
+		}
+		if base.readonly(name) {
+			setFlag(&o.Readonly, name)
+		}
+	}
+	if o.Index == nil {
+		o.Index = base.index()
+	}
+}
+
+func setFlag(flags *map[string]bool, name string) {
+	if *flags == nil {
+		*flags = map[string]bool{}
+	}
+	(*flags)[name] = true
+}
+
+// Class is the type of a class itself, as opposed to its instances.
+type Class struct {
+	Name       string
+	TypeParams []*TypeParam // of a generic class
+	Super      *Class
+	Instance   Type
+	Statics    *Object // nil if the statics are not known
+	Construct  *Function
+
+	// Generic is the generic class this is an instance of, as Box is for
+	// the superclass Box<number>.
+	Generic *Class
+
+	// declaring is set until the members of a class being checked are
+	// known, and pending are the instances of it to fill in then.
+	declaring bool
+	pending   []func()
+
+	// Callable classes, such as Error, may be called without new.
+	Callable bool
+}
+
+func (c *Class) String() string { return "typeof " + c.Name }
+
+// Enum is the type of the values of an enum, as in let c: Color. Values
+// of a numeric enum may be given as numbers too.
+type Enum struct {
+	Name    string
+	Const   bool
+	Members []*EnumMember
+}
+
+func (e *Enum) String() string { return e.Name }
+
+func (e *Enum) member(name string) (*EnumMember, bool) {
+	for _, m := range e.Members {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// base is the primitive type of the values of an enum's members.
+func (e *Enum) base() Type {
+	types := make([]Type, len(e.Members))
+	for i, m := range e.Members {
+		types[i] = widen(m.literal())
+	}
+	return unionOf(types...)
+}
+
+// EnumMember is the type of a member of an enum, Color.Red. Value is the
+// member's value, or nil if it is computed at runtime.
+type EnumMember struct {
+	Enum  *Enum
+	Name  string
+	Value interface{}
+}
+
+func (m *EnumMember) String() string { return m.Enum.Name + "." + m.Name }
+
+// literal is the literal type of a member's value.
+func (m *EnumMember) literal() Type {
+	switch value := m.Value.(type) {
+	case string:
+		return &Literal{Value: value, Base: String}
+	case float64:
+		return &Literal{Value: value, Base: Number}
+	}
+	return Number
+}
+
+// EnumObject is the type of the object of an enum, Color in Color.Red.
+type EnumObject struct {
+	Enum *Enum
+}
+
+func (e *EnumObject) String() string { return "typeof " + e.Enum.Name }
+
+// Promise is the type of a promise of a Value.
+type Promise struct {
+	Value Type
+}
+
+func (p *Promise) String() string { return "Promise<" + p.Value.String() + ">" }
+
+// assignable reports whether a value of type src may be stored where dst
+// is expected.
+func assignable(src, dst Type) bool {
+	return isAssignable(src, dst, map[[2]Type]bool{})
+}
+
+// isAssignable implements assignable. seen holds the pairs being compared
+// further up, which are assumed to be assignable so that recursive types
+// terminate.
+func isAssignable(src, dst Type, seen map[[2]Type]bool) bool {
+	if src == dst || src == Any || src == Never || dst == Any || dst == Unknown {
+		return true
+	}
+	if src == Undefined && dst == Void {
+		return true
+	}
+	pair := [2]Type{src, dst}
+	if seen[pair] {
+		return true
+	}
+	seen[pair] = true
+
+	// A union is assignable if all its members are, and assignable to if
+	// any of its members is.
+	if src, ok := src.(*Union); ok {
+		for _, member := range src.Types {
+			if !isAssignable(member, dst, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	if dst, ok := dst.(*Union); ok {
+		for _, member := range dst.Types {
+			if isAssignable(src, member, seen) {
+				return true
+			}
+		}
+		return false
+	}
+	if ok, isEnum := enumAssignable(src, dst, seen); isEnum {
+		return ok
+	}
+	if src, ok := src.(*Literal); ok {
+		if dst, ok := dst.(*Literal); ok {
+			return src.Value == dst.Value
+		}
+		return isAssignable(src.Base, dst, seen)
+	}
+	// A type parameter may stand for any type that satisfies its constraint
+	if src, ok := src.(*TypeParam); ok {
+		return src.Constraint != nil && isAssignable(src.Constraint, dst, seen)
+	}
+
+	switch dst := dst.(type) {
+	case *Array:
+		switch src := src.(type) {
+		case *Array:
+			return isAssignable(src.Elem, dst.Elem, seen)
+		case *Tuple:
+			for _, el := range src.Elems {
+				if !isAssignable(el, dst.Elem, seen) {
+					return false
+				}
+			}
+			return true
+		}
+
+	case *Tuple:
+		src, ok := src.(*Tuple)
+		if !ok || len(src.Elems) != len(dst.Elems) {
+			return false
+		}
+		for i := range src.Elems {
+			if !isAssignable(src.Elems[i], dst.Elems[i], seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Function:
+		src, ok := src.(*Function)
+		if !ok {
+			return false
+		}
+		src, dst = erase(src), erase(dst)
+		if src.required() > len(dst.Params) && dst.Rest == nil {
+			return false
+		}
+		// Parameters are compared in both directions, as TypeScript does
+		for i := range src.Params {
+			if i >= len(dst.Params) {
+				break
+			}
+			s, d := src.Params[i].Type, dst.Params[i].Type
+			if !isAssignable(d, s, seen) && !isAssignable(s, d, seen) {
+				return false
+			}
+		}
+		return dst.Return == Void || isAssignable(src.Return, dst.Return, seen)
+
+	case *Object:
+		switch src.(type) {
+		case *Object, *Array, *Tuple:
+		default:
+			if src != String {
+				return false
+			}
+		}
+		for _, name := range dst.properties() {
+			want, _ := dst.Lookup(name)
+			got, ok := apparentMember(src, name)
+			if !dst.required(name) {
+				if !ok {
+					continue
+				}
+				want = unionOf(want, Undefined)
+			}
+			if !ok || !isAssignable(got, want, seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Promise:
+		src, ok := src.(*Promise)
+		return ok && isAssignable(src.Value, dst.Value, seen)
+
+	case *Class:
+		for src, ok := src.(*Class); ok && src != nil; src = src.Super {
+			if src == dst || src.Generic == dst {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// apparentMember looks up the property name of src for assignment to an
+// object type. Besides objects, strings and arrays have properties such as
+// length, so that they satisfy { length: number }.
+func apparentMember(src Type, name string) (Type, bool) {
+	switch src := src.(type) {
+	case *Object:
+		return src.Lookup(name)
+	case *Array, *Tuple:
+		return propertyType(src, name)
+	}
+	if src == String && name == "length" {
+		return propertyType(src, name)
+	}
+	return nil, false
+}
+
+// enumAssignable implements isAssignable where src or dst is an enum or
+// an enum member, reporting isEnum false otherwise. Members are
+// assignable to their enum and, like the enum, to the type of their
+// values. Numbers are assignable to numeric enums, but number literals only
+// if they are the value of a member.
+func enumAssignable(src, dst Type, seen map[[2]Type]bool) (ok, isEnum bool) {
+	switch src := src.(type) {
+	case *EnumMember:
+		switch dst := dst.(type) {
+		case *Enum:
+			return src.Enum == dst, true
+		case *EnumMember:
+			return false, true
+		}
+		return isAssignable(src.literal(), dst, seen), true
+	case *Enum:
+		switch dst.(type) {
+		case *Enum, *EnumMember:
+			return false, true
+		}
+		return isAssignable(src.base(), dst, seen), true
+	}
+
+	switch dst := dst.(type) {
+	case *Enum:
+		if lit, ok := src.(*Literal); ok {
+			for _, m := range dst.Members {
+				if lit.Base == Number && lit.Value == m.Value {
+					return true, true
+				}
+			}
+			return false, true
+		}
+		return src == Number && isAssignable(Number, dst.base(), seen), true
+	case *EnumMember:
+		lit, ok := src.(*Literal)
+		return ok && lit.Base == Number && lit.Value == dst.Value, true
+	}
+	return false, false
+}
+
+// identical reports whether two types accept the same values.
+func identical(a, b Type) bool {
+	if a == Any || b == Any {
+		return a == b
+	}
+	return assignable(a, b) && assignable(b, a)
+}
+
+// unionOf is the union of types. Nested unions are flattened, identical
+// members are kept once and literals are left out if their primitive type
+// is a member, so that the union of "a" and string is string. A union of
+// any is any, and of nothing never.
+func unionOf(types ...Type) Type {
+	var flat []Type
+	for _, t := range types {
+		if u, ok := t.(*Union); ok {
+			flat = append(flat, u.Types...)
+		} else if t != nil && t != Never {
+			flat = append(flat, t)
+		}
+	}
+
+	var members []Type
+	for i, t := range flat {
+		if t == Any {
+			return Any
+		}
+		absorbed := false
+		for j, other := range flat {
+			if lit, ok := t.(*Literal); (ok && other == lit.Base) || (j < i && identical(t, other)) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			members = append(members, t)
+		}
+	}
+
+	switch len(members) {
+	case 0:
+		return Never
+	case 1:
+		return members[0]
+	}
+	return &Union{Types: members}
+}
+
+// members returns the members of a union, or t itself for other types.
+func members(t Type) []Type {
+	if u, ok := t.(*Union); ok {
+		return u.Types
+	}
+	return []Type{t}
+}
+
+// filter is the union of the members of t for which keep returns true.
+func filter(t Type, keep func(Type) bool) Type {
+	var kept []Type
+	for _, member := range members(t) {
+		if keep(member) {
+			kept = append(kept, member)
+		}
+	}
+	return unionOf(kept...)
+}
+
+// widen replaces literal types by their primitive types, as the type of a
+// variable that may be assigned other values is.
+func widen(t Type) Type {
+	switch t := t.(type) {
+	case *Literal:
+		return t.Base
+	case *EnumMember:
+		return t.Enum
+	case *Union:
+		types := make([]Type, len(t.Types))
+		for i, member := range t.Types {
+			types[i] = widen(member)
+		}
+		return unionOf(types...)
+	}
+	return t
+}
+
+// overlaps reports whether some value has both type a and type b, which
+// comparing values of types a and b with === requires. Null may always be
+// compared with.
+func overlaps(a, b Type) bool {
+	for _, a := range members(a) {
+		for _, b := range members(b) {
+			la, aLiteral := a.(*Literal)
+			lb, bLiteral := b.(*Literal)
+			switch {
+			case aLiteral && bLiteral:
+				if la.Value == lb.Value {
+					return true
+				}
+			case isPrimitiveValue(widen(a)) && isPrimitiveValue(widen(b)):
+				if widen(a) == widen(b) {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isPrimitiveValue(t Type) bool {
+	return t == Number || t == String || t == Boolean
+}